@@ -0,0 +1,487 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/Esri/geotrigger-go/geotrigger"
+	"io/ioutil"
+	"strings"
+	"time"
+)
+
+type triggersResponse struct {
+	Triggers []geotrigger.Trigger `json:"triggers"`
+}
+
+type devicesResponse struct {
+	Devices []struct {
+		DeviceID        string   `json:"deviceId"`
+		Tags            []string `json:"tags"`
+		TrackingProfile string   `json:"trackingProfile"`
+	} `json:"devices"`
+}
+
+type tagsResponse struct {
+	Tags []struct {
+		Name string `json:"name"`
+	} `json:"tags"`
+}
+
+type locationsResponse struct {
+	Locations []struct {
+		DeviceID string `json:"deviceId"`
+		geotrigger.Location
+	} `json:"locations"`
+}
+
+// withClient runs `fn` with a client for the current profile, then stores any
+// refreshed device tokens, see saveTokens.
+func (c *cli) withClient(fn func(client *geotrigger.Client) error) error {
+	profile, err := loadProfile(c.configPath, c.profileName, c.getenv)
+	if err != nil {
+		return err
+	}
+
+	client, err := profile.client()
+	if err != nil {
+		return err
+	}
+
+	err = fn(client)
+	if loaded := *profile; profile.update(client) {
+		if saveErr := saveTokens(c.configPath, c.profileName, &loaded, profile); saveErr != nil && err == nil {
+			err = saveErr
+		}
+	}
+
+	return err
+}
+
+// do posts params to a route, printing the response as JSON, or as a table
+// through `table` if one is given and the table format was asked for.
+func (c *cli) do(route string, params interface{}, table func(raw []byte) error) error {
+	return c.withClient(func(client *geotrigger.Client) error {
+		var raw json.RawMessage
+		if err := client.Request(route, params, &raw); err != nil {
+			return err
+		}
+
+		if c.format == "table" && table != nil {
+			return table(raw)
+		}
+
+		return printJSON(c.stdout, raw)
+	})
+}
+
+// readParams parses a JSON argument, reading it from stdin if it is `-`.
+func (c *cli) readParams(arg string) (map[string]interface{}, error) {
+	contents := []byte(arg)
+	if arg == "-" {
+		var err error
+		if contents, err = ioutil.ReadAll(c.stdin); err != nil {
+			return nil, fmt.Errorf("Could not read params from stdin. %s", err)
+		}
+	}
+
+	var params map[string]interface{}
+	if err := json.Unmarshal(contents, &params); err != nil {
+		return nil, fmt.Errorf("Params must be a JSON object. %s", err)
+	}
+
+	return params, nil
+}
+
+// selectFlags parses the -ids and -tags flags shared by the list commands.
+func selectFlags(name string, idKey string, args []string) (map[string]interface{}, error) {
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	ids := flags.String("ids", "", "comma separated ids")
+	tags := flags.String("tags", "", "comma separated tags")
+	if err := flags.Parse(args); err != nil {
+		return nil, err
+	}
+
+	params := map[string]interface{}{}
+	if len(*ids) > 0 {
+		params[idKey] = strings.Split(*ids, ",")
+	}
+	if len(*tags) > 0 {
+		params["tags"] = strings.Split(*tags, ",")
+	}
+
+	return params, nil
+}
+
+func authApplication(c *cli, args []string) error {
+	flags := flag.NewFlagSet("auth application", flag.ContinueOnError)
+	clientID := flags.String("client-id", c.getenv("GEOTRIGGER_CLIENT_ID"), "application client_id")
+	clientSecret := flags.String("client-secret", c.getenv("GEOTRIGGER_CLIENT_SECRET"), "application client_secret")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if len(*clientID) == 0 || len(*clientSecret) == 0 {
+		return errors.New("Both -client-id and -client-secret are required.")
+	}
+
+	profile, err := loadProfile(c.configPath, c.profileName, c.getenv)
+	if err != nil {
+		return err
+	}
+	profile = &Profile{
+		ClientID:      *clientID,
+		ClientSecret:  *clientSecret,
+		GeotriggerURL: profile.GeotriggerURL,
+		AGOURL:        profile.AGOURL,
+	}
+
+	// make sure the credentials work before storing them
+	if _, err := profile.client(); err != nil {
+		return err
+	}
+
+	if err := saveProfile(c.configPath, c.profileName, profile); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(c.stdout, "Saved application %s to profile %s.\n", profile.ClientID, c.profileName)
+	return nil
+}
+
+func authDevice(c *cli, args []string) error {
+	flags := flag.NewFlagSet("auth device", flag.ContinueOnError)
+	clientID := flags.String("client-id", c.getenv("GEOTRIGGER_CLIENT_ID"), "application client_id")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if len(*clientID) == 0 {
+		return errors.New("-client-id is required.")
+	}
+
+	profile, err := loadProfile(c.configPath, c.profileName, c.getenv)
+	if err != nil {
+		return err
+	}
+
+	client, err := geotrigger.NewDeviceWithEnvironment(profile.environment(), *clientID)
+	if err != nil {
+		return err
+	}
+
+	info := client.Info()
+	profile = &Profile{
+		ClientID:      *clientID,
+		DeviceID:      info["device_id"],
+		AccessToken:   info["access_token"],
+		RefreshToken:  info["refresh_token"],
+		GeotriggerURL: profile.GeotriggerURL,
+		AGOURL:        profile.AGOURL,
+	}
+	if err := saveProfile(c.configPath, c.profileName, profile); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(c.stdout, "Registered device %s and saved it to profile %s.\n", profile.DeviceID, c.profileName)
+	return nil
+}
+
+func request(c *cli, args []string) error {
+	if len(args) == 0 || len(args) > 2 {
+		return errors.New("usage: geotrigger request <route> [params]")
+	}
+
+	var params interface{} = map[string]interface{}{}
+	if len(args) == 2 {
+		var err error
+		if params, err = c.readParams(args[1]); err != nil {
+			return err
+		}
+	}
+
+	return c.do(args[0], params, nil)
+}
+
+func listTriggers(c *cli, args []string) error {
+	params, err := selectFlags("triggers list", "triggerIds", args)
+	if err != nil {
+		return err
+	}
+
+	return c.do("trigger/list", params, c.triggerTable)
+}
+
+func createTrigger(c *cli, args []string) error {
+	flags := flag.NewFlagSet("triggers create", flag.ContinueOnError)
+	dryRun := flags.Bool("dry-run", false, "only check the trigger, without creating it")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		return errors.New("usage: geotrigger triggers create [-dry-run] <trigger>")
+	}
+
+	params, err := c.readParams(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	if *dryRun {
+		// check the params as trigger/create reads them: tags are set from
+		// setTags, a single tag or a list of them, and tags is ignored
+		if _, ok := params["tags"]; ok {
+			return errors.New("Invalid trigger. tags: Ignored by trigger/create, use setTags.")
+		}
+		if tag, ok := params["setTags"].(string); ok {
+			params["setTags"] = []string{tag}
+		}
+		raw, err := json.Marshal(params)
+		if err != nil {
+			return err
+		}
+		var create geotrigger.TriggerCreateParams
+		if err := json.Unmarshal(raw, &create); err != nil {
+			return fmt.Errorf("Invalid trigger. %s", err)
+		}
+		if err := create.Validate(); err != nil {
+			return err
+		}
+
+		fmt.Fprintln(c.stdout, "Trigger is valid.")
+		return nil
+	}
+
+	return c.do("trigger/create", params, func(raw []byte) error {
+		return c.triggerTable([]byte(fmt.Sprintf(`{"triggers":[%s]}`, raw)))
+	})
+}
+
+func updateTriggers(c *cli, args []string) error {
+	if len(args) != 1 {
+		return errors.New("usage: geotrigger triggers update <params>")
+	}
+
+	params, err := c.readParams(args[0])
+	if err != nil {
+		return err
+	}
+
+	return c.do("trigger/update", params, c.triggerTable)
+}
+
+func deleteTriggers(c *cli, args []string) error {
+	if len(args) == 0 {
+		return errors.New("usage: geotrigger triggers delete <triggerId>...")
+	}
+
+	return c.do("trigger/delete", map[string]interface{}{"triggerIds": args}, c.triggerTable)
+}
+
+func runTriggers(c *cli, args []string) error {
+	flags := flag.NewFlagSet("triggers run", flag.ContinueOnError)
+	devices := flags.String("devices", "", "comma separated device ids to run the triggers for")
+	tags := flags.String("tags", "", "comma separated tags of the devices to run the triggers for")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() == 0 {
+		return errors.New("usage: geotrigger triggers run (-devices IDS | -tags TAGS) <triggerId>...")
+	}
+
+	params := &geotrigger.RunParams{TriggerIDs: flags.Args()}
+	if len(*devices) > 0 {
+		params.DeviceIDs = strings.Split(*devices, ",")
+	}
+	if len(*tags) > 0 {
+		params.Tags = strings.Split(*tags, ",")
+	}
+
+	return c.withClient(func(client *geotrigger.Client) error {
+		response, err := client.RunTrigger(context.Background(), params)
+		if err != nil {
+			return err
+		}
+
+		if c.format == "table" {
+			raw, err := json.Marshal(triggersResponse{Triggers: response.Triggers})
+			if err != nil {
+				return err
+			}
+			return c.triggerTable(raw)
+		}
+
+		return printJSON(c.stdout, response.Raw)
+	})
+}
+
+func listDevices(c *cli, args []string) error {
+	params, err := selectFlags("devices list", "deviceIds", args)
+	if err != nil {
+		return err
+	}
+
+	return c.do("device/list", params, c.deviceTable)
+}
+
+func updateDevices(c *cli, args []string) error {
+	if len(args) != 1 {
+		return errors.New("usage: geotrigger devices update <params>")
+	}
+
+	params, err := c.readParams(args[0])
+	if err != nil {
+		return err
+	}
+
+	return c.do("device/update", params, c.deviceTable)
+}
+
+func trackDevices(c *cli, args []string) error {
+	flags := flag.NewFlagSet("devices tracking", flag.ContinueOnError)
+	tags := flags.String("tags", "", "comma separated tags of the devices to switch")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if len(*tags) == 0 || flags.NArg() != 1 {
+		return errors.New("usage: geotrigger devices tracking -tags TAGS off|adaptive|rough|fine")
+	}
+
+	return c.withClient(func(client *geotrigger.Client) error {
+		devices, err := client.SetTrackingProfile(context.Background(), flags.Arg(0), strings.Split(*tags, ",")...)
+		if err != nil {
+			return err
+		}
+
+		raw, err := json.Marshal(map[string]interface{}{"devices": devices})
+		if err != nil {
+			return err
+		}
+		if c.format == "table" {
+			return c.deviceTable(raw)
+		}
+
+		return printJSON(c.stdout, raw)
+	})
+}
+
+func listTags(c *cli, args []string) error {
+	return c.do("tag/list", map[string]interface{}{}, func(raw []byte) error {
+		var response tagsResponse
+		if err := json.Unmarshal(raw, &response); err != nil {
+			return err
+		}
+
+		rows := [][]string{}
+		for _, tag := range response.Tags {
+			rows = append(rows, []string{tag.Name})
+		}
+
+		return printTable(c.stdout, []string{"NAME"}, rows)
+	})
+}
+
+func lastLocations(c *cli, args []string) error {
+	params, err := selectFlags("locations last", "deviceIds", args)
+	if err != nil {
+		return err
+	}
+
+	return c.do("location/last", params, func(raw []byte) error {
+		var response locationsResponse
+		if err := json.Unmarshal(raw, &response); err != nil {
+			return err
+		}
+
+		rows := [][]string{}
+		for _, location := range response.Locations {
+			rows = append(rows, []string{
+				location.DeviceID,
+				formatFloat(location.Latitude),
+				formatFloat(location.Longitude),
+				formatFloat(location.Accuracy),
+				location.Timestamp.Format(time.RFC3339),
+			})
+		}
+
+		return printTable(c.stdout, []string{"DEVICE ID", "LATITUDE", "LONGITUDE", "ACCURACY", "TIMESTAMP"}, rows)
+	})
+}
+
+func updateLocation(c *cli, args []string) error {
+	flags := flag.NewFlagSet("locations update", flag.ContinueOnError)
+	latitude := flags.Float64("lat", 0, "latitude")
+	longitude := flags.Float64("lng", 0, "longitude")
+	accuracy := flags.Float64("accuracy", 10, "accuracy in meters")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	// 0 is a valid coordinate, so look for the flags rather than their values
+	set := make(map[string]bool)
+	flags.Visit(func(f *flag.Flag) {
+		set[f.Name] = true
+	})
+	if !set["lat"] || !set["lng"] {
+		return errors.New("Both -lat and -lng are required.")
+	}
+
+	location := geotrigger.Location{
+		Latitude:  *latitude,
+		Longitude: *longitude,
+		Accuracy:  *accuracy,
+		Timestamp: time.Now().UTC(),
+	}
+
+	return c.do("location/update", &geotrigger.LocationUpdateParams{Locations: []geotrigger.Location{location}}, nil)
+}
+
+func (c *cli) triggerTable(raw []byte) error {
+	var response triggersResponse
+	if err := json.Unmarshal(raw, &response); err != nil {
+		return err
+	}
+
+	rows := [][]string{}
+	for _, trigger := range response.Triggers {
+		rows = append(rows, []string{
+			trigger.TriggerID,
+			trigger.Condition.Direction,
+			describeFence(&trigger.Condition.Geo),
+			strings.Join(trigger.Tags, ","),
+			trigger.Action.Message,
+		})
+	}
+
+	return printTable(c.stdout, []string{"TRIGGER ID", "DIRECTION", "FENCE", "TAGS", "MESSAGE"}, rows)
+}
+
+func (c *cli) deviceTable(raw []byte) error {
+	var response devicesResponse
+	if err := json.Unmarshal(raw, &response); err != nil {
+		return err
+	}
+
+	rows := [][]string{}
+	for _, device := range response.Devices {
+		rows = append(rows, []string{device.DeviceID, strings.Join(device.Tags, ","), device.TrackingProfile})
+	}
+
+	return printTable(c.stdout, []string{"DEVICE ID", "TAGS", "TRACKING PROFILE"}, rows)
+}
+
+func describeFence(geo *geotrigger.Geo) string {
+	switch {
+	case geo.IsCircle():
+		return fmt.Sprintf("circle %s,%s r=%sm", formatFloat(geo.Latitude), formatFloat(geo.Longitude),
+			formatFloat(geo.Distance))
+	case geo.GeoJSON != nil:
+		return "geojson " + geo.GeoJSON.Type
+	case geo.EsriJSON != nil:
+		return fmt.Sprintf("esrijson %d rings", len(geo.EsriJSON.Rings))
+	case len(geo.Geocode) > 0:
+		return fmt.Sprintf("geocode %q", geo.Geocode)
+	}
+
+	return ""
+}
@@ -0,0 +1,167 @@
+// Command `geotrigger` talks to the Geotrigger API from the command line.
+//
+// Credentials are read from a profile file (`~/.geotrigger.json`, or the path
+// in `GEOTRIGGER_CONFIG`), written by the `auth` commands. The environment
+// variables `GEOTRIGGER_CLIENT_ID`, `GEOTRIGGER_CLIENT_SECRET`,
+// `GEOTRIGGER_DEVICE_ID` and `GEOTRIGGER_REFRESH_TOKEN` take precedence over
+// the stored values.
+//
+// Usage:
+//
+//	geotrigger [-profile name] [-format json|table] <command> [arguments]
+//
+// Run `geotrigger help` for the list of commands.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+)
+
+const usage = `usage: geotrigger [-profile name] [-format json|table] <command> [arguments]
+
+commands:
+  auth application -client-id ID -client-secret SECRET
+  auth device -client-id ID
+  request <route> [params]      POST params (JSON, or - for stdin) to any route
+  triggers list [-ids IDS] [-tags TAGS]
+  triggers create [-dry-run] <trigger>
+  triggers update <params>
+  triggers delete <triggerId>...
+  triggers run (-devices IDS | -tags TAGS) <triggerId>...
+  devices list [-ids IDS] [-tags TAGS]
+  devices update <params>
+  devices tracking -tags TAGS off|adaptive|rough|fine
+  tags list
+  locations last [-ids IDS] [-tags TAGS]
+  locations update -lat LAT -lng LNG [-accuracy METERS]
+  sim [-devices N] (-route FILE [-loop] | -lat LAT -lng LNG [-radius METERS])
+      [-speed M/S] [-interval DURATION] [-duration DURATION] [-speedup X]
+      [-tags TAGS] [-seed N]     register devices and report simulated locations
+  replay [-speedup X] [-retime] [-batch N] <track.gpx|track.csv>
+                                send a recorded track as the device's locations,
+                                listing the events its triggers fire
+  reconcile [-apply] <config.json>
+                                show, or make, the changes that bring the
+                                application's triggers in line with a config
+
+IDS and TAGS are comma separated.
+`
+
+type cli struct {
+	stdin       io.Reader
+	stdout      io.Writer
+	getenv      func(string) string
+	configPath  string
+	profileName string
+	format      string
+}
+
+type command func(c *cli, args []string) error
+
+var commands = map[string]map[string]command{
+	"auth": {
+		"application": authApplication,
+		"device":      authDevice,
+	},
+	"triggers": {
+		"list":   listTriggers,
+		"create": createTrigger,
+		"update": updateTriggers,
+		"delete": deleteTriggers,
+		"run":    runTriggers,
+	},
+	"devices": {
+		"list":     listDevices,
+		"update":   updateDevices,
+		"tracking": trackDevices,
+	},
+	"tags": {
+		"list": listTags,
+	},
+	"locations": {
+		"last":   lastLocations,
+		"update": updateLocation,
+	},
+}
+
+func main() {
+	if err := run(os.Args[1:], os.Stdin, os.Stdout, os.Getenv); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func run(args []string, stdin io.Reader, stdout io.Writer, getenv func(string) string) error {
+	flags := flag.NewFlagSet("geotrigger", flag.ContinueOnError)
+	flags.SetOutput(stdout)
+	flags.Usage = func() { fmt.Fprint(stdout, usage) }
+
+	c := &cli{
+		stdin:      stdin,
+		stdout:     stdout,
+		getenv:     getenv,
+		configPath: configPath(getenv),
+	}
+	defaultProfile := getenv("GEOTRIGGER_PROFILE")
+	if len(defaultProfile) == 0 {
+		defaultProfile = "default"
+	}
+	flags.StringVar(&c.profileName, "profile", defaultProfile, "name of the profile to use")
+	flags.StringVar(&c.format, "format", "table", "output format: json or table")
+
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if c.format != "json" && c.format != "table" {
+		return fmt.Errorf("Unknown format: %s.", c.format)
+	}
+
+	args = flags.Args()
+	if len(args) == 0 || args[0] == "help" {
+		fmt.Fprint(stdout, usage)
+		return nil
+	}
+
+	switch args[0] {
+	case "request":
+		return request(c, args[1:])
+	case "sim":
+		return simulate(c, args[1:])
+	case "replay":
+		return replayTrack(c, args[1:])
+	case "reconcile":
+		return reconcileConfig(c, args[1:])
+	}
+
+	subcommands, ok := commands[args[0]]
+	if !ok {
+		return fmt.Errorf("Unknown command: %s. Run `geotrigger help` for usage.", args[0])
+	}
+
+	if len(args) < 2 {
+		return fmt.Errorf("Missing subcommand for %s, one of: %s.", args[0], subcommandNames(subcommands))
+	}
+
+	cmd, ok := subcommands[args[1]]
+	if !ok {
+		return fmt.Errorf("Unknown subcommand for %s: %s. Expected one of: %s.", args[0], args[1],
+			subcommandNames(subcommands))
+	}
+
+	return cmd(c, args[2:])
+}
+
+func subcommandNames(subcommands map[string]command) string {
+	var names []string
+	for name := range subcommands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return strings.Join(names, ", ")
+}
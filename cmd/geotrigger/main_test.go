@@ -0,0 +1,169 @@
+package main
+
+import (
+	"bytes"
+	"github.com/Esri/geotrigger-go/geotrigger"
+	"github.com/Esri/geotrigger-go/geotrigger/geotriggertest"
+	"github.com/Esri/geotrigger-go/geotrigger/test"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func testGetenv(dir string, server *geotriggertest.Server) func(string) string {
+	return func(key string) string {
+		switch key {
+		case "GEOTRIGGER_CONFIG":
+			return filepath.Join(dir, "profiles.json")
+		case "GEOTRIGGER_URL", "GEOTRIGGER_AGO_URL":
+			return server.URL
+		}
+		return ""
+	}
+}
+
+func runCommand(t *testing.T, getenv func(string) string, args ...string) string {
+	var out bytes.Buffer
+	err := run(args, strings.NewReader(""), &out, getenv)
+	test.Expect(t, err, nil)
+	return out.String()
+}
+
+func TestCommands(t *testing.T) {
+	dir, err := ioutil.TempDir("", "geotrigger-cli")
+	test.Expect(t, err, nil)
+	defer os.RemoveAll(dir)
+
+	server := geotriggertest.NewServer()
+	defer server.Close()
+	server.AddApplication("good_client_id", "good_client_secret")
+	getenv := testGetenv(dir, server)
+
+	out := runCommand(t, getenv, "auth", "application", "-client-id", "good_client_id", "-client-secret", "good_client_secret")
+	test.Expect(t, out, "Saved application good_client_id to profile default.\n")
+
+	out = runCommand(t, getenv, "triggers", "create",
+		`{"triggerId":"derp","condition":{"direction":"enter","geo":{"latitude":45.5,"longitude":-122.6,"distance":100}},"action":{"message":"hi"},"setTags":["herp"]}`)
+	test.Expect(t, out, "TRIGGER ID  DIRECTION  FENCE                      TAGS  MESSAGE\n"+
+		"derp        enter      circle 45.5,-122.6 r=100m  herp  hi\n")
+
+	out = runCommand(t, getenv, "-format", "json", "triggers", "list", "-tags", "herp")
+	test.Expect(t, strings.Contains(out, `"triggerId": "derp"`), true)
+
+	out = runCommand(t, getenv, "tags", "list")
+	test.Expect(t, out, "NAME\nherp\n")
+
+	out = runCommand(t, getenv, "-profile", "phone", "auth", "device", "-client-id", "good_client_id")
+	deviceID := server.Devices()[0].DeviceID
+	test.Expect(t, out, "Registered device "+deviceID+" and saved it to profile phone.\n")
+
+	out = runCommand(t, getenv, "-profile", "phone", "locations", "update", "-lat", "45.5", "-lng", "-122.6")
+	test.Expect(t, strings.Contains(out, `"processedLocations": 1`), true)
+
+	out = runCommand(t, getenv, "devices", "list")
+	test.Expect(t, strings.Contains(out, deviceID), true)
+
+	out = runCommand(t, getenv, "devices", "tracking", "-tags", "device:"+deviceID, "fine")
+	test.Expect(t, strings.Contains(out, deviceID), true)
+	test.Expect(t, server.Devices()[0].TrackingProfile, geotrigger.TrackingFine)
+	err = run([]string{"devices", "tracking", "-tags", "device:" + deviceID, "precise"}, nil, &bytes.Buffer{}, getenv)
+	test.Expect(t, err.Error(), "Invalid params for device/update. trackingProfile: Must be off, adaptive, rough or fine, not precise.")
+	err = run([]string{"devices", "tracking", "-tags", "device:" + deviceID, ""}, nil, &bytes.Buffer{}, getenv)
+	test.Expect(t, err.Error(), "Invalid params for device/update. trackingProfile: Missing.")
+
+	out = runCommand(t, getenv, "triggers", "run", "-devices", deviceID, "derp")
+	test.Expect(t, strings.Contains(out, "derp        enter"), true)
+
+	out = runCommand(t, getenv, "triggers", "create", "-dry-run",
+		`{"condition":{"direction":"enter","geo":{"geocode":"Portland"}},"action":{"message":"hi"}}`)
+	test.Expect(t, out, "Trigger is valid.\n")
+	err = run([]string{"triggers", "create", "-dry-run", `{"condition":{"direction":"enter","geo":{"geocode":"Portland"}}}`},
+		nil, &bytes.Buffer{}, getenv)
+	test.Expect(t, err.Error(), "Invalid trigger. action: Needs a message, callbackUrl, notification or trackingProfile.")
+	out = runCommand(t, getenv, "triggers", "create", "-dry-run",
+		`{"condition":{"direction":"enter","geo":{"geocode":"Portland"}},"action":{"message":"hi"},"setTags":"pdx"}`)
+	test.Expect(t, out, "Trigger is valid.\n")
+	err = run([]string{"triggers", "create", "-dry-run", `{"condition":{"direction":"enter","geo":{"geocode":"Portland"}},"action":{"message":"hi"},"setTags":["pdx",""]}`},
+		nil, &bytes.Buffer{}, getenv)
+	test.Expect(t, err.Error(), "Invalid trigger. setTags[1]: Tags can't be empty.")
+	err = run([]string{"triggers", "create", "-dry-run", `{"condition":{"direction":"enter","geo":{"geocode":"Portland"}},"action":{"message":"hi"},"tags":["pdx"]}`},
+		nil, &bytes.Buffer{}, getenv)
+	test.Expect(t, err.Error(), "Invalid trigger. tags: Ignored by trigger/create, use setTags.")
+	test.Expect(t, len(server.Triggers()), 1)
+
+	out = runCommand(t, getenv, "request", "trigger/delete", `{"triggerIds":"derp"}`)
+	test.Expect(t, strings.Contains(out, `"triggerId": "derp"`), true)
+	test.Expect(t, len(server.Triggers()), 0)
+}
+
+func TestDeviceTokensAreSaved(t *testing.T) {
+	dir, err := ioutil.TempDir("", "geotrigger-cli")
+	test.Expect(t, err, nil)
+	defer os.RemoveAll(dir)
+
+	server := geotriggertest.NewServer()
+	defer server.Close()
+	server.AddApplication("good_client_id", "good_client_secret")
+	getenv := testGetenv(dir, server)
+
+	runCommand(t, getenv, "auth", "device", "-client-id", "good_client_id")
+	path := configPath(getenv)
+	before, err := loadProfile(path, "default", getenv)
+	test.Expect(t, err, nil)
+
+	// the stored access token is refreshed before use, and saved again
+	runCommand(t, getenv, "devices", "list")
+	after, err := loadProfile(path, "default", getenv)
+	test.Expect(t, err, nil)
+	test.Refute(t, after.AccessToken, before.AccessToken)
+	test.Expect(t, after.RefreshToken, before.RefreshToken)
+	test.Expect(t, after.DeviceID, before.DeviceID)
+
+	// with the URLs and refresh token only in the environment, only the
+	// access token is written
+	profiles, err := readProfiles(path)
+	test.Expect(t, err, nil)
+	stored := profiles.Profiles["default"]
+	stored.GeotriggerURL, stored.AGOURL = "", ""
+	stored.AccessToken, stored.RefreshToken = "", "stored_refresh_token"
+	test.Expect(t, writeProfiles(path, profiles), nil)
+
+	envGetenv := func(key string) string {
+		if key == "GEOTRIGGER_REFRESH_TOKEN" {
+			return before.RefreshToken
+		}
+		return getenv(key)
+	}
+	runCommand(t, envGetenv, "devices", "list")
+	profiles, err = readProfiles(path)
+	test.Expect(t, err, nil)
+	stored = profiles.Profiles["default"]
+	test.Refute(t, stored.AccessToken, "")
+	test.Expect(t, stored.RefreshToken, "stored_refresh_token")
+	test.Expect(t, stored.GeotriggerURL, "")
+	test.Expect(t, stored.AGOURL, "")
+	test.Expect(t, stored.ClientSecret, "")
+}
+
+func TestUsageErrors(t *testing.T) {
+	getenv := func(string) string { return "" }
+	var out bytes.Buffer
+
+	err := run([]string{"help"}, nil, &out, getenv)
+	test.Expect(t, err, nil)
+	test.Expect(t, out.String(), usage)
+
+	err = run([]string{"derp"}, nil, &out, getenv)
+	test.Expect(t, err.Error(), "Unknown command: derp. Run `geotrigger help` for usage.")
+
+	err = run([]string{"triggers"}, nil, &out, getenv)
+	test.Expect(t, err.Error(), "Missing subcommand for triggers, one of: create, delete, list, run, update.")
+
+	err = run([]string{"locations", "update", "-lng", "-122.6"}, nil, &out, getenv)
+	test.Expect(t, err.Error(), "Both -lat and -lng are required.")
+
+	err = run([]string{"-format", "xml", "tags", "list"}, nil, &out, getenv)
+	test.Expect(t, err.Error(), "Unknown format: xml.")
+}
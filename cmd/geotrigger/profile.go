@@ -0,0 +1,170 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/Esri/geotrigger-go/geotrigger"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// Profile holds the credentials for one application or device. Profiles are
+// stored by name in a JSON file, `~/.geotrigger.json` unless
+// `GEOTRIGGER_CONFIG` says otherwise.
+type Profile struct {
+	ClientID      string `json:"client_id"`
+	ClientSecret  string `json:"client_secret,omitempty"`
+	DeviceID      string `json:"device_id,omitempty"`
+	AccessToken   string `json:"access_token,omitempty"`
+	RefreshToken  string `json:"refresh_token,omitempty"`
+	GeotriggerURL string `json:"geotrigger_url,omitempty"`
+	AGOURL        string `json:"ago_url,omitempty"`
+}
+
+type profileFile struct {
+	Profiles map[string]*Profile `json:"profiles"`
+}
+
+func configPath(getenv func(string) string) string {
+	if path := getenv("GEOTRIGGER_CONFIG"); len(path) > 0 {
+		return path
+	}
+
+	return filepath.Join(getenv("HOME"), ".geotrigger.json")
+}
+
+func readProfiles(path string) (*profileFile, error) {
+	profiles := &profileFile{Profiles: make(map[string]*Profile)}
+
+	contents, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return profiles, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("Could not read profiles from %s. %s", path, err)
+	}
+
+	if err := json.Unmarshal(contents, profiles); err != nil {
+		return nil, fmt.Errorf("Could not parse profiles in %s. %s", path, err)
+	}
+	if profiles.Profiles == nil {
+		profiles.Profiles = make(map[string]*Profile)
+	}
+
+	return profiles, nil
+}
+
+func writeProfiles(path string, profiles *profileFile) error {
+	contents, err := json.MarshalIndent(profiles, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	// profiles hold secrets, keep them private
+	return ioutil.WriteFile(path, contents, 0600)
+}
+
+// loadProfile returns the named profile, with any credentials found in the
+// environment taking precedence over the stored ones.
+func loadProfile(path string, name string, getenv func(string) string) (*Profile, error) {
+	profiles, err := readProfiles(path)
+	if err != nil {
+		return nil, err
+	}
+
+	profile := &Profile{}
+	if stored, ok := profiles.Profiles[name]; ok {
+		*profile = *stored
+	}
+
+	overrides := []struct {
+		key   string
+		value *string
+	}{
+		{"GEOTRIGGER_CLIENT_ID", &profile.ClientID},
+		{"GEOTRIGGER_CLIENT_SECRET", &profile.ClientSecret},
+		{"GEOTRIGGER_DEVICE_ID", &profile.DeviceID},
+		{"GEOTRIGGER_REFRESH_TOKEN", &profile.RefreshToken},
+		{"GEOTRIGGER_URL", &profile.GeotriggerURL},
+		{"GEOTRIGGER_AGO_URL", &profile.AGOURL},
+	}
+	for _, override := range overrides {
+		if value := getenv(override.key); len(value) > 0 {
+			*override.value = value
+		}
+	}
+
+	return profile, nil
+}
+
+func saveProfile(path string, name string, profile *Profile) error {
+	profiles, err := readProfiles(path)
+	if err != nil {
+		return err
+	}
+
+	profiles.Profiles[name] = profile
+	return writeProfiles(path, profiles)
+}
+
+// saveTokens stores the tokens of a device session refreshed while running a
+// command, leaving the rest of the profile as it is on disk: credentials and
+// URLs taken from the environment are never written. Nothing is stored if the
+// profile on disk is for another device, or there isn't one. A refresh token
+// is stored only if the service issued a new one.
+func saveTokens(path string, name string, loaded *Profile, refreshed *Profile) error {
+	profiles, err := readProfiles(path)
+	if err != nil {
+		return err
+	}
+
+	stored, ok := profiles.Profiles[name]
+	if !ok || stored.ClientID != refreshed.ClientID || stored.DeviceID != refreshed.DeviceID {
+		return nil
+	}
+
+	stored.AccessToken = refreshed.AccessToken
+	if refreshed.RefreshToken != loaded.RefreshToken {
+		stored.RefreshToken = refreshed.RefreshToken
+	}
+
+	return writeProfiles(path, profiles)
+}
+
+func (profile *Profile) environment() geotrigger.Environment {
+	return geotrigger.Environment{
+		GeotriggerURL: profile.GeotriggerURL,
+		AGOURL:        profile.AGOURL,
+	}
+}
+
+// client creates a Client for the profile: an application if it has a client
+// secret, an existing device if it has a device id and refresh token.
+func (profile *Profile) client() (*geotrigger.Client, error) {
+	switch {
+	case len(profile.ClientID) == 0:
+		return nil, errors.New("No client_id found. Run `geotrigger auth` or set GEOTRIGGER_CLIENT_ID.")
+	case len(profile.ClientSecret) > 0:
+		return geotrigger.NewApplicationWithEnvironment(profile.environment(), profile.ClientID, profile.ClientSecret)
+	case len(profile.DeviceID) > 0 && len(profile.RefreshToken) > 0:
+		// with no known expiry, the access token is refreshed before first use
+		return geotrigger.ExistingDeviceWithEnvironment(profile.environment(), profile.ClientID, profile.DeviceID,
+			profile.AccessToken, 0, profile.RefreshToken), nil
+	}
+
+	return nil, errors.New("No client_secret or device credentials found. Run `geotrigger auth` first.")
+}
+
+// update copies the current tokens of a device session back into the
+// profile, so refreshed tokens aren't lost between runs.
+func (profile *Profile) update(client *geotrigger.Client) bool {
+	info := client.Info()
+	if _, isDevice := info["device_id"]; !isDevice || info["access_token"] == profile.AccessToken {
+		return false
+	}
+
+	profile.AccessToken = info["access_token"]
+	profile.RefreshToken = info["refresh_token"]
+	return true
+}
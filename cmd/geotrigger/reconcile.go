@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/Esri/geotrigger-go/geotrigger"
+	"github.com/Esri/geotrigger-go/geotrigger/reconcile"
+)
+
+// reconcileConfig shows the changes that would bring the profile's
+// application in line with a config file, and makes them with -apply.
+func reconcileConfig(c *cli, args []string) error {
+	flags := flag.NewFlagSet("reconcile", flag.ContinueOnError)
+	apply := flags.Bool("apply", false, "make the changes, instead of only showing them")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		return errors.New("usage: geotrigger reconcile [-apply] <config.json>")
+	}
+
+	config, err := reconcile.LoadConfig(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	return c.withClient(func(client *geotrigger.Client) error {
+		ctx := context.Background()
+		plan, err := reconcile.NewPlan(ctx, client, config)
+		if err != nil {
+			return err
+		}
+
+		if c.format == "json" {
+			raw, err := json.Marshal(plan)
+			if err != nil {
+				return err
+			}
+			if err := printJSON(c.stdout, raw); err != nil {
+				return err
+			}
+		} else {
+			fmt.Fprint(c.stdout, plan.Diff())
+		}
+
+		if plan.Empty() {
+			return nil
+		}
+		if !*apply {
+			if c.format == "table" {
+				fmt.Fprintln(c.stdout, "Run with -apply to make these changes.")
+			}
+			return nil
+		}
+
+		if err := plan.Apply(ctx, client); err != nil {
+			return err
+		}
+		if c.format == "table" {
+			fmt.Fprintf(c.stdout, "Applied %d changes.\n", len(plan.Triggers)+len(plan.TagPermissions))
+		}
+
+		return nil
+	})
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"github.com/Esri/geotrigger-go/geotrigger"
+	"github.com/Esri/geotrigger-go/geotrigger/geotriggertest"
+	"github.com/Esri/geotrigger-go/geotrigger/test"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestReconcileCommand(t *testing.T) {
+	dir, err := ioutil.TempDir("", "geotrigger-cli")
+	test.Expect(t, err, nil)
+	defer os.RemoveAll(dir)
+
+	server := geotriggertest.NewServer()
+	defer server.Close()
+	server.AddApplication("good_client_id", "good_client_secret")
+	server.AddTrigger(geotrigger.Trigger{
+		TriggerID: "old",
+		Condition: geotrigger.Condition{Direction: geotrigger.DirectionEnter, Geo: geotrigger.Geo{Geocode: "Portland"}},
+	})
+	getenv := testGetenv(dir, server)
+
+	configPath := filepath.Join(dir, "triggers.json")
+	err = ioutil.WriteFile(configPath, []byte(`{"triggers": [
+		{"triggerId": "welcome", "condition": {"direction": "enter", "geo": {"geocode": "Portland"}}, "action": {"message": "Hi."}}
+	]}`), 0600)
+	test.Expect(t, err, nil)
+
+	runCommand(t, getenv, "auth", "application", "-client-id", "good_client_id", "-client-secret", "good_client_secret")
+
+	out := runCommand(t, getenv, "reconcile", configPath)
+	test.Expect(t, out, "+ trigger welcome\n- trigger old\nRun with -apply to make these changes.\n")
+	test.Expect(t, server.Triggers()[0].TriggerID, "old")
+
+	out = runCommand(t, getenv, "reconcile", "-apply", configPath)
+	test.Expect(t, out, "+ trigger welcome\n- trigger old\nApplied 2 changes.\n")
+	test.Expect(t, len(server.Triggers()), 1)
+	test.Expect(t, server.Triggers()[0].TriggerID, "welcome")
+
+	out = runCommand(t, getenv, "reconcile", "-apply", configPath)
+	test.Expect(t, out, "No changes.\n")
+}
@@ -0,0 +1,316 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/Esri/geotrigger-go/geotrigger"
+	"github.com/Esri/geotrigger-go/geotrigger/geofence"
+	"github.com/Esri/geotrigger-go/geotrigger/sim"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
+)
+
+type simFlags struct {
+	devices   int
+	route     string
+	loop      bool
+	latitude  float64
+	longitude float64
+	radius    float64
+	speed     float64
+	interval  time.Duration
+	duration  time.Duration
+	speedup   float64
+	tags      string
+	seed      int64
+}
+
+type simEvent struct {
+	Timestamp time.Time `json:"timestamp"`
+	DeviceID  string    `json:"deviceId"`
+	TriggerID string    `json:"triggerId"`
+	Direction string    `json:"direction"`
+	Message   string    `json:"message,omitempty"`
+}
+
+type simReport struct {
+	Devices   int        `json:"devices"`
+	Locations int        `json:"locations"`
+	Errors    []string   `json:"errors"`
+	Events    []simEvent `json:"events"`
+	Elapsed   string     `json:"elapsed"`
+	// such as why triggers weren't evaluated
+	Warnings []string `json:"warnings"`
+}
+
+// simulate registers a fleet of devices for the profile's application and
+// moves them along a route or at random, reporting their locations. If the
+// profile has a client secret, the application's triggers are evaluated
+// locally against the same locations, and the events they fire are listed.
+func simulate(c *cli, args []string) error {
+	var options simFlags
+	flags := flag.NewFlagSet("sim", flag.ContinueOnError)
+	flags.IntVar(&options.devices, "devices", 10, "number of devices to register")
+	flags.StringVar(&options.route, "route", "", "GPX or GeoJSON file with a route for the devices to follow")
+	flags.BoolVar(&options.loop, "loop", false, "go back to the start of the route at its end")
+	flags.Float64Var(&options.latitude, "lat", 0, "latitude to random walk around, without a route")
+	flags.Float64Var(&options.longitude, "lng", 0, "longitude to random walk around, without a route")
+	flags.Float64Var(&options.radius, "radius", 1000, "meters to random walk within")
+	flags.Float64Var(&options.speed, "speed", 1.4, "meters per second")
+	flags.DurationVar(&options.interval, "interval", 10*time.Second, "simulated time between location reports")
+	flags.DurationVar(&options.duration, "duration", 10*time.Minute, "simulated time to run for, 0 for the whole route")
+	flags.Float64Var(&options.speedup, "speedup", 0, "run this many times faster than real time, 0 for as fast as possible")
+	flags.StringVar(&options.tags, "tags", "", "comma separated tags to add to every device")
+	flags.Int64Var(&options.seed, "seed", 1, "seed for random walks")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if options.devices < 1 {
+		return errors.New("-devices must be at least 1.")
+	}
+	// 0 is a valid coordinate, so look for the flags rather than their values
+	set := make(map[string]bool)
+	flags.Visit(func(f *flag.Flag) {
+		set[f.Name] = true
+	})
+	if len(options.route) == 0 && (!set["lat"] || !set["lng"]) {
+		return errors.New("Either -route, or -lat and -lng, are required.")
+	}
+
+	profile, err := loadProfile(c.configPath, c.profileName, c.getenv)
+	if err != nil {
+		return err
+	}
+	if len(profile.ClientID) == 0 {
+		return errors.New("No client_id found. Run `geotrigger auth` or set GEOTRIGGER_CLIENT_ID.")
+	}
+
+	routes, err := simRoutes(&options)
+	if err != nil {
+		return err
+	}
+
+	simulator := &sim.Simulator{
+		Interval: options.interval,
+		Duration: options.duration,
+		Speedup:  options.speedup,
+	}
+
+	var warnings []string
+	if len(profile.ClientSecret) > 0 {
+		if simulator.Engine, err = simEngine(profile); err != nil {
+			warnings = append(warnings, fmt.Sprintf("Not evaluating triggers: %s", err))
+		}
+	}
+
+	clients, err := sim.RegisterDevices(profile.environment(), profile.ClientID, options.devices)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		for _, client := range clients {
+			client.Close()
+		}
+	}()
+
+	var tags []string
+	if len(options.tags) > 0 {
+		tags = strings.Split(options.tags, ",")
+	}
+	for i, client := range clients {
+		simulator.Devices = append(simulator.Devices, &sim.Device{
+			Client: client,
+			Route:  routes[i],
+			Speed:  options.speed,
+			Tags:   tags,
+		})
+	}
+
+	// stop early, and still report, on ctrl-c
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	report, err := simulator.Run(ctx)
+	if err != nil {
+		return err
+	}
+
+	return c.printSimReport(len(clients), report, warnings)
+}
+
+// simRoutes makes a route for each device: along the route file, spread out
+// evenly, or random walks with seeds counting up from -seed.
+func simRoutes(options *simFlags) ([]sim.Route, error) {
+	routes := make([]sim.Route, options.devices)
+
+	if len(options.route) == 0 {
+		center := sim.Point{Latitude: options.latitude, Longitude: options.longitude}
+		for i := range routes {
+			routes[i] = sim.NewRandomWalk(center, options.radius, options.seed+int64(i))
+		}
+		return routes, nil
+	}
+
+	points, err := sim.LoadRoute(options.route)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range routes {
+		path, err := sim.NewPath(points, 0, options.loop)
+		if err != nil {
+			return nil, err
+		}
+		path.Advance(path.Length() * float64(i) / float64(options.devices))
+		routes[i] = path
+	}
+
+	return routes, nil
+}
+
+// simEngine loads the application's triggers for local evaluation.
+func simEngine(profile *Profile) (*geofence.Engine, error) {
+	client, err := profile.client()
+	if err != nil {
+		return nil, err
+	}
+	defer client.Close()
+
+	var response triggersResponse
+	if err := client.Request("trigger/list", map[string]interface{}{}, &response); err != nil {
+		return nil, err
+	}
+
+	return geofence.NewEngine(response.Triggers)
+}
+
+func (c *cli) printSimReport(devices int, report *sim.Report, warnings []string) error {
+	summary := simReport{
+		Devices:   devices,
+		Locations: report.Locations,
+		Errors:    []string{},
+		Events:    []simEvent{},
+		Elapsed:   report.Elapsed.Round(time.Millisecond).String(),
+		Warnings:  append([]string{}, warnings...),
+	}
+	for _, err := range report.Errors {
+		summary.Errors = append(summary.Errors, err.Error())
+	}
+	for _, event := range report.Events {
+		summary.Events = append(summary.Events, simEvent{
+			Timestamp: event.Location.Timestamp,
+			DeviceID:  event.DeviceID,
+			TriggerID: event.TriggerID,
+			Direction: event.Direction,
+			Message:   event.Trigger.Action.Message,
+		})
+	}
+
+	if c.format == "json" {
+		raw, err := json.Marshal(summary)
+		if err != nil {
+			return err
+		}
+		return printJSON(c.stdout, raw)
+	}
+
+	fmt.Fprintf(c.stdout, "Ran %d devices for %s: %d locations reported, %d failed.\n",
+		summary.Devices, summary.Elapsed, summary.Locations, len(summary.Errors))
+	for _, err := range summary.Errors {
+		fmt.Fprintln(c.stdout, err)
+	}
+	for _, warning := range summary.Warnings {
+		fmt.Fprintln(c.stdout, warning)
+	}
+	if len(summary.Events) == 0 {
+		return nil
+	}
+
+	rows := [][]string{}
+	for _, event := range summary.Events {
+		rows = append(rows, []string{
+			event.Timestamp.Format(time.RFC3339),
+			event.DeviceID,
+			event.TriggerID,
+			event.Direction,
+			event.Message,
+		})
+	}
+
+	return printTable(c.stdout, []string{"TIME", "DEVICE ID", "TRIGGER ID", "DIRECTION", "MESSAGE"}, rows)
+}
+
+// replayTrack sends a recorded GPX or CSV track as the locations of the
+// profile's device. The triggers the device can see are evaluated locally
+// against the same locations, and the events they fire are listed.
+func replayTrack(c *cli, args []string) error {
+	flags := flag.NewFlagSet("replay", flag.ContinueOnError)
+	speedup := flags.Float64("speedup", 1, "send this many times faster than recorded, 0 for as fast as possible")
+	retime := flags.Bool("retime", false, "move timestamps to start now, scaled by -speedup")
+	batch := flags.Int("batch", 1, "locations per location/update request")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		return errors.New("usage: geotrigger replay [-speedup X] [-retime] [-batch N] <track.gpx|track.csv>")
+	}
+
+	track, err := sim.LoadTrack(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	return c.withClient(func(client *geotrigger.Client) error {
+		replay := &sim.Replay{
+			Client:    client,
+			Track:     track,
+			Speedup:   *speedup,
+			Retime:    *retime,
+			BatchSize: *batch,
+		}
+
+		var warnings []string
+		if replay.Engine, replay.Tags, err = replayEngine(client); err != nil {
+			warnings = append(warnings, fmt.Sprintf("Not evaluating triggers: %s", err))
+		}
+
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer stop()
+
+		report, err := replay.Run(ctx)
+		if err != nil {
+			return err
+		}
+
+		return c.printSimReport(1, report, warnings)
+	})
+}
+
+// replayEngine loads the triggers a device can see for local evaluation,
+// along with the device's tags to match them by.
+func replayEngine(client *geotrigger.Client) (*geofence.Engine, []string, error) {
+	var devices devicesResponse
+	if err := client.Request("device/list", map[string]interface{}{}, &devices); err != nil {
+		return nil, nil, err
+	}
+	if len(devices.Devices) == 0 {
+		return nil, nil, errors.New("The device wasn't found.")
+	}
+
+	var response triggersResponse
+	if err := client.Request("trigger/list", map[string]interface{}{}, &response); err != nil {
+		return nil, nil, err
+	}
+
+	engine, err := geofence.NewEngine(response.Triggers)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return engine, devices.Devices[0].Tags, nil
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"encoding/json"
+	"github.com/Esri/geotrigger-go/geotrigger"
+	"github.com/Esri/geotrigger-go/geotrigger/geotriggertest"
+	"github.com/Esri/geotrigger-go/geotrigger/test"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSimCommand(t *testing.T) {
+	dir, err := ioutil.TempDir("", "geotrigger-cli")
+	test.Expect(t, err, nil)
+	defer os.RemoveAll(dir)
+
+	server := geotriggertest.NewServer()
+	defer server.Close()
+	server.AddApplication("good_client_id", "good_client_secret")
+	server.AddTrigger(geotrigger.Trigger{
+		TriggerID: "start",
+		Condition: geotrigger.Condition{
+			Direction: geotrigger.DirectionEnter,
+			Geo:       geotrigger.Geo{Latitude: 45.5, Longitude: -122.6, Distance: 100},
+		},
+		Action: geotrigger.Action{Message: "Off we go."},
+		Tags:   []string{"walkers"},
+	})
+	getenv := testGetenv(dir, server)
+
+	runCommand(t, getenv, "auth", "application", "-client-id", "good_client_id", "-client-secret", "good_client_secret")
+
+	out := runCommand(t, getenv, "-format", "json", "sim", "-devices", "2", "-lat", "45.5", "-lng", "-122.6",
+		"-interval", "1m", "-duration", "5m", "-tags", "walkers")
+
+	var report simReport
+	test.Expect(t, json.Unmarshal([]byte(out), &report), nil)
+	test.Expect(t, report.Devices, 2)
+	test.Expect(t, report.Locations, 12)
+	test.Expect(t, len(report.Errors), 0)
+
+	// both devices start inside the fence
+	test.Expect(t, len(report.Events) >= 2, true)
+	test.Expect(t, report.Events[0].TriggerID, "start")
+	test.Expect(t, report.Events[0].Message, "Off we go.")
+	test.Expect(t, len(server.Devices()), 2)
+
+	out = runCommand(t, getenv, "sim", "-devices", "1", "-lat", "45.5", "-lng", "-122.6", "-duration", "1m")
+	test.Expect(t, strings.Index(out, "Ran 1 devices for "), 0)
+	test.Expect(t, strings.Contains(out, ": 7 locations reported, 0 failed.\n"), true)
+
+	err = run([]string{"sim", "-devices", "1"}, nil, ioutil.Discard, getenv)
+	test.Expect(t, err.Error(), "Either -route, or -lat and -lng, are required.")
+	err = run([]string{"sim", "-devices", "1", "-lat", "45.5"}, nil, ioutil.Discard, getenv)
+	test.Expect(t, err.Error(), "Either -route, or -lat and -lng, are required.")
+
+	// 0,0 is a center like any other
+	out = runCommand(t, getenv, "sim", "-devices", "1", "-lat", "0", "-lng", "0", "-duration", "1m")
+	test.Expect(t, strings.Contains(out, ": 7 locations reported, 0 failed.\n"), true)
+
+	// triggers that can't be evaluated locally are reported, without
+	// breaking the JSON
+	server.AddTrigger(geotrigger.Trigger{
+		TriggerID: "geocoded",
+		Condition: geotrigger.Condition{Direction: geotrigger.DirectionEnter, Geo: geotrigger.Geo{Geocode: "Portland"}},
+		Action:    geotrigger.Action{Message: "Hi."},
+	})
+	out = runCommand(t, getenv, "-format", "json", "sim", "-devices", "1", "-lat", "45.5", "-lng", "-122.6",
+		"-duration", "1m")
+	report = simReport{}
+	test.Expect(t, json.Unmarshal([]byte(out), &report), nil)
+	test.Expect(t, report.Locations, 7)
+	test.Expect(t, len(report.Events), 0)
+	test.Expect(t, len(report.Warnings), 1)
+	test.Expect(t, strings.Index(report.Warnings[0], "Not evaluating triggers: Could not evaluate fence of trigger geocoded."), 0)
+}
+
+func TestReplayCommand(t *testing.T) {
+	dir, err := ioutil.TempDir("", "geotrigger-cli")
+	test.Expect(t, err, nil)
+	defer os.RemoveAll(dir)
+
+	server := geotriggertest.NewServer()
+	defer server.Close()
+	server.AddApplication("good_client_id", "good_client_secret")
+	getenv := testGetenv(dir, server)
+
+	trackPath := filepath.Join(dir, "track.csv")
+	err = ioutil.WriteFile(trackPath, []byte("lat,lng,timestamp,accuracy\n45.5,-122.6,1398187800,5\n45.51,-122.6,1398187860,5\n"), 0600)
+	test.Expect(t, err, nil)
+
+	runCommand(t, getenv, "auth", "device", "-client-id", "good_client_id")
+	deviceID := server.Devices()[0].DeviceID
+
+	out := runCommand(t, getenv, "replay", "-speedup", "0", trackPath)
+	test.Expect(t, strings.Contains(out, ": 2 locations reported, 0 failed.\n"), true)
+	test.Expect(t, len(server.Locations(deviceID)), 2)
+	test.Expect(t, server.Locations(deviceID)[1].Latitude, 45.51)
+
+	// the device's triggers are evaluated along the track
+	server.AddTrigger(geotrigger.Trigger{
+		TriggerID: "north",
+		Condition: geotrigger.Condition{
+			Direction: geotrigger.DirectionEnter,
+			Geo:       geotrigger.Geo{Latitude: 45.51, Longitude: -122.6, Distance: 100},
+		},
+		Action: geotrigger.Action{Message: "Made it."},
+		Tags:   []string{"device:" + deviceID},
+	})
+	out = runCommand(t, getenv, "-format", "json", "replay", "-speedup", "0", trackPath)
+	var report simReport
+	test.Expect(t, json.Unmarshal([]byte(out), &report), nil)
+	test.Expect(t, report.Locations, 2)
+	test.Expect(t, len(report.Warnings), 0)
+	test.Expect(t, len(report.Events), 1)
+	test.Expect(t, report.Events[0].TriggerID, "north")
+	test.Expect(t, report.Events[0].Message, "Made it.")
+
+	err = run([]string{"replay", filepath.Join(dir, "track.kml")}, nil, ioutil.Discard, getenv)
+	test.Expect(t, strings.Index(err.Error(), "Unknown track format: "), 0)
+}
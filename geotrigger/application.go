@@ -1,6 +1,7 @@
 package geotrigger
 
 import (
+	"context"
 	"net/url"
 )
 
@@ -16,8 +17,9 @@ type applicationTokenResponse struct {
 	ExpiresIn   int64  `json:"expires_in"`
 }
 
-func (application *application) request(route string, params interface{}, responseJSON interface{}) error {
-	return geotriggerPost(application.env, application, route, params, responseJSON)
+func (application *application) request(ctx context.Context, route string, params interface{},
+	decode responseDecoder) error {
+	return geotriggerPost(ctx, application.env, application, route, params, decode)
 }
 
 func (application *application) info() map[string]string {
@@ -28,11 +30,11 @@ func (application *application) info() map[string]string {
 	}
 }
 
-func newApplication(clientID string, clientSecret string) (session, error) {
+func newApplication(env *environment, clientID string, clientSecret string) (session, error) {
 	application := &application{
 		clientID:     clientID,
 		clientSecret: clientSecret,
-		env:          defEnv,
+		env:          env,
 	}
 	return application, application.requestAccess()
 }
@@ -74,6 +76,10 @@ func (application *application) prepareTokenRequestValues() []byte {
 	return []byte(values.Encode())
 }
 
+func (application *application) getEnv() *environment {
+	return application.env
+}
+
 func (application *application) setEnv(env *environment) {
 	application.env = env
 }
@@ -0,0 +1,196 @@
+package geotrigger
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+)
+
+// DefaultBulkParallelism is how many requests Bulk makes at once when
+// BulkOptions doesn't say.
+const DefaultBulkParallelism = 8
+
+// Operation is a single request of a bulk run: a route and its params, as
+// they would be passed to Client.Request.
+type Operation struct {
+	Route  string
+	Params interface{}
+}
+
+// BulkOptions tunes a bulk run. A nil *BulkOptions uses the defaults.
+type BulkOptions struct {
+	// requests made at once; DefaultBulkParallelism if not positive
+	Parallelism int
+	// called after each operation finishes, one call at a time, in the
+	// order the operations finish
+	Progress func(progress BulkProgress)
+}
+
+// BulkProgress counts the operations of a bulk run that have finished, and
+// how many of those failed, out of the total being run.
+type BulkProgress struct {
+	Done   int
+	Failed int
+	Total  int
+}
+
+// BulkResult is the outcome of one operation: the raw JSON response if it
+// succeeded, or why it failed. Decode a response with Unmarshal.
+type BulkResult struct {
+	Operation Operation
+	Response  json.RawMessage
+	Err       error
+	// how many times the operation has been run, counting retries
+	Attempts int
+}
+
+// BulkReport holds the result of every operation of a bulk run, in the order
+// the operations were given.
+type BulkReport struct {
+	Results []BulkResult
+}
+
+// BulkError is returned by BulkReport.Err when any operation failed. Err is
+// the first failure, in operation order.
+type BulkError struct {
+	Failed int
+	Total  int
+	Err    error
+}
+
+func (bulkError *BulkError) Error() string {
+	return fmt.Sprintf("%d of %d operations failed. %s", bulkError.Failed, bulkError.Total, bulkError.Err)
+}
+
+func (bulkError *BulkError) Unwrap() error {
+	return bulkError.Err
+}
+
+// Bulk runs many operations, such as thousands of `trigger/create` or
+// `device/update` requests, a bounded number at a time, and reports how each
+// one went. A failed operation doesn't stop the others; use BulkReport.Err to
+// see whether any failed, and RetryFailed to run just those again.
+//
+// The requests share the client's tokens, so when the access token expires
+// mid-run, one request refreshes it while the rest wait for the new token,
+// rather than each refreshing on its own. Cancelling `ctx` aborts the requests
+// in flight, and fails the operations not yet started with the context's
+// error, leaving them to be retried.
+func (client *Client) Bulk(ctx context.Context, operations []Operation, options *BulkOptions) *BulkReport {
+	report := &BulkReport{Results: make([]BulkResult, len(operations))}
+	pending := make([]int, len(operations))
+	for i, operation := range operations {
+		report.Results[i].Operation = operation
+		pending[i] = i
+	}
+
+	client.runBulk(ctx, report, pending, options)
+	return report
+}
+
+// RetryFailed runs the failed operations of a report again, returning a new
+// report in which the operations that had succeeded keep their results.
+func (client *Client) RetryFailed(ctx context.Context, report *BulkReport, options *BulkOptions) *BulkReport {
+	retried := &BulkReport{Results: append([]BulkResult(nil), report.Results...)}
+	var pending []int
+	for i, result := range report.Results {
+		if result.Err != nil {
+			pending = append(pending, i)
+		}
+	}
+
+	client.runBulk(ctx, retried, pending, options)
+	return retried
+}
+
+// runBulk runs the operations of the report at the pending indices, filling in
+// their results.
+func (client *Client) runBulk(ctx context.Context, report *BulkReport, pending []int, options *BulkOptions) {
+	parallelism := DefaultBulkParallelism
+	var progress func(progress BulkProgress)
+	if options != nil {
+		if options.Parallelism > 0 {
+			parallelism = options.Parallelism
+		}
+		progress = options.Progress
+	}
+	if parallelism > len(pending) {
+		parallelism = len(pending)
+	}
+
+	work := make(chan int)
+	finished := make(chan int)
+	var workers sync.WaitGroup
+	for i := 0; i < parallelism; i++ {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			for index := range work {
+				// each worker only touches the results it was handed
+				client.runOperation(ctx, &report.Results[index])
+				finished <- index
+			}
+		}()
+	}
+
+	go func() {
+		for _, index := range pending {
+			work <- index
+		}
+		close(work)
+		workers.Wait()
+		close(finished)
+	}()
+
+	status := BulkProgress{Total: len(pending)}
+	for index := range finished {
+		status.Done++
+		if report.Results[index].Err != nil {
+			status.Failed++
+		}
+		if progress != nil {
+			progress(status)
+		}
+	}
+}
+
+func (client *Client) runOperation(ctx context.Context, result *BulkResult) {
+	result.Response, result.Err = nil, nil
+	if err := ctx.Err(); err != nil {
+		result.Err = err
+		return
+	}
+
+	result.Attempts++
+	var response json.RawMessage
+	if err := client.request(ctx, result.Operation.Route, result.Operation.Params, bufferedDecoder(&response)); err != nil {
+		result.Err = err
+		return
+	}
+
+	result.Response = response
+}
+
+// Failures returns the results of the operations that failed, in operation
+// order.
+func (report *BulkReport) Failures() []BulkResult {
+	failures := []BulkResult{}
+	for _, result := range report.Results {
+		if result.Err != nil {
+			failures = append(failures, result)
+		}
+	}
+
+	return failures
+}
+
+// Err returns a *BulkError if any operation failed, and nil otherwise.
+func (report *BulkReport) Err() error {
+	failures := report.Failures()
+	if len(failures) == 0 {
+		return nil
+	}
+
+	return &BulkError{Failed: len(failures), Total: len(report.Results), Err: failures[0].Err}
+}
@@ -0,0 +1,266 @@
+package geotrigger
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// cachedRoutes only read, and are asked for often enough to be worth caching.
+var cachedRoutes = []string{
+	"trigger/list",
+	"tag/list",
+	"application/permissions",
+	"device/list",
+}
+
+// readRoutes change nothing, so they don't invalidate a cache either. Any
+// other route is taken to be a write.
+var readRoutes = append([]string{
+	"trigger/history",
+	"device/locations",
+	"location/last",
+	"tag/permissions",
+}, cachedRoutes...)
+
+// errorPeekSize bounds how much of a write's response is read to tell an error
+// from success. Error envelopes are far smaller.
+const errorPeekSize = 4096
+
+// ResponseCache keeps the responses of read-only routes (`trigger/list`,
+// `tag/list`, `application/permissions` and `device/list`) for a while, for
+// callers that ask for the same lists again and again, such as dashboards.
+// Responses are keyed by route, params and the client they were made for, so
+// one cache can be shared by many clients without them seeing each other's
+// data.
+//
+// When a write, such as `trigger/update`, succeeds through a client, every
+// response cached for that client is dropped, so it sees its own changes
+// straight away. Changes made by anyone else show up once the cached
+// responses expire, or the cache is invalidated. Reads made with Stream
+// aren't cached.
+type ResponseCache struct {
+	ttl     time.Duration
+	lock    sync.Mutex
+	entries map[cacheKey]*cacheEntry
+	// bumped whenever a session's responses, or every response, are dropped,
+	// so a read that was in flight at the time isn't stored after
+	generations map[string]uint64
+	epoch       uint64
+}
+
+type cacheKey struct {
+	session string
+	path    string
+	params  string
+}
+
+type cacheEntry struct {
+	body      []byte
+	header    http.Header
+	expiresAt time.Time
+}
+
+// NewResponseCache creates an empty cache keeping responses for `ttl`.
+func NewResponseCache(ttl time.Duration) *ResponseCache {
+	return &ResponseCache{
+		ttl:         ttl,
+		entries:     make(map[cacheKey]*cacheEntry),
+		generations: make(map[string]uint64),
+	}
+}
+
+// UseCache adds middleware answering the client's reads from `cache` where it
+// can, as with Use.
+func (client *Client) UseCache(cache *ResponseCache) {
+	info := client.Info()
+	client.Use(cache.middleware(info["client_id"] + "/" + info["device_id"]))
+}
+
+// Invalidate drops every cached response.
+func (cache *ResponseCache) Invalidate() {
+	cache.lock.Lock()
+	defer cache.lock.Unlock()
+
+	cache.entries = make(map[cacheKey]*cacheEntry)
+	cache.epoch++
+}
+
+// Len returns the number of responses cached, including any that have
+// expired but haven't been dropped yet.
+func (cache *ResponseCache) Len() int {
+	cache.lock.Lock()
+	defer cache.lock.Unlock()
+
+	return len(cache.entries)
+}
+
+func (cache *ResponseCache) middleware(session string) Middleware {
+	return func(next Handler) Handler {
+		return func(req *http.Request) (*http.Response, error) {
+			cached := routeIn(req.URL.Path, cachedRoutes) && !streaming(req)
+			if !cached && routeIn(req.URL.Path, readRoutes) {
+				return next(req)
+			}
+
+			var key cacheKey
+			if cached {
+				params, err := readBody(req)
+				if err != nil {
+					return nil, err
+				}
+
+				key = cacheKey{session: session, path: req.URL.Path, params: string(params)}
+				if entry := cache.get(key); entry != nil {
+					return bufferedResponse(req, 200, entry.header, entry.body), nil
+				}
+			}
+
+			generation := cache.generation(session)
+			res, err := next(req)
+			if err != nil || res.StatusCode != 200 {
+				return res, err
+			}
+
+			if !cached {
+				// errors come back as 200s too, and change nothing
+				if !peekError(res) {
+					cache.invalidateSession(session)
+				}
+				return res, nil
+			}
+
+			body, err := ioutil.ReadAll(limitBody(req, res.Body))
+			res.Body.Close()
+			if err != nil {
+				return nil, err
+			}
+			res.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+			if errorCheck(body) == nil {
+				cache.put(key, generation, &cacheEntry{body: body, header: res.Header.Clone()})
+			}
+
+			return res, nil
+		}
+	}
+}
+
+// generation returns a number that changes whenever the session's cached
+// responses are dropped.
+func (cache *ResponseCache) generation(session string) uint64 {
+	cache.lock.Lock()
+	defer cache.lock.Unlock()
+
+	return cache.epoch + cache.generations[session]
+}
+
+func (cache *ResponseCache) get(key cacheKey) *cacheEntry {
+	cache.lock.Lock()
+	defer cache.lock.Unlock()
+
+	entry := cache.entries[key]
+	if entry == nil || !time.Now().Before(entry.expiresAt) {
+		return nil
+	}
+
+	return entry
+}
+
+// put stores a response, unless the session's responses were dropped since
+// the request for it was made, as it may be from before the change that
+// dropped them.
+func (cache *ResponseCache) put(key cacheKey, generation uint64, entry *cacheEntry) {
+	cache.lock.Lock()
+	defer cache.lock.Unlock()
+
+	if cache.epoch+cache.generations[key.session] != generation {
+		return
+	}
+
+	now := time.Now()
+	for other, existing := range cache.entries {
+		if !now.Before(existing.expiresAt) {
+			delete(cache.entries, other)
+		}
+	}
+
+	entry.expiresAt = now.Add(cache.ttl)
+	cache.entries[key] = entry
+}
+
+func (cache *ResponseCache) invalidateSession(session string) {
+	cache.lock.Lock()
+	defer cache.lock.Unlock()
+
+	cache.generations[session]++
+	for key := range cache.entries {
+		if key.session == session {
+			delete(cache.entries, key)
+		}
+	}
+}
+
+// peekError reports whether a response is an error envelope, reading no more
+// of it than errorPeekSize, and leaving the whole body to be read.
+func peekError(res *http.Response) bool {
+	reader := bufio.NewReaderSize(res.Body, errorPeekSize)
+	peeked, err := reader.Peek(errorPeekSize)
+	res.Body = struct {
+		io.Reader
+		io.Closer
+	}{reader, res.Body}
+
+	// a larger response isn't an error, and an unreadable one may not be
+	return err == io.EOF && errorCheck(peeked) != nil
+}
+
+// bufferedResponse makes a fresh response for a request from a response body
+// read earlier, so the body can be handed out more than once.
+func bufferedResponse(req *http.Request, status int, header http.Header, body []byte) *http.Response {
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", status, http.StatusText(status)),
+		StatusCode:    status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header.Clone(),
+		Body:          ioutil.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}
+}
+
+// readBody reads a request's body, leaving it to be read again.
+func readBody(req *http.Request) ([]byte, error) {
+	if req.Body == nil {
+		return nil, nil
+	}
+
+	body, err := ioutil.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	req.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+	return body, nil
+}
+
+// routeIn reports whether a request path is for one of the routes, allowing
+// for a Geotrigger URL with a path of its own.
+func routeIn(path string, routes []string) bool {
+	for _, route := range routes {
+		if strings.HasSuffix(path, "/"+route) {
+			return true
+		}
+	}
+
+	return false
+}
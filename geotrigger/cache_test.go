@@ -0,0 +1,143 @@
+package geotrigger
+
+import (
+	"context"
+	"fmt"
+	"github.com/Esri/geotrigger-go/geotrigger/test"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestResponseCache(t *testing.T) {
+	var lock sync.Mutex
+	requests := make(map[string]int)
+	gtServer := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, r *http.Request) {
+		lock.Lock()
+		requests[r.URL.Path]++
+		count := requests[r.URL.Path]
+		lock.Unlock()
+
+		switch r.URL.Path {
+		case "/trigger/delete":
+			fmt.Fprintln(res, `{"error":{"code":400,"message":"No triggers found."}}`)
+		case "/tag/list":
+			fmt.Fprintln(res, `{"error":{"code":500,"message":"Try again."}}`)
+		default:
+			fmt.Fprintf(res, `{"triggers":[],"count":%d}`+"\n", count)
+		}
+	}))
+	defer gtServer.Close()
+
+	env := Environment{GeotriggerURL: gtServer.URL}
+	client := ExistingDeviceWithEnvironment(env, "good_client_id", "device_id", "good_access_token", 1800,
+		"good_refresh_token")
+	other := ExistingDeviceWithEnvironment(env, "good_client_id", "other_device_id", "good_access_token", 1800,
+		"good_refresh_token")
+	cache := NewResponseCache(time.Minute)
+	client.UseCache(cache)
+	other.UseCache(cache)
+	ctx := context.Background()
+
+	list := func(client *Client, params interface{}) float64 {
+		response, err := Do[map[string]interface{}](ctx, client, "trigger/list", params)
+		test.Expect(t, err, nil)
+		return response["count"].(float64)
+	}
+
+	// the same route and params are answered from the cache
+	test.Expect(t, list(client, nil), float64(1))
+	test.Expect(t, list(client, nil), float64(1))
+	test.Expect(t, list(client, map[string]interface{}{"tags": "pdx"}), float64(2))
+	test.Expect(t, list(client, map[string]interface{}{"tags": "pdx"}), float64(2))
+	// as are the same params, for another client
+	test.Expect(t, list(other, nil), float64(3))
+	test.Expect(t, requests["/trigger/list"], 3)
+	test.Expect(t, cache.Len(), 3)
+
+	// a write drops what was cached for the client that made it
+	_, err := Do[map[string]interface{}](ctx, client, "trigger/update", map[string]interface{}{"triggerIds": "derp"})
+	test.Expect(t, err, nil)
+	test.Expect(t, cache.Len(), 1)
+	test.Expect(t, list(client, nil), float64(4))
+	test.Expect(t, list(other, nil), float64(3))
+
+	// a write that fails changes nothing, so the cache is kept
+	_, err = Do[map[string]interface{}](ctx, client, "trigger/delete", map[string]interface{}{"triggerIds": "derp"})
+	test.Refute(t, err, nil)
+	test.Expect(t, list(client, nil), float64(4))
+
+	// reads that aren't cached don't invalidate either
+	for _, route := range []string{"trigger/history", "device/locations"} {
+		_, err = Do[map[string]interface{}](ctx, client, route, nil)
+		test.Expect(t, err, nil)
+		test.Expect(t, list(client, nil), float64(4))
+	}
+
+	// errors aren't cached
+	for i := 0; i < 2; i++ {
+		_, err = Do[map[string]interface{}](ctx, client, "tag/list", nil)
+		test.Refute(t, err, nil)
+	}
+	test.Expect(t, requests["/tag/list"], 2)
+
+	// expired responses are fetched again
+	cache.lock.Lock()
+	for _, entry := range cache.entries {
+		entry.expiresAt = time.Now().Add(-time.Second)
+	}
+	cache.lock.Unlock()
+	test.Expect(t, list(client, nil), float64(5))
+	test.Expect(t, cache.Len(), 1)
+
+	cache.Invalidate()
+	test.Expect(t, cache.Len(), 0)
+	test.Expect(t, list(other, nil), float64(6))
+}
+
+func TestResponseCacheStaleRead(t *testing.T) {
+	var lists int32
+	listing := make(chan struct{})
+	release := make(chan struct{})
+	gtServer := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/trigger/list" {
+			if count := atomic.AddInt32(&lists, 1); count == 1 {
+				close(listing)
+				<-release
+			}
+			fmt.Fprintf(res, `{"triggers":[],"count":%d}`+"\n", atomic.LoadInt32(&lists))
+			return
+		}
+		fmt.Fprintln(res, `{"triggers":[]}`)
+	}))
+	defer gtServer.Close()
+
+	client := ExistingDeviceWithEnvironment(Environment{GeotriggerURL: gtServer.URL}, "good_client_id",
+		"device_id", "good_access_token", 1800, "good_refresh_token")
+	cache := NewResponseCache(time.Minute)
+	client.UseCache(cache)
+	ctx := context.Background()
+
+	// a list made before a write lands after it
+	stale := make(chan float64)
+	go func() {
+		response, err := Do[map[string]interface{}](ctx, client, "trigger/list", nil)
+		test.Expect(t, err, nil)
+		stale <- response["count"].(float64)
+	}()
+	<-listing
+	_, err := Do[map[string]interface{}](ctx, client, "trigger/update", map[string]interface{}{"triggerIds": "derp"})
+	test.Expect(t, err, nil)
+	close(release)
+	test.Expect(t, <-stale, float64(1))
+
+	// so isn't cached
+	test.Expect(t, cache.Len(), 0)
+	response, err := Do[map[string]interface{}](ctx, client, "trigger/list", nil)
+	test.Expect(t, err, nil)
+	test.Expect(t, response["count"], float64(2))
+	test.Expect(t, cache.Len(), 1)
+}
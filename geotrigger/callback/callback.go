@@ -0,0 +1,255 @@
+// Package `callback` receives the requests the Geotrigger Service sends to a
+// trigger's `callbackUrl` when it fires. A Receiver is an `http.Handler`
+// that parses each request into an Event and passes it to the handlers
+// registered for the trigger's ID or tags:
+//
+//	receiver := callback.NewReceiver()
+//	receiver.Verify = callback.QueryToken("token", os.Getenv("CALLBACK_TOKEN"))
+//	receiver.HandleTrigger("store-entrance", func(ctx context.Context, event *callback.Event) error {
+//		log.Printf("%s is at the store", event.Device.DeviceID)
+//		return nil
+//	})
+//	http.Handle("/geotrigger", receiver)
+//
+// The service doesn't sign its callbacks, so the usual way to check that a
+// request came from it is a secret token in the trigger's `callbackUrl`,
+// checked with QueryToken. HMACSignature is there for callbacks relayed
+// through something that does sign them.
+package callback
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/Esri/geotrigger-go/geotrigger"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+// DefaultMaxBodySize is the largest callback body a Receiver reads, unless
+// its MaxBodySize says otherwise.
+const DefaultMaxBodySize = 1 << 20
+
+// Event is the payload of a trigger callback: the trigger that fired, the
+// device that fired it, and where the device was at the time. As with
+// `geotrigger.Unmarshal`, anything in the payload without a field of its own
+// is kept in Extra.
+type Event struct {
+	Trigger  geotrigger.Trigger     `json:"trigger"`
+	Device   Device                 `json:"device"`
+	Location geotrigger.Location    `json:"location"`
+	Extra    map[string]interface{} `json:"-"`
+}
+
+// Device is the device a callback was fired for.
+type Device struct {
+	DeviceID        string                 `json:"deviceId"`
+	Tags            []string               `json:"tags,omitempty"`
+	Properties      map[string]interface{} `json:"properties,omitempty"`
+	TrackingProfile string                 `json:"trackingProfile,omitempty"`
+	Extra           map[string]interface{} `json:"-"`
+}
+
+// HandlerFunc handles a single callback. Returning an error makes the
+// Receiver respond with a 500, so the failure shows up on the service's side.
+type HandlerFunc func(ctx context.Context, event *Event) error
+
+// Verifier checks that a callback request is genuine, given the request and
+// its body. Returning an error makes the Receiver respond with a 401 without
+// calling any handlers.
+type Verifier func(r *http.Request, body []byte) error
+
+// Receiver is an `http.Handler` for trigger callbacks. Handlers can be
+// registered while it is serving.
+type Receiver struct {
+	// if set, every request must pass it before being handled
+	Verify Verifier
+	// largest body read, DefaultMaxBodySize if zero
+	MaxBodySize int64
+
+	lock      sync.RWMutex
+	byTrigger map[string][]HandlerFunc
+	byTag     map[string][]HandlerFunc
+	fallback  []HandlerFunc
+}
+
+// NewReceiver creates a Receiver with no handlers. Until some are registered,
+// callbacks are accepted and dropped.
+func NewReceiver() *Receiver {
+	return &Receiver{
+		byTrigger: make(map[string][]HandlerFunc),
+		byTag:     make(map[string][]HandlerFunc),
+	}
+}
+
+// HandleTrigger registers a handler for callbacks from the trigger with the
+// provided ID.
+func (receiver *Receiver) HandleTrigger(triggerID string, handler HandlerFunc) {
+	receiver.lock.Lock()
+	defer receiver.lock.Unlock()
+
+	receiver.byTrigger[triggerID] = append(receiver.byTrigger[triggerID], handler)
+}
+
+// HandleTag registers a handler for callbacks from any trigger with the
+// provided tag.
+func (receiver *Receiver) HandleTag(tag string, handler HandlerFunc) {
+	receiver.lock.Lock()
+	defer receiver.lock.Unlock()
+
+	receiver.byTag[tag] = append(receiver.byTag[tag], handler)
+}
+
+// HandleDefault registers a handler for callbacks that no trigger ID or tag
+// handler matched.
+func (receiver *Receiver) HandleDefault(handler HandlerFunc) {
+	receiver.lock.Lock()
+	defer receiver.lock.Unlock()
+
+	receiver.fallback = append(receiver.fallback, handler)
+}
+
+// ServeHTTP parses a callback and calls its handlers, in the order: trigger ID
+// handlers, then tag handlers in the order of the trigger's tags. Default
+// handlers are only called if there were none of either. Handling stops at
+// the first handler to return an error.
+func (receiver *Receiver) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "Callbacks must be POSTed.", http.StatusMethodNotAllowed)
+		return
+	}
+
+	maxBodySize := receiver.MaxBodySize
+	if maxBodySize <= 0 {
+		maxBodySize = DefaultMaxBodySize
+	}
+
+	body, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, maxBodySize))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Could not read callback. %s", err), http.StatusBadRequest)
+		return
+	}
+
+	if receiver.Verify != nil {
+		if err := receiver.Verify(r, body); err != nil {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+	}
+
+	event, err := Parse(r.Header.Get("Content-Type"), body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	for _, handler := range receiver.handlers(event) {
+		if err := handler(r.Context(), event); err != nil {
+			http.Error(w, fmt.Sprintf("Error handling callback for trigger %s. %s",
+				event.Trigger.TriggerID, err), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// handlers collects the handlers for an event.
+func (receiver *Receiver) handlers(event *Event) []HandlerFunc {
+	receiver.lock.RLock()
+	defer receiver.lock.RUnlock()
+
+	var handlers []HandlerFunc
+	handlers = append(handlers, receiver.byTrigger[event.Trigger.TriggerID]...)
+	for _, tag := range event.Trigger.Tags {
+		handlers = append(handlers, receiver.byTag[tag]...)
+	}
+
+	if len(handlers) == 0 {
+		handlers = append(handlers, receiver.fallback...)
+	}
+
+	return handlers
+}
+
+// Parse reads a callback body into an Event. The body is either JSON, or a
+// form whose `trigger`, `device` and `location` fields each hold JSON.
+func Parse(contentType string, body []byte) (*Event, error) {
+	event := &Event{}
+
+	if strings.HasPrefix(contentType, "application/x-www-form-urlencoded") {
+		form, err := url.ParseQuery(string(body))
+		if err != nil {
+			return nil, fmt.Errorf("Could not parse callback form. %s", err)
+		}
+
+		fields := map[string]interface{}{
+			"trigger":  &event.Trigger,
+			"device":   &event.Device,
+			"location": &event.Location,
+		}
+		for name, value := range fields {
+			if raw := form.Get(name); len(raw) > 0 {
+				if err := geotrigger.Unmarshal([]byte(raw), value); err != nil {
+					return nil, fmt.Errorf("Could not parse callback %s. %s", name, err)
+				}
+			}
+		}
+	} else if err := geotrigger.Unmarshal(body, event); err != nil {
+		return nil, fmt.Errorf("Could not parse callback. %s", err)
+	}
+
+	if len(event.Trigger.TriggerID) == 0 {
+		return nil, errors.New("Callback has no trigger ID.")
+	}
+
+	return event, nil
+}
+
+// QueryToken verifies callbacks by a secret token in a query parameter of the
+// trigger's `callbackUrl`, such as `https://example.com/geotrigger?token=...`.
+func QueryToken(name string, token string) Verifier {
+	return func(r *http.Request, body []byte) error {
+		if !equal(r.URL.Query().Get(name), token) {
+			return errors.New("Invalid callback token.")
+		}
+		return nil
+	}
+}
+
+// HMACSignature verifies callbacks by a hex encoded HMAC-SHA256 of the body,
+// keyed by `secret`, in the provided header. A `sha256=` prefix on the
+// header's value is ignored.
+func HMACSignature(header string, secret string) Verifier {
+	return func(r *http.Request, body []byte) error {
+		signature := strings.TrimPrefix(r.Header.Get(header), "sha256=")
+		if len(signature) == 0 {
+			return fmt.Errorf("Missing callback signature header %s.", header)
+		}
+
+		if !equal(strings.ToLower(signature), Sign(secret, body)) {
+			return errors.New("Invalid callback signature.")
+		}
+		return nil
+	}
+}
+
+func equal(a string, b string) bool {
+	return len(b) > 0 && subtle.ConstantTimeCompare([]byte(a), []byte(b)) == 1
+}
+
+// Sign returns the signature HMACSignature expects for a body, for whatever
+// relays callbacks, and for tests.
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,166 @@
+package callback
+
+import (
+	"context"
+	"errors"
+	"github.com/Esri/geotrigger-go/geotrigger/test"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+/* editing these will break tests */
+var callbackData = `{"trigger":{"triggerId":"store-entrance","condition":{"direction":"enter","geo":{"latitude":45.5165,"longitude":-122.6764,"distance":100}},"action":{"callbackUrl":"https://example.com/geotrigger"},"tags":["stores","portland"],"properties":{"store":"downtown"}},"device":{"deviceId":"dev1","tags":["device:dev1"],"properties":{"name":"kiosk"},"trackingProfile":"adaptive","lastSeen":"2014-05-14T18:00:00Z"},"location":{"latitude":45.5166,"longitude":-122.6765,"accuracy":10,"timestamp":"2014-05-14T18:00:00Z"},"fired":true}`
+
+func post(handler http.Handler, target string, contentType string, body string) *httptest.ResponseRecorder {
+	request := httptest.NewRequest(http.MethodPost, target, strings.NewReader(body))
+	request.Header.Set("Content-Type", contentType)
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, request)
+	return recorder
+}
+
+func TestParse(t *testing.T) {
+	event, err := Parse("application/json", []byte(callbackData))
+	test.Expect(t, err, nil)
+	test.Expect(t, event.Trigger.TriggerID, "store-entrance")
+	test.Expect(t, event.Trigger.Condition.Direction, "enter")
+	test.Expect(t, event.Trigger.Properties["store"], "downtown")
+	test.Expect(t, event.Device.DeviceID, "dev1")
+	test.Expect(t, event.Device.Properties["name"], "kiosk")
+	test.Expect(t, event.Device.TrackingProfile, "adaptive")
+	test.Expect(t, event.Device.Extra["lastSeen"], "2014-05-14T18:00:00Z")
+	test.Expect(t, event.Location.Latitude, 45.5166)
+	test.Expect(t, event.Location.Timestamp.Unix(), int64(1400090400))
+	test.Expect(t, event.Extra["fired"], true)
+}
+
+func TestParseForm(t *testing.T) {
+	form := url.Values{
+		"trigger":  {`{"triggerId":"store-entrance","tags":["stores"]}`},
+		"device":   {`{"deviceId":"dev1"}`},
+		"location": {`{"latitude":45.5166,"longitude":-122.6765}`},
+	}
+	event, err := Parse("application/x-www-form-urlencoded", []byte(form.Encode()))
+	test.Expect(t, err, nil)
+	test.Expect(t, event.Trigger.TriggerID, "store-entrance")
+	test.Expect(t, event.Device.DeviceID, "dev1")
+	test.Expect(t, event.Location.Longitude, -122.6765)
+
+	form.Set("device", "not json")
+	_, err = Parse("application/x-www-form-urlencoded", []byte(form.Encode()))
+	test.Refute(t, err, nil)
+	test.Expect(t, strings.HasPrefix(err.Error(), "Could not parse callback device."), true)
+}
+
+func TestParseErrors(t *testing.T) {
+	_, err := Parse("application/json", []byte(`{"trigger":`))
+	test.Refute(t, err, nil)
+	test.Expect(t, strings.HasPrefix(err.Error(), "Could not parse callback."), true)
+
+	_, err = Parse("application/json", []byte(`{"device":{"deviceId":"dev1"}}`))
+	test.Refute(t, err, nil)
+	test.Expect(t, err.Error(), "Callback has no trigger ID.")
+}
+
+func TestReceiverDispatch(t *testing.T) {
+	receiver := NewReceiver()
+	var called []string
+	record := func(name string) HandlerFunc {
+		return func(ctx context.Context, event *Event) error {
+			called = append(called, name+":"+event.Device.DeviceID)
+			return nil
+		}
+	}
+	receiver.HandleTag("portland", record("portland"))
+	receiver.HandleTrigger("store-entrance", record("trigger"))
+	receiver.HandleTag("stores", record("stores"))
+	receiver.HandleTag("seattle", record("seattle"))
+	receiver.HandleDefault(record("default"))
+
+	recorder := post(receiver, "/geotrigger", "application/json", callbackData)
+	test.Expect(t, recorder.Code, http.StatusOK)
+	test.Expect(t, called, []string{"trigger:dev1", "stores:dev1", "portland:dev1"})
+
+	called = nil
+	recorder = post(receiver, "/geotrigger", "application/json", `{"trigger":{"triggerId":"other"},"device":{"deviceId":"dev2"}}`)
+	test.Expect(t, recorder.Code, http.StatusOK)
+	test.Expect(t, called, []string{"default:dev2"})
+}
+
+func TestReceiverErrors(t *testing.T) {
+	receiver := NewReceiver()
+	receiver.HandleTrigger("store-entrance", func(ctx context.Context, event *Event) error {
+		return errors.New("Database is down.")
+	})
+
+	recorder := httptest.NewRecorder()
+	receiver.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/geotrigger", nil))
+	test.Expect(t, recorder.Code, http.StatusMethodNotAllowed)
+	test.Expect(t, recorder.Header().Get("Allow"), http.MethodPost)
+
+	recorder = post(receiver, "/geotrigger", "application/json", `{`)
+	test.Expect(t, recorder.Code, http.StatusBadRequest)
+
+	recorder = post(receiver, "/geotrigger", "application/json", callbackData)
+	test.Expect(t, recorder.Code, http.StatusInternalServerError)
+	test.Expect(t, strings.TrimSpace(recorder.Body.String()),
+		"Error handling callback for trigger store-entrance. Database is down.")
+
+	receiver.MaxBodySize = 16
+	recorder = post(receiver, "/geotrigger", "application/json", callbackData)
+	test.Expect(t, recorder.Code, http.StatusBadRequest)
+}
+
+func TestQueryToken(t *testing.T) {
+	receiver := NewReceiver()
+	receiver.Verify = QueryToken("token", "s3cret")
+	handled := 0
+	receiver.HandleDefault(func(ctx context.Context, event *Event) error {
+		handled++
+		return nil
+	})
+
+	recorder := post(receiver, "/geotrigger?token=s3cret", "application/json", callbackData)
+	test.Expect(t, recorder.Code, http.StatusOK)
+
+	recorder = post(receiver, "/geotrigger?token=wrong", "application/json", callbackData)
+	test.Expect(t, recorder.Code, http.StatusUnauthorized)
+
+	recorder = post(receiver, "/geotrigger", "application/json", callbackData)
+	test.Expect(t, recorder.Code, http.StatusUnauthorized)
+	test.Expect(t, handled, 1)
+
+	// an empty token never verifies anything
+	receiver.Verify = QueryToken("token", "")
+	recorder = post(receiver, "/geotrigger?token=", "application/json", callbackData)
+	test.Expect(t, recorder.Code, http.StatusUnauthorized)
+}
+
+func TestHMACSignature(t *testing.T) {
+	receiver := NewReceiver()
+	receiver.Verify = HMACSignature("X-Signature", "s3cret")
+
+	signed := func(signature string) *httptest.ResponseRecorder {
+		request := httptest.NewRequest(http.MethodPost, "/geotrigger", strings.NewReader(callbackData))
+		request.Header.Set("Content-Type", "application/json")
+		if len(signature) > 0 {
+			request.Header.Set("X-Signature", signature)
+		}
+		recorder := httptest.NewRecorder()
+		receiver.ServeHTTP(recorder, request)
+		return recorder
+	}
+
+	signature := Sign("s3cret", []byte(callbackData))
+	test.Expect(t, signed(signature).Code, http.StatusOK)
+	test.Expect(t, signed("sha256="+signature).Code, http.StatusOK)
+	test.Expect(t, signed(strings.ToUpper(signature)).Code, http.StatusOK)
+	test.Expect(t, signed(Sign("other", []byte(callbackData))).Code, http.StatusUnauthorized)
+
+	recorder := signed("")
+	test.Expect(t, recorder.Code, http.StatusUnauthorized)
+	test.Expect(t, strings.TrimSpace(recorder.Body.String()), "Missing callback signature header X-Signature.")
+}
@@ -9,23 +9,67 @@
 // https://developers.arcgis.com/en/geotrigger-service/
 package geotrigger
 
+import (
+	"context"
+	"net/http"
+)
+
 // Client manages credentials for an ArcGIS Application or Device based on what
 // you pass in to the provided constructors.
 type Client struct {
 	session
 }
 
+// Environment holds the base URLs of the services a Client talks to, and the
+// HTTP client used to reach them. Empty fields fall back to the production
+// Geotrigger Service and ArcGIS Online, and to `http.DefaultClient`.
+//
+// MaxResponseSize caps the number of bytes read from any one response; a
+// larger response fails the request. MaxRequestSize likewise caps the JSON
+// sent as params; larger params fail with a ValidationError before anything is
+// sent. Zero means no limit.
+//
+// Middleware wraps every request to the Geotrigger Service, in order, after
+// the default middleware; see Middleware.
+//
+// Provided primarily as a way of pointing a client at a test server, such as the
+// one in the `github.com/Esri/geotrigger-go/geotrigger/geotriggertest` package.
+type Environment struct {
+	GeotriggerURL   string
+	AGOURL          string
+	HTTPClient      *http.Client
+	MaxResponseSize int64
+	MaxRequestSize  int64
+	Middleware      []Middleware
+}
+
 // NewApplication creates and registers a new application associated with the
 // provided client_id and client_secret.
 func NewApplication(clientID string, clientSecret string) (*Client, error) {
-	session, err := newApplication(clientID, clientSecret)
+	session, err := newApplication(defEnv, clientID, clientSecret)
+
+	return &Client{session}, err
+}
+
+// NewApplicationWithEnvironment is like NewApplication, but talks to the
+// services described by `env`.
+func NewApplicationWithEnvironment(env Environment, clientID string, clientSecret string) (*Client, error) {
+	session, err := newApplication(env.internal(), clientID, clientSecret)
 
 	return &Client{session}, err
 }
 
 // NewDevice creates and registers a new device associated with the provided client_id.
 func NewDevice(clientID string) (*Client, error) {
-	session, err := newDevice(clientID)
+	session, err := newDevice(defEnv, clientID)
+
+	return &Client{session}, err
+}
+
+// NewDeviceWithEnvironment is like NewDevice, but talks to the services
+// described by `env`.
+func NewDeviceWithEnvironment(env Environment, clientID string) (*Client, error) {
+	session, err := newDevice(env.internal(), clientID)
 
 	return &Client{session}, err
 }
@@ -34,10 +78,22 @@ func NewDevice(clientID string) (*Client, error) {
 //
 // Provided primarily as a way of debugging an active mobile install.
 func ExistingDevice(clientID string, deviceID string, accessToken string, expiresIn int64, refreshToken string) *Client {
+	return existingDevice(defEnv, clientID, deviceID, accessToken, expiresIn, refreshToken)
+}
+
+// ExistingDeviceWithEnvironment is like ExistingDevice, but talks to the
+// services described by `env`.
+func ExistingDeviceWithEnvironment(env Environment, clientID string, deviceID string, accessToken string,
+	expiresIn int64, refreshToken string) *Client {
+	return existingDevice(env.internal(), clientID, deviceID, accessToken, expiresIn, refreshToken)
+}
+
+func existingDevice(env *environment, clientID string, deviceID string, accessToken string,
+	expiresIn int64, refreshToken string) *Client {
 	device := &device{
 		clientID: clientID,
 		deviceID: deviceID,
-		env:      defEnv,
+		env:      env,
 	}
 
 	device.tokenManager = newTokenManager(accessToken, refreshToken, expiresIn)
@@ -49,10 +105,33 @@ func ExistingDevice(clientID string, deviceID string, accessToken string, expire
 //
 // `response` can be a pointer to a struct modeling the expected JSON, or to an
 // arbitrary JSON map (`map[string]interface{}`) that can then be used with the
-// helper methods `GetValueFromJSONObject` and `GetValueFromJSONArray` provided
-// in the `github.com/Esri/geotrigger-go/geotrigger/json` package.
+// helpers provided in the `github.com/Esri/geotrigger-go/geotrigger/json`
+// package, such as `Get`, `Index` and `GetValueFromJSONObject`.
+//
+// A struct, or any struct within it, can keep what it doesn't model by
+// declaring `Raw json.RawMessage` and `Extra map[string]interface{}` fields
+// tagged `json:"-"`. They are filled with the JSON object the struct was
+// decoded from, and with the keys of it that no other field matched.
 func (client *Client) Request(route string, params interface{}, response interface{}) error {
-	return client.request(route, params, response)
+	return client.request(context.Background(), route, params, bufferedDecoder(response))
+}
+
+// Do performs an API request like `Client.Request`, decoding the response into
+// a new value of type Resp, which is checked at compile time rather than when
+// the response arrives, ie:
+//
+//	response, err := geotrigger.Do[map[string]interface{}](ctx, client, "trigger/list", params)
+//
+// Cancelling `ctx`, or letting its deadline pass, aborts the HTTP request to
+// the Geotrigger Service. Typed wrappers around individual routes build on this.
+func Do[Resp any](ctx context.Context, client *Client, route string, params interface{}) (Resp, error) {
+	var response Resp
+	if err := client.request(ctx, route, params, bufferedDecoder(&response)); err != nil {
+		var zero Resp
+		return zero, err
+	}
+
+	return response, nil
 }
 
 // Info returns information about the current session.
@@ -63,3 +142,10 @@ func (client *Client) Request(route string, params interface{}, response interfa
 func (client *Client) Info() map[string]string {
 	return client.info()
 }
+
+// Close stops the routine that manages the client's tokens. Requests made with
+// a closed client fail. Closing a client more than once is harmless.
+func (client *Client) Close() error {
+	client.stop()
+	return nil
+}
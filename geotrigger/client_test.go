@@ -1,6 +1,7 @@
 package geotrigger
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"github.com/Esri/geotrigger-go/geotrigger/test"
@@ -10,6 +11,7 @@ import (
 	"net/url"
 	"strings"
 	"testing"
+	"time"
 )
 
 func TestClientWithNewApplication(t *testing.T) {
@@ -103,3 +105,63 @@ func TestClientWithExistingDevice(t *testing.T) {
 	err := client.Request("/some/route", params, &responseJSON)
 	test.Expect(t, err, nil)
 }
+
+func TestEnvironmentDefaults(t *testing.T) {
+	env := Environment{}.internal()
+	test.Expect(t, env.geotriggerURL, geotrigger_base_url)
+	test.Expect(t, env.agoURL, ago_base_url)
+
+	env = Environment{GeotriggerURL: "http://localhost:1234"}.internal()
+	test.Expect(t, env.geotriggerURL, "http://localhost:1234")
+	test.Expect(t, env.agoURL, ago_base_url)
+
+	// the shared default environment is never modified
+	test.Expect(t, defEnv.geotriggerURL, geotrigger_base_url)
+}
+
+func TestDo(t *testing.T) {
+	gtServer := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, r *http.Request) {
+		test.Expect(t, r.URL.Path, "/trigger/list")
+		fmt.Fprintln(res, `{"triggers":[{"triggerId":"derp","tags":["herp"]}]}`)
+	}))
+	defer gtServer.Close()
+
+	client := ExistingDeviceWithEnvironment(Environment{GeotriggerURL: gtServer.URL},
+		"good_client_id", "device_id", "good_access_token", 1800, "good_refresh_token")
+
+	type triggerList struct {
+		Triggers []Trigger `json:"triggers"`
+	}
+
+	response, err := Do[triggerList](context.Background(), client, "trigger/list", nil)
+	test.Expect(t, err, nil)
+	test.Expect(t, len(response.Triggers), 1)
+	test.Expect(t, response.Triggers[0].TriggerID, "derp")
+
+	pointer, err := Do[*triggerList](context.Background(), client, "trigger/list", nil)
+	test.Expect(t, err, nil)
+	test.Expect(t, pointer.Triggers[0].Tags, []string{"herp"})
+}
+
+func TestDoCancelled(t *testing.T) {
+	done := make(chan struct{})
+	gtServer := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, r *http.Request) {
+		select {
+		case <-done:
+		case <-r.Context().Done():
+		}
+	}))
+	defer gtServer.Close()
+	defer close(done)
+
+	client := ExistingDeviceWithEnvironment(Environment{GeotriggerURL: gtServer.URL},
+		"good_client_id", "device_id", "good_access_token", 1800, "good_refresh_token")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	response, err := Do[map[string]interface{}](ctx, client, "trigger/list", nil)
+	test.Refute(t, err, nil)
+	test.Expect(t, strings.Contains(err.Error(), "context deadline exceeded"), true)
+	test.Expect(t, response, map[string]interface{}(nil))
+}
@@ -1,6 +1,7 @@
 package geotrigger
 
 import (
+	"context"
 	"net/url"
 )
 
@@ -32,8 +33,9 @@ type deviceRefreshResponse struct {
 	ExpiresIn   int64  `json:"expires_in"`
 }
 
-func (device *device) request(route string, params interface{}, responseJSON interface{}) error {
-	return geotriggerPost(device.env, device, route, params, responseJSON)
+func (device *device) request(ctx context.Context, route string, params interface{},
+	decode responseDecoder) error {
+	return geotriggerPost(ctx, device.env, device, route, params, decode)
 }
 
 func (device *device) info() map[string]string {
@@ -45,10 +47,10 @@ func (device *device) info() map[string]string {
 	}
 }
 
-func newDevice(clientID string) (session, error) {
+func newDevice(env *environment, clientID string) (session, error) {
 	device := &device{
 		clientID: clientID,
-		env:      defEnv,
+		env:      env,
 	}
 
 	return device, device.register()
@@ -93,6 +95,10 @@ func (device *device) refresh(refreshToken string) error {
 	return nil
 }
 
+func (device *device) getEnv() *environment {
+	return device.env
+}
+
 func (device *device) setEnv(env *environment) {
 	device.env = env
 }
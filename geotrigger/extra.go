@@ -0,0 +1,234 @@
+package geotrigger
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// Typed responses can keep what the models don't know about. After a response
+// is decoded into a struct, any struct in it (at any depth) that declares
+//
+//	Raw   json.RawMessage        `json:"-"`
+//	Extra map[string]interface{} `json:"-"`
+//
+// gets `Raw` set to the JSON object it was decoded from, and `Extra` set to
+// the keys of that object that no field matched, decoded as with
+// `map[string]interface{}`. `Extra` is nil when every key was matched.
+// Either field can be declared without the other. Types with their own
+// `UnmarshalJSON` are left alone.
+//
+// This lets models stay forward compatible with fields added to the service,
+// without reaching for a `map[string]interface{}` response.
+
+var (
+	rawMessageType  = reflect.TypeOf(json.RawMessage(nil))
+	extraType       = reflect.TypeOf(map[string]interface{}(nil))
+	unmarshalerType = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+)
+
+// structFields describes how the keys of a JSON object map onto a struct.
+type structFields struct {
+	fields map[string][]int
+	raw    []int
+	extra  []int
+}
+
+// cache of reflect.Type -> *structFields, and reflect.Type -> bool for wantsExtras
+var fieldsCache, wantsCache sync.Map
+
+// Unmarshal is json.Unmarshal, followed by filling in any `Raw` and `Extra`
+// fields as Client.Request does, for JSON that didn't come from a request,
+// such as the body of a trigger callback.
+func Unmarshal(data []byte, value interface{}) error {
+	if err := json.Unmarshal(data, value); err != nil {
+		return err
+	}
+
+	fillExtras(reflect.ValueOf(value), data)
+	return nil
+}
+
+// fillExtras walks `value` alongside the JSON it was decoded from, filling in
+// `Raw` and `Extra` fields. Anything that doesn't line up is skipped, as
+// encoding/json has already decided what the value holds.
+func fillExtras(value reflect.Value, data []byte) {
+	if !wantsExtras(value.Type()) {
+		return
+	}
+
+	switch value.Kind() {
+	case reflect.Ptr:
+		if !value.IsNil() {
+			fillExtras(value.Elem(), data)
+		}
+	case reflect.Struct:
+		var object map[string]json.RawMessage
+		if err := json.Unmarshal(data, &object); err != nil || object == nil {
+			return
+		}
+
+		fields := fieldsOf(value.Type())
+		if fields.raw != nil {
+			value.FieldByIndex(fields.raw).SetBytes(append([]byte(nil), data...))
+		}
+
+		var extra map[string]interface{}
+		for key, inner := range object {
+			if index := fields.lookup(key); index != nil {
+				fillExtras(value.FieldByIndex(index), inner)
+				continue
+			}
+
+			var unknown interface{}
+			if err := json.Unmarshal(inner, &unknown); err == nil {
+				if extra == nil {
+					extra = make(map[string]interface{})
+				}
+				extra[key] = unknown
+			}
+		}
+
+		if fields.extra != nil {
+			value.FieldByIndex(fields.extra).Set(reflect.ValueOf(extra))
+		}
+	case reflect.Slice, reflect.Array:
+		var array []json.RawMessage
+		if err := json.Unmarshal(data, &array); err != nil {
+			return
+		}
+
+		for i := 0; i < len(array) && i < value.Len(); i++ {
+			fillExtras(value.Index(i), array[i])
+		}
+	case reflect.Map:
+		var object map[string]json.RawMessage
+		if err := json.Unmarshal(data, &object); err != nil || value.Type().Key().Kind() != reflect.String {
+			return
+		}
+
+		for key, inner := range object {
+			mapKey := reflect.ValueOf(key).Convert(value.Type().Key())
+			element := value.MapIndex(mapKey)
+			if !element.IsValid() {
+				continue
+			}
+
+			// map elements can't be set in place, so fill a copy and put it back
+			copied := reflect.New(element.Type()).Elem()
+			copied.Set(element)
+			fillExtras(copied, inner)
+			value.SetMapIndex(mapKey, copied)
+		}
+	}
+}
+
+// wantsExtras reports whether a value of type `t` can hold a `Raw` or `Extra`
+// field anywhere, so the work of walking it can be skipped when it can't.
+func wantsExtras(t reflect.Type) bool {
+	if cached, ok := wantsCache.Load(t); ok {
+		return cached.(bool)
+	}
+
+	wants := searchExtras(t, make(map[reflect.Type]bool))
+	wantsCache.Store(t, wants)
+	return wants
+}
+
+// searchExtras does the work of wantsExtras, skipping types already being
+// searched, as a type can refer to itself.
+func searchExtras(t reflect.Type, visiting map[reflect.Type]bool) bool {
+	if visiting[t] {
+		return false
+	}
+	visiting[t] = true
+
+	switch t.Kind() {
+	case reflect.Ptr, reflect.Slice, reflect.Array, reflect.Map:
+		return searchExtras(t.Elem(), visiting)
+	case reflect.Struct:
+		if reflect.PtrTo(t).Implements(unmarshalerType) {
+			return false
+		}
+
+		fields := fieldsOf(t)
+		if fields.raw != nil || fields.extra != nil {
+			return true
+		}
+
+		for _, index := range fields.fields {
+			if searchExtras(t.FieldByIndex(index).Type, visiting) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+func fieldsOf(t reflect.Type) *structFields {
+	if cached, ok := fieldsCache.Load(t); ok {
+		return cached.(*structFields)
+	}
+
+	fields := &structFields{fields: make(map[string][]int)}
+	collectFields(t, nil, fields)
+	fieldsCache.Store(t, fields)
+	return fields
+}
+
+// collectFields finds the JSON names of the fields of a struct, including
+// those promoted from embedded structs, the way encoding/json does.
+func collectFields(t reflect.Type, parent []int, fields *structFields) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		index := append(append([]int(nil), parent...), i)
+		tag := field.Tag.Get("json")
+		name := strings.Split(tag, ",")[0]
+
+		if tag == "-" {
+			switch {
+			case field.Name == "Raw" && field.Type == rawMessageType && parent == nil:
+				fields.raw = index
+			case field.Name == "Extra" && field.Type == extraType && parent == nil:
+				fields.extra = index
+			}
+			continue
+		}
+
+		if field.Anonymous && len(name) == 0 && field.Type.Kind() == reflect.Struct {
+			collectFields(field.Type, index, fields)
+			continue
+		}
+
+		if len(field.PkgPath) > 0 {
+			// unexported
+			continue
+		}
+
+		if len(name) == 0 {
+			name = field.Name
+		}
+
+		// fields closer to the top win, as with encoding/json
+		if _, taken := fields.fields[name]; !taken || parent == nil {
+			fields.fields[name] = index
+		}
+	}
+}
+
+// lookup finds the field a key decodes into, ignoring case as encoding/json does.
+func (fields *structFields) lookup(key string) []int {
+	if index, ok := fields.fields[key]; ok {
+		return index
+	}
+
+	for name, index := range fields.fields {
+		if strings.EqualFold(name, key) {
+			return index
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,86 @@
+package geotrigger
+
+import (
+	"encoding/json"
+	"github.com/Esri/geotrigger-go/geotrigger/test"
+	"reflect"
+	"testing"
+)
+
+type extraTriggerList struct {
+	Triggers    []Trigger              `json:"triggers"`
+	BoundingBox map[string]float64     `json:"boundingBox"`
+	Raw         json.RawMessage        `json:"-"`
+	Extra       map[string]interface{} `json:"-"`
+}
+
+type extraNode struct {
+	Name     string                 `json:"name"`
+	Children []extraNode            `json:"children"`
+	Byname   map[string]*extraNode  `json:"byName"`
+	Extra    map[string]interface{} `json:"-"`
+}
+
+type extraEmbedded struct {
+	Trigger
+	Note  string                 `json:"note"`
+	Extra map[string]interface{} `json:"-"`
+}
+
+func TestFillExtras(t *testing.T) {
+	var list extraTriggerList
+	err := parseJSONResponse(triggerListData, &list)
+	test.Expect(t, err, nil)
+
+	test.Expect(t, string(list.Raw), string(triggerListData))
+	test.Expect(t, list.Extra, nil)
+	test.Expect(t, len(list.Triggers), 1)
+
+	trigger := list.Triggers[0]
+	test.Expect(t, trigger.TriggerID, "6fd01180fa1a012f27f1705681b27197")
+	test.Expect(t, trigger.Extra, nil)
+	test.Expect(t, trigger.Condition.Extra, nil)
+	// the geocode context and the old style callback aren't modeled
+	test.Expect(t, trigger.Condition.Geo.Extra["context"].(map[string]interface{})["zipcode"], "97204")
+	test.Expect(t, trigger.Action.Extra, map[string]interface{}{"callback": "http://pdx.gov/welcome"})
+
+	// nested slices, maps and pointers, in a recursive type
+	var tree extraNode
+	err = parseJSONResponse([]byte(`{"name":"root","shiny":true,"children":[{"name":"a","children":[{"name":"b","age":3}]}],"byName":{"c":{"name":"c","color":"red"}}}`), &tree)
+	test.Expect(t, err, nil)
+	test.Expect(t, tree.Extra, map[string]interface{}{"shiny": true})
+	test.Expect(t, tree.Children[0].Extra, nil)
+	test.Expect(t, tree.Children[0].Children[0].Extra, map[string]interface{}{"age": float64(3)})
+	test.Expect(t, tree.Byname["c"].Extra, map[string]interface{}{"color": "red"})
+
+	// keys are matched ignoring case, and through embedded structs
+	var embedded extraEmbedded
+	err = parseJSONResponse([]byte(`{"TRIGGERID":"derp","note":"hi","owner":"me"}`), &embedded)
+	test.Expect(t, err, nil)
+	test.Expect(t, embedded.TriggerID, "derp")
+	test.Expect(t, embedded.Extra, map[string]interface{}{"owner": "me"})
+
+	// a reused value doesn't keep stale extras
+	err = parseJSONResponse([]byte(`{"note":"hi"}`), &embedded)
+	test.Expect(t, err, nil)
+	test.Expect(t, embedded.Extra, nil)
+}
+
+func TestWantsExtras(t *testing.T) {
+	test.Expect(t, wantsExtras(reflect.TypeOf(Trigger{})), true)
+	test.Expect(t, wantsExtras(reflect.TypeOf([]*Trigger{})), true)
+	test.Expect(t, wantsExtras(reflect.TypeOf(extraNode{})), true)
+	test.Expect(t, wantsExtras(reflect.TypeOf(Location{})), false)
+	test.Expect(t, wantsExtras(reflect.TypeOf(map[string]interface{}{})), false)
+}
+
+func TestUnmarshal(t *testing.T) {
+	var trigger Trigger
+	err := Unmarshal([]byte(`{"triggerId":"derp","action":{"message":"hi","sms":"555"}}`), &trigger)
+	test.Expect(t, err, nil)
+	test.Expect(t, trigger.TriggerID, "derp")
+	test.Expect(t, trigger.Action.Extra, map[string]interface{}{"sms": "555"})
+
+	err = Unmarshal([]byte(`[]`), &trigger)
+	test.Refute(t, err, nil)
+}
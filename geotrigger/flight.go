@@ -0,0 +1,148 @@
+package geotrigger
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// errPanicked is what the reads waiting on one whose request panicked get,
+// before they make requests of their own.
+var errPanicked = errors.New("Request being waited on panicked.")
+
+// flightGroup holds a client's reads in flight, see UseDedupe.
+type flightGroup struct {
+	lock  sync.Mutex
+	calls map[flightKey]*flight
+}
+
+type flightKey struct {
+	url string
+	// every header the request is sent with, as written on the wire
+	header string
+	params string
+}
+
+type flight struct {
+	// closed once the fields below are set
+	done   chan struct{}
+	status int
+	header http.Header
+	body   []byte
+	err    error
+}
+
+// UseDedupe makes reads of the same route, with the same params and headers,
+// made while an identical read is in flight wait for its response rather than
+// make requests of their own, so a burst of goroutines asking for the same
+// list (such as on a cache miss) costs one request. This is to responses what
+// manageTokens is to token refreshes. Writes are always sent, as are reads
+// made with Stream, whose responses would have to be held in memory to be
+// shared.
+//
+// Reads are matched as they are sent, after every middleware has run, so
+// headers set by middleware tell reads apart. A read that gives up waiting,
+// through its context, does so without affecting the others. If the read
+// being waited for is given up on, or panics, those waiting make their own
+// requests.
+func (client *Client) UseDedupe() {
+	env := *client.getEnv()
+	env.flights = &flightGroup{calls: make(map[flightKey]*flight)}
+	client.setEnv(&env)
+}
+
+// dedupe is the innermost middleware of a chain, when UseDedupe has been
+// called.
+func (group *flightGroup) dedupe(env *environment, next Handler) Handler {
+	return func(req *http.Request) (*http.Response, error) {
+		if !routeIn(req.URL.Path, readRoutes) || streaming(req) {
+			return next(req)
+		}
+
+		params, err := readBody(req)
+		if err != nil {
+			return nil, err
+		}
+		var header strings.Builder
+		if err := req.Header.Write(&header); err != nil {
+			return nil, err
+		}
+		key := flightKey{url: req.URL.String(), header: header.String(), params: string(params)}
+
+		group.lock.Lock()
+		if call, ok := group.calls[key]; ok {
+			group.lock.Unlock()
+
+			select {
+			case <-call.done:
+			case <-req.Context().Done():
+				return nil, req.Context().Err()
+			}
+			if call.abandoned() && req.Context().Err() == nil {
+				// only the request waited for was given up on
+				return next(req)
+			}
+			if call.err != nil {
+				return nil, call.err
+			}
+
+			return bufferedResponse(req, call.status, call.header, call.body), nil
+		}
+
+		call := &flight{done: make(chan struct{})}
+		group.calls[key] = call
+		group.lock.Unlock()
+
+		// release those waiting even if the request panics
+		landed := false
+		defer func() {
+			if !landed {
+				call.err = errPanicked
+			}
+
+			group.lock.Lock()
+			delete(group.calls, key)
+			group.lock.Unlock()
+			close(call.done)
+		}()
+
+		call.fly(env, next, req)
+		landed = true
+
+		if call.err != nil {
+			return nil, call.err
+		}
+
+		return bufferedResponse(req, call.status, call.header, call.body), nil
+	}
+}
+
+// fly makes the request, reading the whole response to share it.
+func (call *flight) fly(env *environment, next Handler, req *http.Request) {
+	res, err := next(req)
+	if err != nil {
+		call.err = err
+		return
+	}
+	defer res.Body.Close()
+
+	body, err := ioutil.ReadAll(limitResponse(env, req.URL.Path, res.Body))
+	if err != nil {
+		call.err = err
+		return
+	}
+
+	call.status = res.StatusCode
+	call.header = res.Header
+	call.body = body
+}
+
+// abandoned reports whether the request waited for ended without a response
+// of its own, by being given up on or panicking.
+func (call *flight) abandoned() bool {
+	return errors.Is(call.err, errPanicked) || errors.Is(call.err, context.Canceled) ||
+		errors.Is(call.err, context.DeadlineExceeded)
+}
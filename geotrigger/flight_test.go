@@ -0,0 +1,290 @@
+package geotrigger
+
+import (
+	"context"
+	"fmt"
+	"github.com/Esri/geotrigger-go/geotrigger/test"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// waitingContext closes `waiting` the first time something waits on it, as a
+// read does once it has joined the identical read in flight.
+type waitingContext struct {
+	context.Context
+	once    sync.Once
+	waiting chan struct{}
+}
+
+func newWaitingContext(parent context.Context) *waitingContext {
+	return &waitingContext{Context: parent, waiting: make(chan struct{})}
+}
+
+func (ctx *waitingContext) Done() <-chan struct{} {
+	ctx.once.Do(func() {
+		close(ctx.waiting)
+	})
+	return ctx.Context.Done()
+}
+
+// roundTripFunc lets a test stand in for the HTTP transport.
+type roundTripFunc func(req *http.Request) (*http.Response, error)
+
+func (roundTrip roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return roundTrip(req)
+}
+
+func TestDedupe(t *testing.T) {
+	var requests int32
+	release := make(chan struct{})
+	gtServer := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, r *http.Request) {
+		count := atomic.AddInt32(&requests, 1)
+		if r.URL.Path == "/trigger/list" {
+			<-release
+		}
+		fmt.Fprintf(res, `{"triggers":[],"count":%d}`+"\n", count)
+	}))
+	defer gtServer.Close()
+
+	client := ExistingDeviceWithEnvironment(Environment{GeotriggerURL: gtServer.URL}, "good_client_id",
+		"device_id", "good_access_token", 1800, "good_refresh_token")
+	client.UseDedupe()
+
+	var wait sync.WaitGroup
+	counts := make([]interface{}, 10)
+	for i := range counts {
+		// the read sent waits on its response, the others on the read sent
+		ctx := newWaitingContext(context.Background())
+		wait.Add(1)
+		go func(i int) {
+			defer wait.Done()
+			response, err := Do[map[string]interface{}](ctx, client, "trigger/list", map[string]interface{}{"tags": "pdx"})
+			test.Expect(t, err, nil)
+			counts[i] = response["count"]
+		}(i)
+		<-ctx.waiting
+	}
+
+	close(release)
+	wait.Wait()
+
+	test.Expect(t, atomic.LoadInt32(&requests), int32(1))
+	for _, count := range counts {
+		test.Expect(t, count, float64(1))
+	}
+
+	// once it has landed, the next read is made afresh
+	var response map[string]interface{}
+	test.Expect(t, client.Request("trigger/list", map[string]interface{}{"tags": "pdx"}, &response), nil)
+	test.Expect(t, response["count"], float64(2))
+
+	// writes are always sent
+	for i := 0; i < 3; i++ {
+		test.Expect(t, client.Request("trigger/update", map[string]interface{}{"triggerIds": "derp"}, &response), nil)
+	}
+	test.Expect(t, atomic.LoadInt32(&requests), int32(5))
+}
+
+// concurrentReads makes two identical reads at once, with the region in their
+// contexts, returning how many requests the server saw. The server holds the
+// first request until the second arrives, or gives up after a while if the
+// second read is waiting on the first.
+func concurrentReads(t *testing.T, dedupe bool, regions [2]string) int32 {
+	var requests int32
+	second := make(chan struct{})
+	gtServer := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&requests, 1) == 1 {
+			select {
+			case <-second:
+			case <-time.After(5 * time.Second):
+			}
+		} else {
+			close(second)
+		}
+		fmt.Fprint(res, `{"devices":[]}`)
+	}))
+	defer gtServer.Close()
+
+	client := ExistingDeviceWithEnvironment(Environment{GeotriggerURL: gtServer.URL}, "good_client_id",
+		"device_id", "good_access_token", 1800, "good_refresh_token")
+	client.Use(func(next Handler) Handler {
+		return func(req *http.Request) (*http.Response, error) {
+			req.Header.Set("X-Region", req.Context().Value(regionKey{}).(string))
+			return next(req)
+		}
+	})
+	if dedupe {
+		client.UseDedupe()
+	}
+
+	var wait sync.WaitGroup
+	for _, region := range regions {
+		wait.Add(1)
+		go func(region string) {
+			defer wait.Done()
+			_, err := Do[map[string]interface{}](context.WithValue(context.Background(), regionKey{}, region), client,
+				"device/list", nil)
+			test.Expect(t, err, nil)
+		}(region)
+	}
+	wait.Wait()
+
+	return atomic.LoadInt32(&requests)
+}
+
+type regionKey struct{}
+
+func TestDedupeIsOptIn(t *testing.T) {
+	test.Expect(t, concurrentReads(t, false, [2]string{"us", "us"}), int32(2))
+}
+
+func TestDedupeKeysOnHeaders(t *testing.T) {
+	// reads told apart by a header set in middleware aren't shared
+	test.Expect(t, concurrentReads(t, true, [2]string{"us", "eu"}), int32(2))
+}
+
+func TestDedupePanic(t *testing.T) {
+	var requests int32
+	follower := newWaitingContext(context.Background())
+	transport := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		if atomic.AddInt32(&requests, 1) == 1 {
+			<-follower.waiting
+			panic("boom")
+		}
+		return &http.Response{
+			StatusCode: 200,
+			Header:     http.Header{},
+			Body:       ioutil.NopCloser(strings.NewReader(`{"count":2}`)),
+			Request:    req,
+		}, nil
+	})
+
+	env := Environment{GeotriggerURL: "http://geotrigger.test", HTTPClient: &http.Client{Transport: transport}}
+	client := ExistingDeviceWithEnvironment(env, "good_client_id", "device_id", "good_access_token", 1800,
+		"good_refresh_token")
+	client.UseDedupe()
+
+	recovered := make(chan interface{})
+	go func() {
+		defer func() {
+			recovered <- recover()
+		}()
+		Do[map[string]interface{}](context.Background(), client, "device/list", nil)
+	}()
+	for atomic.LoadInt32(&requests) == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	// the read waiting on one that panics is released, and makes its own
+	response, err := Do[map[string]interface{}](follower, client, "device/list", nil)
+	test.Expect(t, err, nil)
+	test.Expect(t, response["count"], float64(2))
+	test.Expect(t, <-recovered, "boom")
+}
+
+func TestDedupeCancelled(t *testing.T) {
+	var requests int32
+	release := make(chan struct{})
+	gtServer := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, r *http.Request) {
+		count := atomic.AddInt32(&requests, 1)
+		if count == 1 {
+			<-release
+		}
+		fmt.Fprintf(res, `{"count":%d}`+"\n", count)
+	}))
+	defer gtServer.Close()
+	defer close(release)
+
+	client := ExistingDeviceWithEnvironment(Environment{GeotriggerURL: gtServer.URL}, "good_client_id",
+		"device_id", "good_access_token", 1800, "good_refresh_token")
+	client.UseDedupe()
+
+	// the first request, which the others wait for, is given up on
+	leaderCtx, cancelLeader := context.WithCancel(context.Background())
+	leaderErr := make(chan error)
+	go func() {
+		_, err := Do[map[string]interface{}](leaderCtx, client, "device/list", nil)
+		leaderErr <- err
+	}()
+	for atomic.LoadInt32(&requests) == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	// a waiting request that gives up returns straight away
+	followerCtx, cancelFollower := context.WithCancel(context.Background())
+	waiting := newWaitingContext(followerCtx)
+	followerErr := make(chan error)
+	go func() {
+		_, err := Do[map[string]interface{}](waiting, client, "device/list", nil)
+		followerErr <- err
+	}()
+	<-waiting.waiting
+	cancelFollower()
+	test.Expect(t, strings.HasSuffix((<-followerErr).Error(), "context canceled"), true)
+
+	// and one that is still wanted makes its own request when the first is
+	// given up on
+	waiting = newWaitingContext(context.Background())
+	response := make(chan map[string]interface{})
+	go func() {
+		result, err := Do[map[string]interface{}](waiting, client, "device/list", nil)
+		test.Expect(t, err, nil)
+		response <- result
+	}()
+	<-waiting.waiting
+	cancelLeader()
+	test.Expect(t, strings.HasSuffix((<-leaderErr).Error(), "context canceled"), true)
+	test.Expect(t, (<-response)["count"], float64(2))
+}
+
+func TestStreamIsNotBuffered(t *testing.T) {
+	routes := []string{"trigger/history", "device/locations", "trigger/list"}
+	firstRecord := make(map[string]chan struct{})
+	finished := make(map[string]*int32)
+	for _, route := range routes {
+		firstRecord["/"+route] = make(chan struct{})
+		finished["/"+route] = new(int32)
+	}
+
+	gtServer := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(res, `{"logs":[{"triggerId":"first"}`)
+		res.(http.Flusher).Flush()
+
+		// the rest of the export is only sent once the first record has been
+		// handled, which a middleware buffering the response would wait on
+		select {
+		case <-firstRecord[r.URL.Path]:
+		case <-time.After(5 * time.Second):
+		}
+		for i := 0; i < 1000; i++ {
+			fmt.Fprintf(res, `,{"triggerId":"trigger%d"}`, i)
+		}
+		fmt.Fprint(res, `]}`)
+		atomic.StoreInt32(finished[r.URL.Path], 1)
+	}))
+	defer gtServer.Close()
+
+	client := ExistingDeviceWithEnvironment(Environment{GeotriggerURL: gtServer.URL}, "good_client_id",
+		"device_id", "good_access_token", 1800, "good_refresh_token")
+	client.UseCache(NewResponseCache(time.Minute))
+
+	for _, route := range routes {
+		count := 0
+		err := Stream(context.Background(), client, route, nil, "logs", func(event HistoryEvent) error {
+			if count == 0 {
+				test.Expect(t, atomic.LoadInt32(finished["/"+route]), int32(0))
+				close(firstRecord["/"+route])
+			}
+			count++
+			return nil
+		})
+		test.Expect(t, err, nil)
+		test.Expect(t, count, 1001)
+	}
+}
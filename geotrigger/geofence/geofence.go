@@ -0,0 +1,289 @@
+// Package `geofence` evaluates device locations against Geotrigger triggers
+// locally, reporting the enter and leave events the Geotrigger Service would
+// report for the same locations. It is meant for testing trigger designs and
+// location feeds offline, before anything is sent through `Client.Request`.
+package geofence
+
+import (
+	"errors"
+	"fmt"
+	"github.com/Esri/geotrigger-go/geotrigger"
+	"math"
+	"sync"
+	"time"
+)
+
+// EarthRadius is the mean radius of the earth in meters, as Distance uses.
+const EarthRadius = 6371008.8
+
+// Event is reported when a device crosses a fence in the direction its trigger
+// is listening for.
+type Event struct {
+	TriggerID string
+	DeviceID  string
+	Direction string
+	Location  geotrigger.Location
+	Trigger   geotrigger.Trigger
+}
+
+// Engine holds a set of triggers and the last known position of each device
+// relative to their fences. It is safe for concurrent use.
+//
+// A trigger with `Times` set stops firing once it has fired that many times,
+// across devices, and one with `RateLimit` set fires for each device at most
+// once in that many seconds, going by the locations' timestamps. Where the
+// service deletes a trigger that has used up its `Times`, the engine only
+// stops firing it.
+type Engine struct {
+	fences []*fence
+	// device id -> trigger id -> whether the device was last seen inside
+	inside map[string]map[string]bool
+	// trigger id -> number of events fired
+	fired map[string]int
+	// device id -> trigger id -> when the last event fired
+	lastFired map[string]map[string]time.Time
+	lock      sync.Mutex
+}
+
+type fence struct {
+	trigger  geotrigger.Trigger
+	contains func(latitude, longitude float64) bool
+}
+
+// NewEngine prepares the fences of the provided triggers, typically the
+// `triggers` array of a `trigger/list` response. An error is returned if a
+// trigger has a fence that can't be evaluated offline, such as a geocode.
+func NewEngine(triggers []geotrigger.Trigger) (*Engine, error) {
+	engine := &Engine{
+		inside:    make(map[string]map[string]bool),
+		fired:     make(map[string]int),
+		lastFired: make(map[string]map[string]time.Time),
+	}
+
+	for _, trigger := range triggers {
+		contains, err := containsFunc(&trigger.Condition.Geo)
+		if err != nil {
+			return nil, fmt.Errorf("Could not evaluate fence of trigger %s. %s", trigger.TriggerID, err)
+		}
+
+		engine.fences = append(engine.fences, &fence{trigger, contains})
+	}
+
+	return engine, nil
+}
+
+// Update moves a device to a new location and returns the events fired by
+// the move, in trigger order. `tags` are the tags of the device; as on the
+// service, every device also implicitly has the tag `device:<deviceID>`.
+//
+// A device the engine has not seen before is considered outside of every
+// fence, so its first location can fire enter events but never leave events.
+// Locations with a zero timestamp are treated as happening now.
+func (engine *Engine) Update(deviceID string, tags []string, location geotrigger.Location) []Event {
+	engine.lock.Lock()
+	defer engine.lock.Unlock()
+
+	state, ok := engine.inside[deviceID]
+	if !ok {
+		state = make(map[string]bool)
+		engine.inside[deviceID] = state
+	}
+	lastFired, ok := engine.lastFired[deviceID]
+	if !ok {
+		lastFired = make(map[string]time.Time)
+		engine.lastFired[deviceID] = lastFired
+	}
+
+	at := location.Timestamp
+	if at.IsZero() {
+		at = time.Now()
+	}
+
+	deviceTags := append([]string{"device:" + deviceID}, tags...)
+
+	var events []Event
+	for _, fence := range engine.fences {
+		trigger := fence.trigger
+		if !sharesTag(trigger.Tags, deviceTags) {
+			continue
+		}
+
+		wasInside := state[trigger.TriggerID]
+		isInside := fence.contains(location.Latitude, location.Longitude)
+		state[trigger.TriggerID] = isInside
+
+		var direction string
+		switch {
+		case !wasInside && isInside:
+			direction = geotrigger.DirectionEnter
+		case wasInside && !isInside:
+			direction = geotrigger.DirectionLeave
+		default:
+			continue
+		}
+
+		if direction != trigger.Condition.Direction || !inWindow(&trigger.Condition, at) {
+			continue
+		}
+		if trigger.Times > 0 && engine.fired[trigger.TriggerID] >= trigger.Times {
+			continue
+		}
+		last, firedBefore := lastFired[trigger.TriggerID]
+		if trigger.RateLimit > 0 && firedBefore && at.Sub(last) < time.Duration(trigger.RateLimit)*time.Second {
+			continue
+		}
+		engine.fired[trigger.TriggerID]++
+		lastFired[trigger.TriggerID] = at
+
+		events = append(events, Event{
+			TriggerID: trigger.TriggerID,
+			DeviceID:  deviceID,
+			Direction: direction,
+			Location:  location,
+			Trigger:   trigger,
+		})
+	}
+
+	return events
+}
+
+// Reset forgets the last known position of every device, and the events
+// fired so far, as counted against `Times` and `RateLimit`.
+func (engine *Engine) Reset() {
+	engine.lock.Lock()
+	defer engine.lock.Unlock()
+
+	engine.inside = make(map[string]map[string]bool)
+	engine.fired = make(map[string]int)
+	engine.lastFired = make(map[string]map[string]time.Time)
+}
+
+func sharesTag(triggerTags []string, deviceTags []string) bool {
+	for _, triggerTag := range triggerTags {
+		for _, deviceTag := range deviceTags {
+			if triggerTag == deviceTag {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+func inWindow(condition *geotrigger.Condition, at time.Time) bool {
+	if condition.FromTimestamp != nil && at.Before(*condition.FromTimestamp) {
+		return false
+	}
+
+	if condition.ToTimestamp != nil && at.After(*condition.ToTimestamp) {
+		return false
+	}
+
+	return true
+}
+
+func containsFunc(geo *geotrigger.Geo) (func(float64, float64) bool, error) {
+	switch {
+	case geo.IsCircle():
+		centerLat, centerLng, radius := geo.Latitude, geo.Longitude, geo.Distance
+		return func(latitude, longitude float64) bool {
+			return Distance(centerLat, centerLng, latitude, longitude) <= radius
+		}, nil
+	case geo.GeoJSON != nil:
+		polygons, err := geo.GeoJSON.Polygons()
+		if err != nil {
+			return nil, err
+		}
+
+		return func(latitude, longitude float64) bool {
+			for _, rings := range polygons {
+				if inRings(rings, longitude, latitude) {
+					return true
+				}
+			}
+			return false
+		}, nil
+	case geo.EsriJSON != nil:
+		rings, err := esriRings(geo.EsriJSON)
+		if err != nil {
+			return nil, err
+		}
+
+		return func(latitude, longitude float64) bool {
+			return inRings(rings, longitude, latitude)
+		}, nil
+	case len(geo.Geocode) > 0:
+		return nil, fmt.Errorf("Geocoded fences (%s) are resolved by the service and can't be evaluated locally.", geo.Geocode)
+	}
+
+	return nil, errors.New("No distance, geojson or esrijson provided.")
+}
+
+// Distance returns the great-circle distance in meters between two points.
+func Distance(latitude1, longitude1, latitude2, longitude2 float64) float64 {
+	lat1 := latitude1 * math.Pi / 180
+	lat2 := latitude2 * math.Pi / 180
+	deltaLat := lat2 - lat1
+	deltaLng := (longitude2 - longitude1) * math.Pi / 180
+
+	a := math.Sin(deltaLat/2)*math.Sin(deltaLat/2) +
+		math.Cos(lat1)*math.Cos(lat2)*math.Sin(deltaLng/2)*math.Sin(deltaLng/2)
+
+	return 2 * EarthRadius * math.Atan2(math.Sqrt(a), math.Sqrt(1-a))
+}
+
+// inRings uses the even-odd rule, so holes (and, for Esri geometries, multiple
+// outer rings) are handled without looking at ring orientation.
+func inRings(rings [][][]float64, x, y float64) bool {
+	inside := false
+	for _, ring := range rings {
+		for i, j := 0, len(ring)-1; i < len(ring); j, i = i, i+1 {
+			if len(ring[i]) < 2 || len(ring[j]) < 2 {
+				continue
+			}
+
+			xi, yi := ring[i][0], ring[i][1]
+			xj, yj := ring[j][0], ring[j][1]
+			if (yi > y) != (yj > y) && x < (xj-xi)*(y-yi)/(yj-yi)+xi {
+				inside = !inside
+			}
+		}
+	}
+
+	return inside
+}
+
+// esriRings returns the rings of an Esri geometry in longitude/latitude,
+// converting from web mercator when needed.
+func esriRings(esri *geotrigger.EsriJSON) ([][][]float64, error) {
+	wkid := 4326
+	if sr := esri.SpatialReference; sr != nil {
+		if sr.LatestWKID != 0 {
+			wkid = sr.LatestWKID
+		} else if sr.WKID != 0 {
+			wkid = sr.WKID
+		}
+	}
+
+	switch wkid {
+	case 4326:
+		return esri.Rings, nil
+	case 3857, 102100, 102113:
+		rings := make([][][]float64, len(esri.Rings))
+		for i, ring := range esri.Rings {
+			rings[i] = make([][]float64, len(ring))
+			for j, point := range ring {
+				if len(point) < 2 {
+					return nil, fmt.Errorf("Invalid point in ring %d.", i)
+				}
+				rings[i][j] = []float64{
+					point[0] / 6378137 * 180 / math.Pi,
+					(2*math.Atan(math.Exp(point[1]/6378137)) - math.Pi/2) * 180 / math.Pi,
+				}
+			}
+		}
+		return rings, nil
+	}
+
+	return nil, fmt.Errorf("Unsupported spatial reference: %d.", wkid)
+}
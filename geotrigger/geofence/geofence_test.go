@@ -0,0 +1,159 @@
+package geofence
+
+import (
+	"encoding/json"
+	"github.com/Esri/geotrigger-go/geotrigger"
+	"github.com/Esri/geotrigger-go/geotrigger/test"
+	"testing"
+	"time"
+)
+
+/* editing these will break tests */
+var triggerListData = []byte(`{"triggers":[
+{"triggerId":"circle-enter","condition":{"direction":"enter","geo":{"latitude":45.5165,"longitude":-122.6764,"distance":100}},"action":{"message":"hello"},"tags":["portland"]},
+{"triggerId":"circle-leave","condition":{"direction":"leave","geo":{"latitude":45.5165,"longitude":-122.6764,"distance":100}},"action":{"message":"bye"},"tags":["portland"]},
+{"triggerId":"donut","condition":{"direction":"enter","geo":{"geojson":{"type":"Polygon","coordinates":[[[-122.7,45.5],[-122.6,45.5],[-122.6,45.6],[-122.7,45.6],[-122.7,45.5]],[[-122.66,45.54],[-122.64,45.54],[-122.64,45.56],[-122.66,45.56],[-122.66,45.54]]]}}},"action":{"message":"donut"},"tags":["device:dev2"]},
+{"triggerId":"esri","condition":{"direction":"enter","geo":{"esrijson":{"rings":[[[-122.7,45.5],[-122.7,45.6],[-122.6,45.6],[-122.6,45.5],[-122.7,45.5]]],"spatialReference":{"wkid":4326}}},"fromTimestamp":"2014-01-01T00:00:00Z","toTimestamp":"2014-01-02T00:00:00Z"},"action":{"message":"esri"},"tags":["portland"]}
+]}`)
+
+func getTriggers(t *testing.T) []geotrigger.Trigger {
+	var triggerList struct {
+		Triggers []geotrigger.Trigger `json:"triggers"`
+	}
+	err := json.Unmarshal(triggerListData, &triggerList)
+	test.Expect(t, err, nil)
+	test.Expect(t, len(triggerList.Triggers), 4)
+	return triggerList.Triggers
+}
+
+func eventIDs(events []Event) []string {
+	var ids []string
+	for _, event := range events {
+		ids = append(ids, event.TriggerID)
+	}
+	return ids
+}
+
+func TestDistance(t *testing.T) {
+	test.Expect(t, Distance(45.5, -122.6, 45.5, -122.6), float64(0))
+
+	// one degree of latitude is about 111km
+	d := Distance(45, -122, 46, -122)
+	if d < 111000 || d > 111400 {
+		t.Errorf("Unexpected distance for one degree of latitude: %f", d)
+	}
+}
+
+func TestEngineCircle(t *testing.T) {
+	engine, err := NewEngine(getTriggers(t))
+	test.Expect(t, err, nil)
+
+	tags := []string{"portland"}
+	outside := geotrigger.Location{Latitude: 45.52, Longitude: -122.68}
+	inside := geotrigger.Location{Latitude: 45.5166, Longitude: -122.6765}
+
+	// first location outside of everything fires nothing, not even a leave
+	events := engine.Update("dev1", tags, outside)
+	test.Expect(t, len(events), 0)
+
+	events = engine.Update("dev1", tags, inside)
+	test.Expect(t, eventIDs(events), []string{"circle-enter"})
+	test.Expect(t, events[0].DeviceID, "dev1")
+	test.Expect(t, events[0].Direction, geotrigger.DirectionEnter)
+	test.Expect(t, events[0].Trigger.Action.Message, "hello")
+
+	// staying inside fires nothing
+	events = engine.Update("dev1", tags, inside)
+	test.Expect(t, len(events), 0)
+
+	events = engine.Update("dev1", tags, outside)
+	test.Expect(t, eventIDs(events), []string{"circle-leave"})
+
+	// devices without a matching tag are ignored
+	events = engine.Update("dev3", []string{"seattle"}, inside)
+	test.Expect(t, len(events), 0)
+
+	// first location inside fires an enter
+	engine.Reset()
+	events = engine.Update("dev1", tags, inside)
+	test.Expect(t, eventIDs(events), []string{"circle-enter"})
+}
+
+func TestEnginePolygons(t *testing.T) {
+	engine, err := NewEngine(getTriggers(t))
+	test.Expect(t, err, nil)
+
+	// the hole of the donut
+	hole := geotrigger.Location{Latitude: 45.55, Longitude: -122.65}
+	ring := geotrigger.Location{Latitude: 45.52, Longitude: -122.62}
+
+	events := engine.Update("dev2", nil, hole)
+	test.Expect(t, len(events), 0)
+	events = engine.Update("dev2", nil, ring)
+	test.Expect(t, eventIDs(events), []string{"donut"})
+
+	// esri trigger only fires inside its time window
+	during := time.Date(2014, 1, 1, 12, 0, 0, 0, time.UTC)
+	after := time.Date(2014, 1, 3, 12, 0, 0, 0, time.UTC)
+	away := geotrigger.Location{Latitude: 40, Longitude: -120, Timestamp: during}
+
+	engine.Update("dev4", []string{"portland"}, away)
+	events = engine.Update("dev4", []string{"portland"}, geotrigger.Location{Latitude: 45.52, Longitude: -122.62, Timestamp: during})
+	test.Expect(t, eventIDs(events), []string{"esri"})
+
+	engine.Update("dev5", []string{"portland"}, away)
+	events = engine.Update("dev5", []string{"portland"}, geotrigger.Location{Latitude: 45.52, Longitude: -122.62, Timestamp: after})
+	test.Expect(t, len(events), 0)
+}
+
+func TestEngineUnsupportedFence(t *testing.T) {
+	triggers := []geotrigger.Trigger{{
+		TriggerID: "geocoded",
+		Condition: geotrigger.Condition{
+			Direction: geotrigger.DirectionEnter,
+			Geo:       geotrigger.Geo{Geocode: "920 SW 3rd Ave, Portland, OR", DriveTime: 600},
+		},
+	}}
+
+	engine, err := NewEngine(triggers)
+	test.Expect(t, engine, nil)
+	test.Refute(t, err, nil)
+	test.Expect(t, err.Error(), "Could not evaluate fence of trigger geocoded. Geocoded fences (920 SW 3rd Ave, Portland, OR) are resolved by the service and can't be evaluated locally.")
+
+	triggers[0].Condition.Geo = geotrigger.Geo{GeoJSON: &geotrigger.GeoJSON{Type: "Point"}}
+	_, err = NewEngine(triggers)
+	test.Refute(t, err, nil)
+	test.Expect(t, err.Error(), "Could not evaluate fence of trigger geocoded. Unsupported GeoJSON geometry type: Point.")
+}
+
+func TestEngineTimesAndRateLimit(t *testing.T) {
+	circle := geotrigger.Geo{Latitude: 45.5165, Longitude: -122.6764, Distance: 100}
+	engine, err := NewEngine([]geotrigger.Trigger{
+		{TriggerID: "twice", Condition: geotrigger.Condition{Direction: geotrigger.DirectionEnter, Geo: circle}, Tags: []string{"twice"}, Times: 2},
+		{TriggerID: "hourly", Condition: geotrigger.Condition{Direction: geotrigger.DirectionEnter, Geo: circle}, Tags: []string{"hourly"}, RateLimit: 3600},
+	})
+	test.Expect(t, err, nil)
+
+	start := time.Date(2014, 1, 1, 12, 0, 0, 0, time.UTC)
+	enter := func(deviceID string, tag string, at time.Duration) []string {
+		engine.Update(deviceID, []string{tag}, geotrigger.Location{Latitude: 40, Longitude: -120, Timestamp: start.Add(at)})
+		return eventIDs(engine.Update(deviceID, []string{tag}, geotrigger.Location{Latitude: 45.5166, Longitude: -122.6765, Timestamp: start.Add(at)}))
+	}
+
+	// two events in all, across devices
+	test.Expect(t, enter("dev1", "twice", 0), []string{"twice"})
+	test.Expect(t, enter("dev2", "twice", 0), []string{"twice"})
+	test.Expect(t, len(enter("dev3", "twice", 0)), 0)
+	test.Expect(t, len(enter("dev1", "twice", 24*time.Hour)), 0)
+
+	// one event an hour, for each device
+	test.Expect(t, enter("dev1", "hourly", 0), []string{"hourly"})
+	test.Expect(t, len(enter("dev1", "hourly", 59*time.Minute)), 0)
+	test.Expect(t, enter("dev2", "hourly", 59*time.Minute), []string{"hourly"})
+	test.Expect(t, enter("dev1", "hourly", time.Hour), []string{"hourly"})
+
+	// both start over
+	engine.Reset()
+	test.Expect(t, enter("dev3", "twice", 0), []string{"twice"})
+	test.Expect(t, enter("dev1", "hourly", time.Hour), []string{"hourly"})
+}
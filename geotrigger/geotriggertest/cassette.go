@@ -0,0 +1,264 @@
+package geotriggertest
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/Esri/geotrigger-go/geotrigger"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+// scrubbed values are replaced by this, both when recording and when matching
+// requests during replay
+const scrubbed = "[scrubbed]"
+
+// keys holding credentials, in form bodies and JSON objects alike
+var secretKeys = map[string]bool{
+	"access_token":  true,
+	"refresh_token": true,
+	"client_secret": true,
+}
+
+// Interaction is a single recorded request and the response it got.
+type Interaction struct {
+	Method         string      `json:"method"`
+	Path           string      `json:"path"`
+	RequestBody    string      `json:"requestBody"`
+	Status         int         `json:"status"`
+	ResponseHeader http.Header `json:"responseHeader"`
+	ResponseBody   string      `json:"responseBody"`
+}
+
+// Cassette is the on-disk format of a recording.
+type Cassette struct {
+	Interactions []Interaction `json:"interactions"`
+}
+
+// Recorder is an `http.RoundTripper` that either records the exchanges a
+// Client has with the services into a cassette file, or replays a cassette
+// file without touching the network. Tokens and secrets are scrubbed from
+// everything that is recorded.
+//
+// Hand a Recorder to a Client through its environment:
+//
+//	recorder := geotriggertest.NewRecorder("testdata/triggers.json", nil)
+//	client, err := geotrigger.NewApplicationWithEnvironment(recorder.Environment(), id, secret)
+//	...
+//	err = recorder.Save()
+type Recorder struct {
+	path      string
+	transport http.RoundTripper
+	replaying bool
+
+	lock     sync.Mutex
+	cassette Cassette
+	used     []bool
+}
+
+// NewRecorder returns a Recorder that passes requests through to `transport`
+// (`http.DefaultTransport` if nil) and records them. Call Save to write the
+// cassette to `path`.
+func NewRecorder(path string, transport http.RoundTripper) *Recorder {
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+
+	return &Recorder{
+		path:      path,
+		transport: transport,
+	}
+}
+
+// NewReplayer returns a Recorder that answers requests from the cassette at
+// `path`. Each recorded interaction is served once, in recorded order, to the
+// first request with the same method, path and (scrubbed) body.
+func NewReplayer(path string) (*Recorder, error) {
+	contents, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("Could not read cassette %s. %s", path, err)
+	}
+
+	recorder := &Recorder{
+		path:      path,
+		replaying: true,
+	}
+	if err := json.Unmarshal(contents, &recorder.cassette); err != nil {
+		return nil, fmt.Errorf("Could not parse cassette %s. %s", path, err)
+	}
+	recorder.used = make([]bool, len(recorder.cassette.Interactions))
+
+	return recorder, nil
+}
+
+// Environment returns an environment that sends every request through the
+// Recorder. The production URLs are kept, so a cassette recorded against the
+// real services replays against them too.
+func (recorder *Recorder) Environment() geotrigger.Environment {
+	return geotrigger.Environment{
+		HTTPClient: &http.Client{Transport: recorder},
+	}
+}
+
+// Interactions returns a copy of what has been recorded, or loaded for
+// replay, so far.
+func (recorder *Recorder) Interactions() []Interaction {
+	recorder.lock.Lock()
+	defer recorder.lock.Unlock()
+
+	return append([]Interaction(nil), recorder.cassette.Interactions...)
+}
+
+// Save writes the recorded interactions to the cassette file.
+func (recorder *Recorder) Save() error {
+	if recorder.replaying {
+		return errors.New("Cannot save a cassette that is being replayed.")
+	}
+
+	recorder.lock.Lock()
+	contents, err := json.MarshalIndent(&recorder.cassette, "", "  ")
+	recorder.lock.Unlock()
+	if err != nil {
+		return fmt.Errorf("Could not encode cassette. %s", err)
+	}
+
+	return ioutil.WriteFile(recorder.path, contents, 0644)
+}
+
+// RoundTrip implements `http.RoundTripper`.
+func (recorder *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil {
+		var err error
+		if body, err = ioutil.ReadAll(req.Body); err != nil {
+			return nil, err
+		}
+		req.Body.Close()
+		req.Body = ioutil.NopCloser(bytes.NewReader(body))
+	}
+
+	scrubbedBody := scrub(body)
+	if recorder.replaying {
+		return recorder.replay(req, scrubbedBody)
+	}
+
+	resp, err := recorder.transport.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	contents, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = ioutil.NopCloser(bytes.NewReader(contents))
+
+	header := http.Header{}
+	for key, values := range resp.Header {
+		if key != "Set-Cookie" {
+			header[key] = values
+		}
+	}
+
+	recorder.lock.Lock()
+	recorder.cassette.Interactions = append(recorder.cassette.Interactions, Interaction{
+		Method:         req.Method,
+		Path:           req.URL.Path,
+		RequestBody:    scrubbedBody,
+		Status:         resp.StatusCode,
+		ResponseHeader: header,
+		ResponseBody:   scrub(contents),
+	})
+	recorder.lock.Unlock()
+
+	return resp, nil
+}
+
+func (recorder *Recorder) replay(req *http.Request, body string) (*http.Response, error) {
+	recorder.lock.Lock()
+	defer recorder.lock.Unlock()
+
+	for i, interaction := range recorder.cassette.Interactions {
+		if recorder.used[i] || interaction.Method != req.Method || interaction.Path != req.URL.Path ||
+			interaction.RequestBody != body {
+			continue
+		}
+
+		recorder.used[i] = true
+		return &http.Response{
+			Status:        fmt.Sprintf("%d %s", interaction.Status, http.StatusText(interaction.Status)),
+			StatusCode:    interaction.Status,
+			Proto:         "HTTP/1.1",
+			ProtoMajor:    1,
+			ProtoMinor:    1,
+			Header:        interaction.ResponseHeader,
+			Body:          ioutil.NopCloser(strings.NewReader(interaction.ResponseBody)),
+			ContentLength: int64(len(interaction.ResponseBody)),
+			Request:       req,
+		}, nil
+	}
+
+	return nil, fmt.Errorf("No recorded interaction left for %s %s with body: %s", req.Method, req.URL.Path, body)
+}
+
+// scrub replaces credentials in a form encoded or JSON body. Anything else is
+// returned as is.
+func scrub(body []byte) string {
+	if len(body) == 0 {
+		return ""
+	}
+
+	var decoded interface{}
+	if err := json.Unmarshal(body, &decoded); err == nil {
+		if scrubJSON(decoded) {
+			if encoded, err := json.Marshal(decoded); err == nil {
+				return string(encoded)
+			}
+		}
+		return string(body)
+	}
+
+	if values, err := url.ParseQuery(string(body)); err == nil {
+		found := false
+		for key := range values {
+			if secretKeys[key] {
+				values.Set(key, scrubbed)
+				found = true
+			}
+		}
+		if found {
+			return values.Encode()
+		}
+	}
+
+	return string(body)
+}
+
+// scrubJSON replaces credentials in place, reporting whether any were found.
+func scrubJSON(value interface{}) bool {
+	found := false
+	switch value := value.(type) {
+	case map[string]interface{}:
+		for key, inner := range value {
+			if _, isString := inner.(string); isString && secretKeys[key] {
+				value[key] = scrubbed
+				found = true
+			} else if scrubJSON(inner) {
+				found = true
+			}
+		}
+	case []interface{}:
+		for _, inner := range value {
+			if scrubJSON(inner) {
+				found = true
+			}
+		}
+	}
+
+	return found
+}
@@ -0,0 +1,77 @@
+package geotriggertest
+
+import (
+	"github.com/Esri/geotrigger-go/geotrigger"
+	"github.com/Esri/geotrigger-go/geotrigger/test"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// rewrites requests for the production hosts to a test server
+type redirectTransport struct {
+	server *Server
+}
+
+func (transport *redirectTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req.URL.Scheme = "http"
+	req.URL.Host = strings.TrimPrefix(transport.server.URL, "http://")
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+func TestRecordAndReplay(t *testing.T) {
+	dir, err := ioutil.TempDir("", "geotriggertest")
+	test.Expect(t, err, nil)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "cassette.json")
+
+	server := NewServer()
+	server.AddApplication("good_client_id", "good_client_secret")
+	server.AddTrigger(geotrigger.Trigger{TriggerID: "derp", Tags: []string{"herp"}})
+	// make sure a refresh gets recorded too
+	server.AddFault(Fault{Route: "trigger/list", InvalidToken: true})
+
+	recorder := NewRecorder(path, &redirectTransport{server})
+	client, err := geotrigger.NewApplicationWithEnvironment(recorder.Environment(), "good_client_id", "good_client_secret")
+	test.Expect(t, err, nil)
+
+	var recorded map[string]interface{}
+	err = client.Request("trigger/list", map[string]interface{}{"tags": "herp"}, &recorded)
+	test.Expect(t, err, nil)
+	err = recorder.Save()
+	test.Expect(t, err, nil)
+	server.Close()
+
+	interactions := recorder.Interactions()
+	test.Expect(t, len(interactions), 4)
+	for _, interaction := range interactions {
+		test.Expect(t, strings.Contains(interaction.RequestBody, "good_client_secret"), false)
+		test.Expect(t, strings.Contains(interaction.ResponseBody, client.Info()["access_token"]), false)
+	}
+	test.Expect(t, interactions[0].RequestBody, "client_id=good_client_id&client_secret=%5Bscrubbed%5D&f=json&grant_type=client_credentials")
+	test.Expect(t, strings.Contains(interactions[0].ResponseBody, `"access_token":"[scrubbed]"`), true)
+
+	// the server is gone, everything comes from the cassette
+	replayer, err := NewReplayer(path)
+	test.Expect(t, err, nil)
+	client, err = geotrigger.NewApplicationWithEnvironment(replayer.Environment(), "good_client_id", "good_client_secret")
+	test.Expect(t, err, nil)
+
+	var replayed map[string]interface{}
+	err = client.Request("trigger/list", map[string]interface{}{"tags": "herp"}, &replayed)
+	test.Expect(t, err, nil)
+	test.Expect(t, replayed, recorded)
+
+	// the cassette is used up
+	err = client.Request("trigger/list", map[string]interface{}{"tags": "herp"}, &replayed)
+	test.Refute(t, err, nil)
+	test.Expect(t, strings.Contains(err.Error(), `No recorded interaction left for POST /trigger/list with body: {"tags":"herp"}`), true)
+
+	test.Refute(t, replayer.Save(), nil)
+
+	_, err = NewReplayer(filepath.Join(dir, "missing.json"))
+	test.Refute(t, err, nil)
+}
@@ -0,0 +1,150 @@
+package geotriggertest
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Fault scripts a misbehavior of the server. Faults are matched against
+// incoming requests in the order they were added; the first one that applies
+// is used.
+//
+// For example, to answer the third `trigger/list` request with an invalid
+// token error, and to slow down every token refresh:
+//
+//	server.AddFault(Fault{Route: "trigger/list", Skip: 2, InvalidToken: true})
+//	server.AddFault(Fault{Route: "/sharing/oauth2/token", Times: -1, Delay: time.Second})
+type Fault struct {
+	// Route restricts the fault to requests for one route, with or without a
+	// leading slash. Empty matches every request, including those to the
+	// ArcGIS Online token and registration routes.
+	Route string
+	// Skip is the number of matching requests to let through untouched first.
+	Skip int
+	// Times is the number of matching requests the fault applies to once
+	// skipping is done. Zero means once, negative means forever.
+	Times int
+
+	// Delay is waited out before the request is answered, faulty or not.
+	Delay time.Duration
+	// InvalidToken answers with a 498 invalid token error, whatever token the
+	// request was made with.
+	InvalidToken bool
+	// Status answers with this HTTP status code instead of 200.
+	Status int
+	// Body answers with this raw body instead of the normal response, which
+	// is how malformed JSON is simulated.
+	Body string
+	// DropConnection closes the connection without answering.
+	DropConnection bool
+}
+
+type scriptedFault struct {
+	Fault
+	seen int
+	hits int
+}
+
+// AddFault scripts a new fault.
+func (server *Server) AddFault(fault Fault) {
+	server.lock.Lock()
+	defer server.lock.Unlock()
+
+	server.faults = append(server.faults, &scriptedFault{Fault: fault})
+}
+
+// ClearFaults removes every scripted fault.
+func (server *Server) ClearFaults() {
+	server.lock.Lock()
+	defer server.lock.Unlock()
+
+	server.faults = nil
+}
+
+// Requests returns the number of requests received for a route, faulty or
+// not. An empty route counts every request.
+func (server *Server) Requests(route string) int {
+	server.lock.Lock()
+	defer server.lock.Unlock()
+
+	if len(route) == 0 {
+		total := 0
+		for _, count := range server.requestCounts {
+			total += count
+		}
+		return total
+	}
+
+	return server.requestCounts[normalizeRoute(route)]
+}
+
+// nextFault counts the request and returns the fault to apply to it, if any.
+// Must be called with the server lock held.
+func (server *Server) nextFault(path string) *Fault {
+	route := normalizeRoute(path)
+	server.requestCounts[route]++
+
+	for _, fault := range server.faults {
+		if len(fault.Route) > 0 && normalizeRoute(fault.Route) != route {
+			continue
+		}
+
+		fault.seen++
+		if fault.seen <= fault.Skip {
+			continue
+		}
+
+		times := fault.Times
+		if times == 0 {
+			times = 1
+		}
+		if times > 0 && fault.hits >= times {
+			continue
+		}
+
+		fault.hits++
+		applied := fault.Fault
+		return &applied
+	}
+
+	return nil
+}
+
+// apply answers the request according to the fault, returning false if the
+// request should then be handled normally.
+func (fault *Fault) apply(res http.ResponseWriter) bool {
+	if fault.Delay > 0 {
+		time.Sleep(fault.Delay)
+	}
+
+	switch {
+	case fault.DropConnection:
+		if hijacker, ok := res.(http.Hijacker); ok {
+			if conn, _, err := hijacker.Hijack(); err == nil {
+				conn.Close()
+				return true
+			}
+		}
+		// can't drop it, so fail it as hard as we can instead
+		panic(http.ErrAbortHandler)
+	case fault.Status != 0 && fault.Status != http.StatusOK:
+		res.WriteHeader(fault.Status)
+		fmt.Fprint(res, fault.Body)
+		return true
+	case len(fault.Body) > 0:
+		fmt.Fprint(res, fault.Body)
+		return true
+	case fault.InvalidToken:
+		res.Header().Set("Content-Type", "application/json")
+		fmt.Fprintln(res, `{"error":{"type":"invalidHeader","message":"invalid header or header value","headers":{"Authorization":[{"type":"invalid","message":"Invalid token."}]},"code":498}}`)
+		return true
+	}
+
+	return false
+}
+
+func normalizeRoute(route string) string {
+	return strings.TrimPrefix(route, "/")
+}
@@ -0,0 +1,102 @@
+package geotriggertest
+
+import (
+	"github.com/Esri/geotrigger-go/geotrigger"
+	"github.com/Esri/geotrigger-go/geotrigger/test"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func getApplicationClient(t *testing.T, server *Server) *geotrigger.Client {
+	server.AddApplication("good_client_id", "good_client_secret")
+	client, err := geotrigger.NewApplicationWithEnvironment(server.Environment(), "good_client_id", "good_client_secret")
+	test.Expect(t, err, nil)
+	return client
+}
+
+func TestInvalidTokenFaultIsRecoveredFrom(t *testing.T) {
+	server := NewServer()
+	defer server.Close()
+	client := getApplicationClient(t, server)
+
+	server.AddFault(Fault{Route: "trigger/list", Skip: 1, InvalidToken: true})
+
+	var response map[string]interface{}
+	for i := 0; i < 3; i++ {
+		err := client.Request("trigger/list", nil, &response)
+		test.Expect(t, err, nil)
+	}
+
+	// 3 successful lists, plus the one that got a 498 on the second try
+	test.Expect(t, server.Requests("/trigger/list"), 4)
+	// the initial token, plus one refresh
+	test.Expect(t, server.Requests("/sharing/oauth2/token"), 2)
+	test.Expect(t, server.Requests(""), 6)
+}
+
+func TestStatusAndBodyFaults(t *testing.T) {
+	server := NewServer()
+	defer server.Close()
+	client := getApplicationClient(t, server)
+
+	var response map[string]interface{}
+	server.AddFault(Fault{Route: "/trigger/list", Status: 503})
+	err := client.Request("trigger/list", nil, &response)
+	test.Refute(t, err, nil)
+	test.Expect(t, err.Error(), "Received status code 503 from /trigger/list.")
+
+	server.AddFault(Fault{Body: `{"triggers":[{"trig`})
+	err = client.Request("trigger/list", nil, &response)
+	test.Refute(t, err, nil)
+	test.Expect(t, strings.Index(err.Error(), `Error parsing response: {"triggers":[{"trig`), 0)
+
+	server.AddFault(Fault{DropConnection: true})
+	err = client.Request("trigger/list", nil, &response)
+	test.Refute(t, err, nil)
+	test.Expect(t, strings.Index(err.Error(), "Error while posting to: /trigger/list."), 0)
+
+	// every fault was used up
+	err = client.Request("trigger/list", nil, &response)
+	test.Expect(t, err, nil)
+
+	server.AddFault(Fault{Times: -1, Status: 500})
+	for i := 0; i < 3; i++ {
+		err = client.Request("trigger/list", nil, &response)
+		test.Refute(t, err, nil)
+	}
+
+	server.ClearFaults()
+	err = client.Request("trigger/list", nil, &response)
+	test.Expect(t, err, nil)
+}
+
+func TestDelayedRefreshIsShared(t *testing.T) {
+	server := NewServer()
+	defer server.Close()
+	client := getApplicationClient(t, server)
+
+	server.AddFault(Fault{Route: "/sharing/oauth2/token", Delay: 100 * time.Millisecond})
+	server.ExpireTokens()
+
+	var w sync.WaitGroup
+	var lock sync.Mutex
+	var errorCount int
+	for i := 0; i < 5; i++ {
+		w.Add(1)
+		go func() {
+			var response map[string]interface{}
+			if err := client.Request("device/list", nil, &response); err != nil {
+				lock.Lock()
+				errorCount++
+				lock.Unlock()
+			}
+			w.Done()
+		}()
+	}
+	w.Wait()
+
+	test.Expect(t, errorCount, 0)
+	test.Expect(t, server.Requests("/sharing/oauth2/token"), 2)
+}
@@ -0,0 +1,692 @@
+package geotriggertest
+
+import (
+	"encoding/json"
+	"github.com/Esri/geotrigger-go/geotrigger"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+)
+
+// routeHandler is called with the server lock held.
+type routeHandler func(server *Server, token *token, body []byte) (interface{}, error)
+
+var routes = map[string]routeHandler{
+	"trigger/create":         createTrigger,
+	"trigger/list":           listTriggers,
+	"trigger/update":         updateTriggers,
+	"trigger/delete":         deleteTriggers,
+	"trigger/history":        triggerHistory,
+	"trigger/run":            runTriggers,
+	"device/list":            listDevices,
+	"device/update":          updateDevices,
+	"tag/list":               listTags,
+	"tag/permissions":        listTagPermissions,
+	"tag/permissions/update": updateTagPermissions,
+	"location/update":        updateLocations,
+	"location/last":          lastLocations,
+}
+
+type apiError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func newError(code int, message string) *apiError {
+	return &apiError{code, message}
+}
+
+func (err *apiError) Error() string {
+	return err.Message
+}
+
+func errorEnvelope(err error) interface{} {
+	apiErr, ok := err.(*apiError)
+	if !ok {
+		apiErr = newError(500, err.Error())
+	}
+
+	return map[string]interface{}{"error": apiErr}
+}
+
+// stringList accepts either a single string or an array of strings, as the
+// service does for tag and id parameters.
+type stringList []string
+
+func (list *stringList) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*list = stringList{single}
+		return nil
+	}
+
+	var multiple []string
+	if err := json.Unmarshal(data, &multiple); err != nil {
+		return err
+	}
+	*list = stringList(multiple)
+	return nil
+}
+
+type selectParams struct {
+	TriggerIDs stringList `json:"triggerIds"`
+	DeviceIDs  stringList `json:"deviceIds"`
+	Tags       stringList `json:"tags"`
+}
+
+type historyParams struct {
+	selectParams
+	FromTimestamp *time.Time `json:"fromTimestamp"`
+	ToTimestamp   *time.Time `json:"toTimestamp"`
+}
+
+type tagParams struct {
+	SetTags    stringList `json:"setTags"`
+	AddTags    stringList `json:"addTags"`
+	RemoveTags stringList `json:"removeTags"`
+}
+
+// triggerCreateParams are a trigger's fields, but for its tags, which are
+// set with tagParams.
+type triggerCreateParams struct {
+	TriggerID  string                 `json:"triggerId"`
+	Condition  geotrigger.Condition   `json:"condition"`
+	Action     geotrigger.Action      `json:"action"`
+	Properties map[string]interface{} `json:"properties"`
+	Times      int                    `json:"times"`
+	RateLimit  int                    `json:"rateLimit"`
+	tagParams
+}
+
+type triggerUpdateParams struct {
+	selectParams
+	tagParams
+	Condition  *geotrigger.Condition  `json:"condition"`
+	Action     *geotrigger.Action     `json:"action"`
+	Properties map[string]interface{} `json:"properties"`
+	Times      *int                   `json:"times"`
+	RateLimit  *int                   `json:"rateLimit"`
+}
+
+type deviceUpdateParams struct {
+	selectParams
+	tagParams
+	Properties        map[string]interface{} `json:"properties"`
+	TrackingProfile   *string                `json:"trackingProfile"`
+	APNSProdToken     string                 `json:"apnsProdToken"`
+	APNSSandboxToken  string                 `json:"apnsSandboxToken"`
+	GCMRegistrationID string                 `json:"gcmRegistrationId"`
+}
+
+// tagPermissionNames are the permissions a tag can grant the devices that
+// have it. Permissions that haven't been set are false.
+var tagPermissionNames = []string{
+	"deviceTagging",
+	"deviceLocation",
+	"deviceToggleTracking",
+	"devicePermissions",
+	"deviceList",
+	"triggerApply",
+	"triggerDelete",
+	"triggerHistory",
+	"triggerList",
+	"triggerUpdate",
+}
+
+type locationUpdateParams struct {
+	Locations []geotrigger.Location `json:"locations"`
+}
+
+// decodeParams decodes a request's params, refusing any the route doesn't
+// read, so that a test sending them notices rather than having them dropped.
+func decodeParams(body []byte, params interface{}) error {
+	if len(body) == 0 {
+		return nil
+	}
+
+	if err := json.Unmarshal(body, params); err != nil {
+		return newError(400, "Invalid JSON in request body.")
+	}
+
+	t := reflect.TypeOf(params).Elem()
+	if t.Kind() != reflect.Struct {
+		return nil
+	}
+
+	var keys map[string]json.RawMessage
+	if err := json.Unmarshal(body, &keys); err != nil {
+		return newError(400, "Invalid JSON in request body.")
+	}
+	names := make(map[string]bool)
+	paramNames(t, names)
+	var unknown []string
+	for key := range keys {
+		if !names[key] {
+			unknown = append(unknown, key)
+		}
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return newError(400, "Unknown parameter: "+unknown[0])
+	}
+
+	return nil
+}
+
+// paramNames adds the keys a params struct reads to names, including those
+// of the structs it embeds.
+func paramNames(t reflect.Type, names map[string]bool) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name := strings.Split(field.Tag.Get("json"), ",")[0]
+		switch {
+		case field.Anonymous && len(name) == 0:
+			paramNames(field.Type, names)
+		case name == "-":
+		case len(name) == 0:
+			names[field.Name] = true
+		default:
+			names[name] = true
+		}
+	}
+}
+
+func createTrigger(server *Server, token *token, body []byte) (interface{}, error) {
+	if len(token.deviceID) > 0 {
+		return nil, newError(403, "Devices may not create triggers.")
+	}
+
+	var params triggerCreateParams
+	if err := decodeParams(body, &params); err != nil {
+		return nil, err
+	}
+
+	trigger := geotrigger.Trigger{
+		TriggerID:  params.TriggerID,
+		Condition:  params.Condition,
+		Action:     params.Action,
+		Properties: params.Properties,
+		Times:      params.Times,
+		RateLimit:  params.RateLimit,
+	}
+	if len(trigger.Condition.Direction) == 0 {
+		return nil, newError(400, "Missing required parameter: condition.direction")
+	}
+	if len(trigger.TriggerID) == 0 {
+		trigger.TriggerID = newID()
+	}
+	for _, existing := range server.triggers {
+		if existing.TriggerID == trigger.TriggerID {
+			return nil, newError(400, "Trigger ID already in use: "+trigger.TriggerID)
+		}
+	}
+	trigger.Tags = params.tagParams.apply(nil)
+
+	server.triggers = append(server.triggers, &trigger)
+	return trigger, nil
+}
+
+func listTriggers(server *Server, token *token, body []byte) (interface{}, error) {
+	var params selectParams
+	if err := decodeParams(body, &params); err != nil {
+		return nil, err
+	}
+
+	var deviceTags []string
+	if device := server.device(token.deviceID); device != nil {
+		deviceTags = device.Tags
+	}
+
+	triggers := []geotrigger.Trigger{}
+	for _, trigger := range server.selectTriggers(&params) {
+		if len(token.deviceID) > 0 && !overlaps(trigger.Tags, deviceTags) {
+			continue
+		}
+		triggers = append(triggers, *trigger)
+	}
+
+	return map[string]interface{}{"triggers": triggers}, nil
+}
+
+func updateTriggers(server *Server, token *token, body []byte) (interface{}, error) {
+	if len(token.deviceID) > 0 {
+		return nil, newError(403, "Devices may not update triggers.")
+	}
+
+	var params triggerUpdateParams
+	if err := decodeParams(body, &params); err != nil {
+		return nil, err
+	}
+	if len(params.TriggerIDs) == 0 && len(params.Tags) == 0 {
+		return nil, newError(400, "Missing required parameter: triggerIds or tags")
+	}
+
+	triggers := []geotrigger.Trigger{}
+	for _, trigger := range server.selectTriggers(&params.selectParams) {
+		if params.Condition != nil {
+			trigger.Condition = *params.Condition
+		}
+		if params.Action != nil {
+			trigger.Action = *params.Action
+		}
+		if params.Properties != nil {
+			trigger.Properties = params.Properties
+		}
+		if params.Times != nil {
+			trigger.Times = *params.Times
+		}
+		if params.RateLimit != nil {
+			trigger.RateLimit = *params.RateLimit
+		}
+		trigger.Tags = params.tagParams.apply(trigger.Tags)
+
+		triggers = append(triggers, *trigger)
+	}
+
+	return map[string]interface{}{"triggers": triggers}, nil
+}
+
+func deleteTriggers(server *Server, token *token, body []byte) (interface{}, error) {
+	if len(token.deviceID) > 0 {
+		return nil, newError(403, "Devices may not delete triggers.")
+	}
+
+	var params selectParams
+	if err := decodeParams(body, &params); err != nil {
+		return nil, err
+	}
+	if len(params.TriggerIDs) == 0 && len(params.Tags) == 0 {
+		return nil, newError(400, "Missing required parameter: triggerIds or tags")
+	}
+
+	deleted := server.selectTriggers(&params)
+	remaining := server.triggers[:0]
+	for _, trigger := range server.triggers {
+		if !containsTrigger(deleted, trigger) {
+			remaining = append(remaining, trigger)
+		}
+	}
+	server.triggers = remaining
+
+	triggers := []geotrigger.Trigger{}
+	for _, trigger := range deleted {
+		triggers = append(triggers, *trigger)
+	}
+
+	return map[string]interface{}{"triggers": triggers}, nil
+}
+
+func triggerHistory(server *Server, token *token, body []byte) (interface{}, error) {
+	if len(token.deviceID) > 0 {
+		return nil, newError(403, "Devices may not list trigger history.")
+	}
+
+	var params historyParams
+	if err := decodeParams(body, &params); err != nil {
+		return nil, err
+	}
+
+	var tagged []string
+	if len(params.Tags) > 0 {
+		for _, trigger := range server.selectTriggers(&selectParams{Tags: params.Tags}) {
+			tagged = append(tagged, trigger.TriggerID)
+		}
+	}
+
+	logs := []geotrigger.HistoryEvent{}
+	for _, event := range server.history {
+		if len(params.TriggerIDs) > 0 && !overlaps(params.TriggerIDs, []string{event.TriggerID}) ||
+			len(params.DeviceIDs) > 0 && !overlaps(params.DeviceIDs, []string{event.DeviceID}) ||
+			len(params.Tags) > 0 && !overlaps(tagged, []string{event.TriggerID}) ||
+			params.FromTimestamp != nil && event.Timestamp.Before(*params.FromTimestamp) ||
+			params.ToTimestamp != nil && event.Timestamp.After(*params.ToTimestamp) {
+			continue
+		}
+		logs = append(logs, event)
+	}
+
+	return map[string]interface{}{"logs": logs}, nil
+}
+
+// runTriggers records a firing of each trigger for each device selected, as
+// if it had crossed the fence, in the history `trigger/history` lists.
+func runTriggers(server *Server, token *token, body []byte) (interface{}, error) {
+	if len(token.deviceID) > 0 {
+		return nil, newError(403, "Devices may not run triggers.")
+	}
+
+	var params selectParams
+	if err := decodeParams(body, &params); err != nil {
+		return nil, err
+	}
+	if len(params.TriggerIDs) == 0 {
+		return nil, newError(400, "Missing required parameter: triggerIds")
+	}
+	if len(params.DeviceIDs) == 0 && len(params.Tags) == 0 {
+		return nil, newError(400, "Missing required parameter: deviceIds or tags")
+	}
+
+	selected := server.selectTriggers(&selectParams{TriggerIDs: params.TriggerIDs})
+	if len(selected) == 0 {
+		return nil, newError(400, "No triggers found: "+strings.Join(params.TriggerIDs, ", "))
+	}
+	devices := server.selectDevices(token, &selectParams{DeviceIDs: params.DeviceIDs, Tags: params.Tags})
+
+	now := time.Now().UTC()
+	triggers := []geotrigger.Trigger{}
+	for _, trigger := range selected {
+		for _, device := range devices {
+			server.history = append(server.history, geotrigger.HistoryEvent{
+				TriggerID: trigger.TriggerID,
+				DeviceID:  device.DeviceID,
+				Direction: trigger.Condition.Direction,
+				Timestamp: now,
+			})
+		}
+		triggers = append(triggers, *trigger)
+	}
+
+	return map[string]interface{}{"triggers": triggers}, nil
+}
+
+func listDevices(server *Server, token *token, body []byte) (interface{}, error) {
+	var params selectParams
+	if err := decodeParams(body, &params); err != nil {
+		return nil, err
+	}
+
+	devices := []Device{}
+	for _, device := range server.selectDevices(token, &params) {
+		devices = append(devices, *device)
+	}
+
+	return map[string]interface{}{"devices": devices}, nil
+}
+
+func updateDevices(server *Server, token *token, body []byte) (interface{}, error) {
+	var params deviceUpdateParams
+	if err := decodeParams(body, &params); err != nil {
+		return nil, err
+	}
+	if len(token.deviceID) == 0 && len(params.DeviceIDs) == 0 && len(params.Tags) == 0 {
+		return nil, newError(400, "Missing required parameter: deviceIds or tags")
+	}
+
+	devices := []Device{}
+	for _, device := range server.selectDevices(token, &params.selectParams) {
+		if params.Properties != nil {
+			device.Properties = params.Properties
+		}
+		if params.TrackingProfile != nil {
+			device.TrackingProfile = *params.TrackingProfile
+		}
+		for platform, pushToken := range map[string]string{
+			geotrigger.PushAPNS:        params.APNSProdToken,
+			geotrigger.PushAPNSSandbox: params.APNSSandboxToken,
+			geotrigger.PushGCM:         params.GCMRegistrationID,
+		} {
+			if len(pushToken) > 0 {
+				if device.PushTokens == nil {
+					device.PushTokens = make(map[string]string)
+				}
+				device.PushTokens[platform] = pushToken
+			}
+		}
+		// every device keeps its own device tag, whatever else happens
+		deviceTag := "device:" + device.DeviceID
+		device.Tags = params.tagParams.apply(device.Tags)
+		if !overlaps(device.Tags, []string{deviceTag}) {
+			device.Tags = append([]string{deviceTag}, device.Tags...)
+		}
+
+		devices = append(devices, *device)
+	}
+
+	return map[string]interface{}{"devices": devices}, nil
+}
+
+func listTags(server *Server, token *token, body []byte) (interface{}, error) {
+	names := make(map[string]bool)
+	for _, trigger := range server.triggers {
+		for _, tag := range trigger.Tags {
+			names[tag] = true
+		}
+	}
+	for _, device := range server.devices {
+		for _, tag := range device.Tags {
+			if !strings.HasPrefix(tag, "device:") {
+				names[tag] = true
+			}
+		}
+	}
+
+	sorted := make([]string, 0, len(names))
+	for name := range names {
+		sorted = append(sorted, name)
+	}
+	sort.Strings(sorted)
+
+	tags := []map[string]interface{}{}
+	for _, name := range sorted {
+		tags = append(tags, map[string]interface{}{"name": name})
+	}
+
+	return map[string]interface{}{"tags": tags}, nil
+}
+
+func listTagPermissions(server *Server, token *token, body []byte) (interface{}, error) {
+	if len(token.deviceID) > 0 {
+		return nil, newError(403, "Devices may not list tag permissions.")
+	}
+
+	var params selectParams
+	if err := decodeParams(body, &params); err != nil {
+		return nil, err
+	}
+
+	names := []string(params.Tags)
+	if len(names) == 0 {
+		for name := range server.tagPermissions {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+	}
+
+	tags := []map[string]interface{}{}
+	for _, name := range names {
+		tag := map[string]interface{}{"name": name}
+		for _, permission := range tagPermissionNames {
+			tag[permission] = server.tagPermissions[name][permission]
+		}
+		tags = append(tags, tag)
+	}
+
+	return map[string]interface{}{"tags": tags}, nil
+}
+
+func updateTagPermissions(server *Server, token *token, body []byte) (interface{}, error) {
+	if len(token.deviceID) > 0 {
+		return nil, newError(403, "Devices may not update tag permissions.")
+	}
+
+	var params map[string]json.RawMessage
+	if err := decodeParams(body, &params); err != nil {
+		return nil, err
+	}
+
+	var tags stringList
+	if raw, ok := params["tags"]; ok {
+		if err := json.Unmarshal(raw, &tags); err != nil {
+			return nil, newError(400, "Invalid parameter: tags")
+		}
+	}
+	if len(tags) == 0 {
+		return nil, newError(400, "Missing required parameter: tags")
+	}
+	delete(params, "tags")
+
+	permissions := make(map[string]bool)
+	for name, raw := range params {
+		if !overlaps(tagPermissionNames, []string{name}) {
+			return nil, newError(400, "Unknown permission: "+name)
+		}
+		var value bool
+		if err := json.Unmarshal(raw, &value); err != nil {
+			return nil, newError(400, "Invalid parameter: "+name)
+		}
+		permissions[name] = value
+	}
+
+	for _, tag := range tags {
+		if server.tagPermissions[tag] == nil {
+			server.tagPermissions[tag] = make(map[string]bool)
+		}
+		for name, value := range permissions {
+			server.tagPermissions[tag][name] = value
+		}
+	}
+
+	listParams, _ := json.Marshal(map[string]interface{}{"tags": tags})
+	return listTagPermissions(server, token, listParams)
+}
+
+func updateLocations(server *Server, token *token, body []byte) (interface{}, error) {
+	if len(token.deviceID) == 0 {
+		return nil, newError(403, "Only devices may update their location.")
+	}
+
+	var params locationUpdateParams
+	if err := decodeParams(body, &params); err != nil {
+		return nil, err
+	}
+	if len(params.Locations) == 0 {
+		return nil, newError(400, "Missing required parameter: locations")
+	}
+
+	server.locations[token.deviceID] = append(server.locations[token.deviceID], params.Locations...)
+
+	return map[string]interface{}{"processedLocations": len(params.Locations)}, nil
+}
+
+func lastLocations(server *Server, token *token, body []byte) (interface{}, error) {
+	var params selectParams
+	if err := decodeParams(body, &params); err != nil {
+		return nil, err
+	}
+
+	locations := []map[string]interface{}{}
+	for _, device := range server.selectDevices(token, &params) {
+		history := server.locations[device.DeviceID]
+		if len(history) == 0 {
+			continue
+		}
+
+		last := history[len(history)-1]
+		locations = append(locations, map[string]interface{}{
+			"deviceId":  device.DeviceID,
+			"latitude":  last.Latitude,
+			"longitude": last.Longitude,
+			"accuracy":  last.Accuracy,
+			"timestamp": last.Timestamp,
+		})
+	}
+
+	return map[string]interface{}{"locations": locations}, nil
+}
+
+func (server *Server) device(deviceID string) *Device {
+	for _, device := range server.devices {
+		if device.DeviceID == deviceID {
+			return device
+		}
+	}
+
+	return nil
+}
+
+// selectTriggers returns the triggers matching the ids and tags in params, or
+// every trigger when neither is provided.
+func (server *Server) selectTriggers(params *selectParams) []*geotrigger.Trigger {
+	var selected []*geotrigger.Trigger
+	for _, trigger := range server.triggers {
+		if len(params.TriggerIDs) == 0 && len(params.Tags) == 0 ||
+			overlaps(params.TriggerIDs, []string{trigger.TriggerID}) ||
+			overlaps(params.Tags, trigger.Tags) {
+			selected = append(selected, trigger)
+		}
+	}
+
+	return selected
+}
+
+// selectDevices is like selectTriggers, but devices only ever see themselves
+// and applications only see their own devices.
+func (server *Server) selectDevices(token *token, params *selectParams) []*Device {
+	if len(token.deviceID) > 0 {
+		if device := server.device(token.deviceID); device != nil {
+			return []*Device{device}
+		}
+		return nil
+	}
+
+	var selected []*Device
+	for _, device := range server.devices {
+		if device.clientID != token.clientID {
+			continue
+		}
+		if len(params.DeviceIDs) == 0 && len(params.Tags) == 0 ||
+			overlaps(params.DeviceIDs, []string{device.DeviceID}) ||
+			overlaps(params.Tags, device.Tags) {
+			selected = append(selected, device)
+		}
+	}
+
+	return selected
+}
+
+func (params *tagParams) apply(tags []string) []string {
+	if params.SetTags != nil {
+		tags = append([]string(nil), params.SetTags...)
+	}
+
+	for _, tag := range params.AddTags {
+		if !overlaps(tags, []string{tag}) {
+			tags = append(tags, tag)
+		}
+	}
+
+	if len(params.RemoveTags) > 0 {
+		kept := []string{}
+		for _, tag := range tags {
+			if !overlaps(params.RemoveTags, []string{tag}) {
+				kept = append(kept, tag)
+			}
+		}
+		tags = kept
+	}
+
+	return tags
+}
+
+func overlaps(a []string, b []string) bool {
+	for _, x := range a {
+		for _, y := range b {
+			if x == y {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+func containsTrigger(triggers []*geotrigger.Trigger, trigger *geotrigger.Trigger) bool {
+	for _, t := range triggers {
+		if t == trigger {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,331 @@
+// Package `geotriggertest` provides an in-process fake of the Geotrigger
+// Service, along with the ArcGIS Online OAuth routes the library uses to get
+// tokens, so code built on `geotrigger.Client` can be tested without the real
+// services.
+//
+// The fake keeps triggers, devices, tags and locations in memory and issues
+// real-looking, expiring tokens, so token refresh and 498 handling are
+// exercised just as they would be in production:
+//
+//	server := geotriggertest.NewServer()
+//	defer server.Close()
+//	server.AddApplication("client_id", "client_secret")
+//
+//	client, err := geotrigger.NewApplicationWithEnvironment(server.Environment(),
+//		"client_id", "client_secret")
+//
+// Params a route doesn't read, such as `tags` sent to `trigger/create`, are
+// refused with a 400, rather than dropped.
+package geotriggertest
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"github.com/Esri/geotrigger-go/geotrigger"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	agoTokenRoute    = "/sharing/oauth2/token"
+	agoRegisterRoute = "/sharing/oauth2/registerDevice"
+)
+
+// Server is a fake Geotrigger Service and ArcGIS Online, listening on a
+// local address. Create one with NewServer and shut it down with Close.
+type Server struct {
+	*httptest.Server
+
+	// ApplicationExpiresIn and DeviceExpiresIn are the lifetimes, in seconds,
+	// of access tokens issued from then on.
+	ApplicationExpiresIn int64
+	DeviceExpiresIn      int64
+
+	lock           sync.Mutex
+	applications   map[string]string
+	tokens         map[string]*token
+	refreshTokens  map[string]string
+	devices        []*Device
+	triggers       []*geotrigger.Trigger
+	locations      map[string][]geotrigger.Location
+	tagPermissions map[string]map[string]bool
+	history        []geotrigger.HistoryEvent
+	faults         []*scriptedFault
+	requestCounts  map[string]int
+}
+
+// Device is a device registered with the fake server.
+type Device struct {
+	DeviceID        string                 `json:"deviceId"`
+	Tags            []string               `json:"tags"`
+	Properties      map[string]interface{} `json:"properties,omitempty"`
+	TrackingProfile string                 `json:"trackingProfile,omitempty"`
+	// push tokens registered through `device/update`, by platform, such as
+	// geotrigger.PushGCM. The service never lists them.
+	PushTokens map[string]string `json:"-"`
+
+	clientID string
+}
+
+type token struct {
+	clientID  string
+	deviceID  string
+	expiresAt time.Time
+}
+
+// NewServer starts a fake server with no applications, devices or triggers.
+func NewServer() *Server {
+	server := &Server{
+		ApplicationExpiresIn: 7200,
+		DeviceExpiresIn:      1800,
+		applications:         make(map[string]string),
+		tokens:               make(map[string]*token),
+		refreshTokens:        make(map[string]string),
+		locations:            make(map[string][]geotrigger.Location),
+		tagPermissions:       make(map[string]map[string]bool),
+		requestCounts:        make(map[string]int),
+	}
+
+	server.Server = httptest.NewServer(http.HandlerFunc(server.serveHTTP))
+	return server
+}
+
+// Environment returns the environment to hand to the `geotrigger` constructors
+// so they talk to this server.
+func (server *Server) Environment() geotrigger.Environment {
+	return geotrigger.Environment{
+		GeotriggerURL: server.URL,
+		AGOURL:        server.URL,
+	}
+}
+
+// AddApplication registers application credentials. Devices can register
+// with any client_id added here.
+func (server *Server) AddApplication(clientID string, clientSecret string) {
+	server.lock.Lock()
+	defer server.lock.Unlock()
+
+	server.applications[clientID] = clientSecret
+}
+
+// ExpireTokens invalidates every access token issued so far. The next request
+// made with one of them gets a 498 invalid token error.
+func (server *Server) ExpireTokens() {
+	server.lock.Lock()
+	defer server.lock.Unlock()
+
+	for _, token := range server.tokens {
+		token.expiresAt = time.Time{}
+	}
+}
+
+// AddTrigger stores a trigger as if it had been created through
+// `trigger/create`, generating a trigger ID if needed. The stored copy is
+// returned.
+func (server *Server) AddTrigger(trigger geotrigger.Trigger) geotrigger.Trigger {
+	server.lock.Lock()
+	defer server.lock.Unlock()
+
+	if len(trigger.TriggerID) == 0 {
+		trigger.TriggerID = newID()
+	}
+	server.triggers = append(server.triggers, &trigger)
+
+	return trigger
+}
+
+// AddHistory records trigger firings, for `trigger/history` to list. The fake
+// doesn't evaluate locations against triggers, so besides `trigger/run`, this
+// is the only way events get there.
+func (server *Server) AddHistory(events ...geotrigger.HistoryEvent) {
+	server.lock.Lock()
+	defer server.lock.Unlock()
+
+	server.history = append(server.history, events...)
+}
+
+// Triggers returns a copy of every stored trigger, in creation order.
+func (server *Server) Triggers() []geotrigger.Trigger {
+	server.lock.Lock()
+	defer server.lock.Unlock()
+
+	triggers := make([]geotrigger.Trigger, len(server.triggers))
+	for i, trigger := range server.triggers {
+		triggers[i] = *trigger
+	}
+
+	return triggers
+}
+
+// Devices returns a copy of every registered device, in registration order.
+func (server *Server) Devices() []Device {
+	server.lock.Lock()
+	defer server.lock.Unlock()
+
+	devices := make([]Device, len(server.devices))
+	for i, device := range server.devices {
+		devices[i] = *device
+	}
+
+	return devices
+}
+
+// Locations returns every location reported by a device, oldest first.
+func (server *Server) Locations(deviceID string) []geotrigger.Location {
+	server.lock.Lock()
+	defer server.lock.Unlock()
+
+	return append([]geotrigger.Location(nil), server.locations[deviceID]...)
+}
+
+func (server *Server) serveHTTP(res http.ResponseWriter, req *http.Request) {
+	body, err := ioutil.ReadAll(req.Body)
+	if err != nil {
+		http.Error(res, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	server.lock.Lock()
+	fault := server.nextFault(req.URL.Path)
+	server.lock.Unlock()
+
+	// faults are applied without holding the lock, so a delayed request
+	// doesn't hold up the others
+	if fault != nil && fault.apply(res) {
+		return
+	}
+
+	server.lock.Lock()
+	defer server.lock.Unlock()
+
+	var response interface{}
+	switch req.URL.Path {
+	case agoTokenRoute:
+		response, err = server.issueToken(body)
+	case agoRegisterRoute:
+		response, err = server.registerDevice(body)
+	default:
+		response, err = server.route(req, body)
+	}
+
+	if err != nil {
+		response = errorEnvelope(err)
+	}
+
+	res.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(res).Encode(response)
+}
+
+func (server *Server) issueToken(body []byte) (interface{}, error) {
+	values, err := url.ParseQuery(string(body))
+	if err != nil {
+		return nil, newError(400, "Invalid request body.")
+	}
+
+	clientID := values.Get("client_id")
+	switch values.Get("grant_type") {
+	case "client_credentials":
+		secret, ok := server.applications[clientID]
+		if !ok || secret != values.Get("client_secret") {
+			return nil, newError(400, "Invalid client_id or client_secret.")
+		}
+
+		return map[string]interface{}{
+			"access_token": server.newToken(clientID, ""),
+			"expires_in":   server.ApplicationExpiresIn,
+		}, nil
+	case "refresh_token":
+		deviceID, ok := server.refreshTokens[values.Get("refresh_token")]
+		if !ok {
+			return nil, newError(400, "Invalid refresh_token.")
+		}
+
+		return map[string]interface{}{
+			"access_token": server.newToken(clientID, deviceID),
+			"expires_in":   server.DeviceExpiresIn,
+		}, nil
+	}
+
+	return nil, newError(400, fmt.Sprintf("Unsupported grant_type: %s.", values.Get("grant_type")))
+}
+
+func (server *Server) registerDevice(body []byte) (interface{}, error) {
+	values, err := url.ParseQuery(string(body))
+	if err != nil {
+		return nil, newError(400, "Invalid request body.")
+	}
+
+	clientID := values.Get("client_id")
+	if _, ok := server.applications[clientID]; !ok {
+		return nil, newError(400, "Unable to register device.")
+	}
+
+	device := &Device{
+		DeviceID: newID(),
+		clientID: clientID,
+	}
+	device.Tags = []string{"device:" + device.DeviceID}
+	server.devices = append(server.devices, device)
+
+	refreshToken := newID()
+	server.refreshTokens[refreshToken] = device.DeviceID
+
+	return map[string]interface{}{
+		"device": map[string]interface{}{
+			"deviceId":  device.DeviceID,
+			"client_id": clientID,
+		},
+		"deviceToken": map[string]interface{}{
+			"access_token":  server.newToken(clientID, device.DeviceID),
+			"expires_in":    server.DeviceExpiresIn,
+			"refresh_token": refreshToken,
+		},
+	}, nil
+}
+
+func (server *Server) newToken(clientID string, deviceID string) string {
+	expiresIn := server.ApplicationExpiresIn
+	if len(deviceID) > 0 {
+		expiresIn = server.DeviceExpiresIn
+	}
+
+	accessToken := newID()
+	server.tokens[accessToken] = &token{
+		clientID:  clientID,
+		deviceID:  deviceID,
+		expiresAt: time.Now().Add(time.Duration(expiresIn) * time.Second),
+	}
+
+	return accessToken
+}
+
+func (server *Server) route(req *http.Request, body []byte) (interface{}, error) {
+	accessToken := strings.TrimPrefix(req.Header.Get("Authorization"), "Bearer ")
+	token, ok := server.tokens[accessToken]
+	if !ok || !time.Now().Before(token.expiresAt) {
+		return nil, newError(498, "Invalid token.")
+	}
+
+	handler, ok := routes[normalizeRoute(req.URL.Path)]
+	if !ok {
+		return nil, newError(404, fmt.Sprintf("Route not found: %s", req.URL.Path))
+	}
+
+	return handler(server, token, body)
+}
+
+func newID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+
+	return hex.EncodeToString(b)
+}
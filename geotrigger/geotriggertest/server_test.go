@@ -0,0 +1,279 @@
+package geotriggertest
+
+import (
+	"context"
+	"github.com/Esri/geotrigger-go/geotrigger"
+	"github.com/Esri/geotrigger-go/geotrigger/test"
+	"testing"
+	"time"
+)
+
+func TestApplicationTriggerWorkflow(t *testing.T) {
+	server := NewServer()
+	defer server.Close()
+	server.AddApplication("good_client_id", "good_client_secret")
+
+	_, err := geotrigger.NewApplicationWithEnvironment(server.Environment(), "good_client_id", "bad_client_secret")
+	test.Refute(t, err, nil)
+	test.Expect(t, err.Error(), "Error from /sharing/oauth2/token, code: 400. Message: Invalid client_id or client_secret.")
+
+	client, err := geotrigger.NewApplicationWithEnvironment(server.Environment(), "good_client_id", "good_client_secret")
+	test.Expect(t, err, nil)
+
+	params := map[string]interface{}{
+		"triggerId": "derp",
+		"condition": map[string]interface{}{
+			"direction": "enter",
+			"geo":       map[string]interface{}{"latitude": 45.5, "longitude": -122.6, "distance": 100},
+		},
+		"action":  map[string]interface{}{"message": "hello"},
+		"setTags": "portland",
+	}
+	var created geotrigger.Trigger
+	err = client.Request("trigger/create", params, &created)
+	test.Expect(t, err, nil)
+	test.Expect(t, created.TriggerID, "derp")
+	test.Expect(t, created.Tags, []string{"portland"})
+
+	// params the route doesn't read are refused, rather than dropped
+	err = client.Request("trigger/create", map[string]interface{}{
+		"condition": params["condition"],
+		"action":    params["action"],
+		"tags":      "portland",
+	}, &created)
+	test.Refute(t, err, nil)
+	test.Expect(t, err.Error(), "Error from /trigger/create, code: 400. Message: Unknown parameter: tags")
+
+	// duplicate ids are refused
+	err = client.Request("trigger/create", params, &created)
+	test.Refute(t, err, nil)
+	test.Expect(t, err.Error(), "Error from /trigger/create, code: 400. Message: Trigger ID already in use: derp")
+
+	var updated struct {
+		Triggers []geotrigger.Trigger `json:"triggers"`
+	}
+	err = client.Request("trigger/update", map[string]interface{}{
+		"triggerIds": "derp",
+		"addTags":    []string{"food"},
+		"action":     map[string]interface{}{"message": "hi"},
+	}, &updated)
+	test.Expect(t, err, nil)
+	test.Expect(t, len(updated.Triggers), 1)
+	test.Expect(t, updated.Triggers[0].Tags, []string{"portland", "food"})
+	test.Expect(t, updated.Triggers[0].Action.Message, "hi")
+
+	var list struct {
+		Triggers []geotrigger.Trigger `json:"triggers"`
+	}
+	err = client.Request("trigger/list", map[string]interface{}{"tags": "food"}, &list)
+	test.Expect(t, err, nil)
+	test.Expect(t, len(list.Triggers), 1)
+	test.Expect(t, server.Triggers()[0].Action.Message, "hi")
+
+	var tags map[string]interface{}
+	err = client.Request("tag/list", nil, &tags)
+	test.Expect(t, err, nil)
+	test.Expect(t, len(tags["tags"].([]interface{})), 2)
+
+	err = client.Request("trigger/delete", map[string]interface{}{"triggerIds": []string{"derp"}}, &list)
+	test.Expect(t, err, nil)
+	test.Expect(t, len(server.Triggers()), 0)
+
+	var notFound map[string]interface{}
+	err = client.Request("herp/derp", nil, &notFound)
+	test.Refute(t, err, nil)
+	test.Expect(t, err.Error(), "Error from /herp/derp, code: 404. Message: Route not found: /herp/derp")
+}
+
+func TestTriggerCreateParams(t *testing.T) {
+	server := NewServer()
+	defer server.Close()
+	server.AddApplication("good_client_id", "good_client_secret")
+
+	client, err := geotrigger.NewApplicationWithEnvironment(server.Environment(), "good_client_id", "good_client_secret")
+	test.Expect(t, err, nil)
+
+	trigger := &geotrigger.Trigger{
+		TriggerID: "derp",
+		Condition: geotrigger.Condition{
+			Direction: geotrigger.DirectionEnter,
+			Geo:       geotrigger.Geo{Latitude: 45.5, Longitude: -122.6, Distance: 100},
+		},
+		Action: geotrigger.Action{Message: "hello"},
+		Tags:   []string{"portland", "food"},
+	}
+	ctx := context.Background()
+	created, err := geotrigger.Do[geotrigger.Trigger](ctx, client, "trigger/create", trigger.CreateParams())
+	test.Expect(t, err, nil)
+	test.Expect(t, created.Tags, []string{"portland", "food"})
+
+	var list struct {
+		Triggers []geotrigger.Trigger `json:"triggers"`
+	}
+	err = client.Request("trigger/list", map[string]interface{}{"tags": "portland"}, &list)
+	test.Expect(t, err, nil)
+	test.Expect(t, len(list.Triggers), 1)
+	test.Expect(t, list.Triggers[0].Tags, []string{"portland", "food"})
+
+	// the trigger itself would have its tags dropped, so isn't sent
+	trigger.TriggerID = "herp"
+	_, err = geotrigger.Do[geotrigger.Trigger](ctx, client, "trigger/create", trigger)
+	test.Refute(t, err, nil)
+	test.Expect(t, len(server.Triggers()), 1)
+}
+
+func TestDeviceLocationWorkflow(t *testing.T) {
+	server := NewServer()
+	defer server.Close()
+	server.AddApplication("good_client_id", "good_client_secret")
+
+	_, err := geotrigger.NewDeviceWithEnvironment(server.Environment(), "bad_client_id")
+	test.Refute(t, err, nil)
+
+	client, err := geotrigger.NewDeviceWithEnvironment(server.Environment(), "good_client_id")
+	test.Expect(t, err, nil)
+	deviceID := client.Info()["device_id"]
+	test.Refute(t, deviceID, "")
+	test.Expect(t, len(server.Devices()), 1)
+
+	var devices struct {
+		Devices []Device `json:"devices"`
+	}
+	err = client.Request("device/update", map[string]interface{}{"addTags": "runner"}, &devices)
+	test.Expect(t, err, nil)
+	test.Expect(t, devices.Devices[0].Tags, []string{"device:" + deviceID, "runner"})
+
+	params := (&geotrigger.DeviceUpdateParams{}).RegisterPushToken(geotrigger.PushGCM, "gcm_id")
+	err = client.Request("device/update", params, &devices)
+	test.Expect(t, err, nil)
+	test.Expect(t, server.Devices()[0].PushTokens, map[string]string{geotrigger.PushGCM: "gcm_id"})
+
+	timestamp := time.Date(2014, 4, 22, 12, 0, 0, 0, time.UTC)
+	var updateResponse map[string]interface{}
+	err = client.Request("location/update", map[string]interface{}{
+		"locations": []geotrigger.Location{{Latitude: 45.5, Longitude: -122.6, Accuracy: 10, Timestamp: timestamp}},
+	}, &updateResponse)
+	test.Expect(t, err, nil)
+	test.Expect(t, updateResponse["processedLocations"], float64(1))
+
+	locations := server.Locations(deviceID)
+	test.Expect(t, len(locations), 1)
+	test.Expect(t, locations[0].Timestamp.Equal(timestamp), true)
+
+	// devices can't manage triggers
+	var created geotrigger.Trigger
+	err = client.Request("trigger/create", map[string]interface{}{}, &created)
+	test.Refute(t, err, nil)
+	test.Expect(t, err.Error(), "Error from /trigger/create, code: 403. Message: Devices may not create triggers.")
+
+	// applications see their devices and their last locations
+	app, err := geotrigger.NewApplicationWithEnvironment(server.Environment(), "good_client_id", "good_client_secret")
+	test.Expect(t, err, nil)
+	var last map[string]interface{}
+	err = app.Request("location/last", map[string]interface{}{"tags": "runner"}, &last)
+	test.Expect(t, err, nil)
+	test.Expect(t, len(last["locations"].([]interface{})), 1)
+}
+
+func TestTagPermissions(t *testing.T) {
+	server := NewServer()
+	defer server.Close()
+	server.AddApplication("good_client_id", "good_client_secret")
+
+	client, err := geotrigger.NewApplicationWithEnvironment(server.Environment(), "good_client_id", "good_client_secret")
+	test.Expect(t, err, nil)
+
+	var permissions struct {
+		Tags []map[string]interface{} `json:"tags"`
+	}
+	err = client.Request("tag/permissions/update", map[string]interface{}{"tags": "portland", "triggerList": true}, &permissions)
+	test.Expect(t, err, nil)
+	test.Expect(t, len(permissions.Tags), 1)
+	test.Expect(t, permissions.Tags[0]["name"], "portland")
+	test.Expect(t, permissions.Tags[0]["triggerList"], true)
+	test.Expect(t, permissions.Tags[0]["deviceTagging"], false)
+
+	err = client.Request("tag/permissions", map[string]interface{}{"tags": []string{"portland", "seattle"}}, &permissions)
+	test.Expect(t, err, nil)
+	test.Expect(t, len(permissions.Tags), 2)
+	test.Expect(t, permissions.Tags[0]["triggerList"], true)
+	test.Expect(t, permissions.Tags[1]["triggerList"], false)
+
+	err = client.Request("tag/permissions/update", map[string]interface{}{"tags": "portland", "flying": true}, &permissions)
+	test.Refute(t, err, nil)
+	test.Expect(t, err.Error(), "Error from /tag/permissions/update, code: 400. Message: Unknown permission: flying")
+}
+
+func TestTriggerHistory(t *testing.T) {
+	server := NewServer()
+	defer server.Close()
+	server.AddApplication("good_client_id", "good_client_secret")
+	server.AddTrigger(geotrigger.Trigger{TriggerID: "cafe", Tags: []string{"food"}})
+
+	start := time.Date(2014, 5, 14, 18, 0, 0, 0, time.UTC)
+	server.AddHistory(
+		geotrigger.HistoryEvent{TriggerID: "cafe", DeviceID: "dev1", Direction: "enter", Timestamp: start},
+		geotrigger.HistoryEvent{TriggerID: "park", DeviceID: "dev1", Direction: "enter", Timestamp: start.Add(time.Hour)},
+		geotrigger.HistoryEvent{TriggerID: "cafe", DeviceID: "dev2", Direction: "enter", Timestamp: start.Add(2 * time.Hour)},
+	)
+
+	client, err := geotrigger.NewApplicationWithEnvironment(server.Environment(), "good_client_id", "good_client_secret")
+	test.Expect(t, err, nil)
+	ctx := context.Background()
+
+	events, err := client.TriggerHistory(ctx, nil)
+	test.Expect(t, err, nil)
+	test.Expect(t, len(events), 3)
+
+	events, err = client.TriggerHistory(ctx, &geotrigger.HistoryParams{Tags: []string{"food"}})
+	test.Expect(t, err, nil)
+	test.Expect(t, len(events), 2)
+
+	from := start.Add(30 * time.Minute)
+	events, err = client.TriggerHistory(ctx, &geotrigger.HistoryParams{DeviceIDs: []string{"dev1"}, FromTimestamp: &from})
+	test.Expect(t, err, nil)
+	test.Expect(t, len(events), 1)
+	test.Expect(t, events[0].TriggerID, "park")
+
+	device, err := geotrigger.NewDeviceWithEnvironment(server.Environment(), "good_client_id")
+	test.Expect(t, err, nil)
+	deviceID := device.Info()["device_id"]
+
+	response, err := client.RunTrigger(ctx, &geotrigger.RunParams{TriggerIDs: []string{"cafe"}, DeviceIDs: []string{deviceID}})
+	test.Expect(t, err, nil)
+	test.Expect(t, response.Triggers[0].TriggerID, "cafe")
+
+	events, err = client.TriggerHistory(ctx, &geotrigger.HistoryParams{DeviceIDs: []string{deviceID}})
+	test.Expect(t, err, nil)
+	test.Expect(t, len(events), 1)
+	test.Expect(t, events[0].TriggerID, "cafe")
+
+	_, err = client.RunTrigger(ctx, &geotrigger.RunParams{TriggerIDs: []string{"nope"}, Tags: []string{"food"}})
+	test.Refute(t, err, nil)
+	test.Expect(t, err.Error(), "Error from /trigger/run, code: 400. Message: No triggers found: nope")
+}
+
+func TestExpiredTokensAreRefreshed(t *testing.T) {
+	server := NewServer()
+	defer server.Close()
+	server.AddApplication("good_client_id", "good_client_secret")
+
+	device, err := geotrigger.NewDeviceWithEnvironment(server.Environment(), "good_client_id")
+	test.Expect(t, err, nil)
+	app, err := geotrigger.NewApplicationWithEnvironment(server.Environment(), "good_client_id", "good_client_secret")
+	test.Expect(t, err, nil)
+
+	oldDeviceToken := device.Info()["access_token"]
+	oldAppToken := app.Info()["access_token"]
+	server.ExpireTokens()
+
+	var response map[string]interface{}
+	err = device.Request("device/list", nil, &response)
+	test.Expect(t, err, nil)
+	test.Refute(t, device.Info()["access_token"], oldDeviceToken)
+
+	err = app.Request("device/list", nil, &response)
+	test.Expect(t, err, nil)
+	test.Refute(t, app.Info()["access_token"], oldAppToken)
+	test.Expect(t, len(response["devices"].([]interface{})), 1)
+}
@@ -0,0 +1,151 @@
+package geotrigger
+
+import (
+	"context"
+	"sort"
+	"time"
+)
+
+// HistoryEvent is a single firing of a trigger, as listed by
+// `trigger/history`.
+type HistoryEvent struct {
+	TriggerID string                 `json:"triggerId"`
+	DeviceID  string                 `json:"deviceId"`
+	Direction string                 `json:"direction"`
+	Timestamp time.Time              `json:"timestamp"`
+	Location  Location               `json:"location"`
+	Extra     map[string]interface{} `json:"-"`
+}
+
+// HistoryParams selects the events TriggerHistory lists. Fields left empty
+// don't narrow the selection.
+type HistoryParams struct {
+	TriggerIDs    []string   `json:"triggerIds,omitempty"`
+	DeviceIDs     []string   `json:"deviceIds,omitempty"`
+	Tags          []string   `json:"tags,omitempty"`
+	FromTimestamp *time.Time `json:"fromTimestamp,omitempty"`
+	ToTimestamp   *time.Time `json:"toTimestamp,omitempty"`
+}
+
+// Dwell is a visit of a device to a place, from an enter event to the leave
+// event that followed it.
+type Dwell struct {
+	DeviceID string
+	Place    string
+	Enter    time.Time
+	Leave    time.Time
+	Duration time.Duration
+}
+
+// TriggerHistory lists the times triggers fired, oldest first, through
+// `trigger/history`. The events are streamed, as with Stream, so long
+// histories aren't held in memory twice.
+func (client *Client) TriggerHistory(ctx context.Context, params *HistoryParams) ([]HistoryEvent, error) {
+	if params == nil {
+		params = &HistoryParams{}
+	}
+
+	events := []HistoryEvent{}
+	err := Stream(ctx, client, "trigger/history", params, "logs", func(event HistoryEvent) error {
+		events = append(events, event)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(events, func(i, j int) bool {
+		return events[i].Timestamp.Before(events[j].Timestamp)
+	})
+
+	return events, nil
+}
+
+// CountByTrigger counts events per trigger ID.
+func CountByTrigger(events []HistoryEvent) map[string]int {
+	counts := make(map[string]int)
+	for _, event := range events {
+		counts[event.TriggerID]++
+	}
+
+	return counts
+}
+
+// CountByDevice counts events per device ID.
+func CountByDevice(events []HistoryEvent) map[string]int {
+	counts := make(map[string]int)
+	for _, event := range events {
+		counts[event.DeviceID]++
+	}
+
+	return counts
+}
+
+// CountByHour counts events per hour, keyed by the start of the hour in UTC.
+func CountByHour(events []HistoryEvent) map[time.Time]int {
+	counts := make(map[time.Time]int)
+	for _, event := range events {
+		counts[event.Timestamp.UTC().Truncate(time.Hour)]++
+	}
+
+	return counts
+}
+
+// DwellTimes pairs each enter event with the next leave event of the same
+// device at the same place, returning the visits ordered by when they began.
+// An enter and a leave usually come from two triggers sharing a fence, so
+// `place` names the place an event happened at, such as by looking its
+// trigger ID up in a map; if nil, the trigger ID itself is the place.
+//
+// Devices still inside a place at the end of the events have no dwell for
+// it, and a leave without an enter before it is ignored. A second enter
+// before a leave starts the visit over.
+func DwellTimes(events []HistoryEvent, place func(event *HistoryEvent) string) []Dwell {
+	if place == nil {
+		place = func(event *HistoryEvent) string {
+			return event.TriggerID
+		}
+	}
+
+	sorted := append([]HistoryEvent(nil), events...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Timestamp.Before(sorted[j].Timestamp)
+	})
+
+	type visitor struct {
+		deviceID string
+		place    string
+	}
+	entered := make(map[visitor]time.Time)
+
+	dwells := []Dwell{}
+	for i := range sorted {
+		event := &sorted[i]
+		key := visitor{event.DeviceID, place(event)}
+
+		switch event.Direction {
+		case DirectionEnter:
+			entered[key] = event.Timestamp
+		case DirectionLeave:
+			enter, ok := entered[key]
+			if !ok {
+				continue
+			}
+			delete(entered, key)
+
+			dwells = append(dwells, Dwell{
+				DeviceID: key.deviceID,
+				Place:    key.place,
+				Enter:    enter,
+				Leave:    event.Timestamp,
+				Duration: event.Timestamp.Sub(enter),
+			})
+		}
+	}
+
+	sort.SliceStable(dwells, func(i, j int) bool {
+		return dwells[i].Enter.Before(dwells[j].Enter)
+	})
+
+	return dwells
+}
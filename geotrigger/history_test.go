@@ -0,0 +1,95 @@
+package geotrigger
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/Esri/geotrigger-go/geotrigger/test"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+/* editing these will break tests */
+var historyData = `{"logs":[
+{"triggerId":"cafe-leave","deviceId":"dev1","direction":"leave","timestamp":"2014-05-14T18:45:00Z","location":{"latitude":45.51,"longitude":-122.67}},
+{"triggerId":"cafe-enter","deviceId":"dev1","direction":"enter","timestamp":"2014-05-14T18:15:00Z","location":{"latitude":45.51,"longitude":-122.67},"actionType":"message"},
+{"triggerId":"cafe-enter","deviceId":"dev2","direction":"enter","timestamp":"2014-05-14T19:05:00Z"},
+{"triggerId":"cafe-leave","deviceId":"dev2","direction":"leave","timestamp":"2014-05-14T19:20:00Z"},
+{"triggerId":"cafe-enter","deviceId":"dev1","direction":"enter","timestamp":"2014-05-14T19:30:00Z"}
+]}`
+
+func historyEvents(t *testing.T) []HistoryEvent {
+	var response struct {
+		Logs []HistoryEvent `json:"logs"`
+	}
+	test.Expect(t, json.Unmarshal([]byte(historyData), &response), nil)
+	return response.Logs
+}
+
+func TestTriggerHistory(t *testing.T) {
+	var params map[string]interface{}
+	gtServer := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, r *http.Request) {
+		test.Expect(t, r.URL.Path, "/trigger/history")
+		body, _ := ioutil.ReadAll(r.Body)
+		test.Expect(t, json.Unmarshal(body, &params), nil)
+		res.Write([]byte(historyData))
+	}))
+	defer gtServer.Close()
+
+	client := ExistingDeviceWithEnvironment(Environment{GeotriggerURL: gtServer.URL}, "good_client_id",
+		"device_id", "good_access_token", 1800, "good_refresh_token")
+
+	from := time.Date(2014, 5, 14, 0, 0, 0, 0, time.UTC)
+	events, err := client.TriggerHistory(context.Background(), &HistoryParams{
+		TriggerIDs:    []string{"cafe-enter", "cafe-leave"},
+		FromTimestamp: &from,
+	})
+	test.Expect(t, err, nil)
+	test.Expect(t, params["triggerIds"], []interface{}{"cafe-enter", "cafe-leave"})
+	test.Expect(t, params["fromTimestamp"], "2014-05-14T00:00:00Z")
+	_, ok := params["deviceIds"]
+	test.Expect(t, ok, false)
+
+	// oldest first
+	test.Expect(t, len(events), 5)
+	test.Expect(t, events[0].TriggerID, "cafe-enter")
+	test.Expect(t, events[0].Location.Latitude, 45.51)
+	test.Expect(t, events[0].Extra["actionType"], "message")
+	test.Expect(t, events[1].Direction, DirectionLeave)
+
+	params = nil
+	events, err = client.TriggerHistory(context.Background(), nil)
+	test.Expect(t, err, nil)
+	test.Expect(t, len(params), 0)
+}
+
+func TestHistoryCounts(t *testing.T) {
+	events := historyEvents(t)
+
+	test.Expect(t, CountByTrigger(events), map[string]int{"cafe-enter": 3, "cafe-leave": 2})
+	test.Expect(t, CountByDevice(events), map[string]int{"dev1": 3, "dev2": 2})
+	test.Expect(t, CountByHour(events), map[time.Time]int{
+		time.Date(2014, 5, 14, 18, 0, 0, 0, time.UTC): 2,
+		time.Date(2014, 5, 14, 19, 0, 0, 0, time.UTC): 3,
+	})
+}
+
+func TestDwellTimes(t *testing.T) {
+	events := historyEvents(t)
+	cafe := func(event *HistoryEvent) string {
+		return "cafe"
+	}
+
+	dwells := DwellTimes(events, cafe)
+	test.Expect(t, len(dwells), 2)
+	test.Expect(t, dwells[0].DeviceID, "dev1")
+	test.Expect(t, dwells[0].Place, "cafe")
+	test.Expect(t, dwells[0].Duration, 30*time.Minute)
+	test.Expect(t, dwells[1].DeviceID, "dev2")
+	test.Expect(t, dwells[1].Duration, 15*time.Minute)
+
+	// by trigger ID, enters and leaves never meet
+	test.Expect(t, len(DwellTimes(events, nil)), 0)
+}
@@ -0,0 +1,171 @@
+package json
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"time"
+)
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// epoch values above this are taken to be in milliseconds, as no sensible
+// timestamp in seconds is this large (it's in the year 5138)
+const maxEpochSeconds = 1e11
+
+// timestamp formats accepted when parsing strings into a time.Time
+var timeLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05.999999999",
+	"2006-01-02 15:04:05.999999999Z07:00",
+	"2006-01-02 15:04:05.999999999",
+	"2006-01-02",
+}
+
+// convert attempts to turn a value found in JSON into a value of type `t`.
+func convert(jsonVal interface{}, t reflect.Type) (reflect.Value, error) {
+	actualType := reflect.TypeOf(jsonVal)
+
+	switch {
+	case t == timeType:
+		return convertTime(jsonVal)
+	case isNumericKind(t.Kind()):
+		if number, ok := numberValue(jsonVal); ok {
+			return convertNumber(number, t)
+		}
+	case t.Kind() == actualType.Kind() && actualType.ConvertibleTo(t):
+		// ie: a string into a named string type
+		return reflect.ValueOf(jsonVal).Convert(t), nil
+	}
+
+	switch jsonVal.(type) {
+	case map[string]interface{}, []interface{}:
+		if decoded, err := decodeInto(jsonVal, t); err == nil {
+			return decoded, nil
+		}
+	}
+
+	return reflect.Value{}, fmt.Errorf(
+		"Provided reference is to a value of type %s that cannot be assigned to type found in JSON: %s.",
+		t, actualType)
+}
+
+// decodeInto runs an object or array back through encoding/json, so it can
+// be read into a struct or a typed map or slice.
+func decodeInto(jsonVal interface{}, t reflect.Type) (reflect.Value, error) {
+	encoded, err := json.Marshal(jsonVal)
+	if err != nil {
+		return reflect.Value{}, err
+	}
+
+	// decode into a fresh value, so nothing is left half set on failure
+	decoded := reflect.New(t)
+	if err := json.Unmarshal(encoded, decoded.Interface()); err != nil {
+		return reflect.Value{}, err
+	}
+
+	return decoded.Elem(), nil
+}
+
+func isNumericKind(kind reflect.Kind) bool {
+	switch kind {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	}
+
+	return false
+}
+
+// numberValue reads a JSON number, as decoded by encoding/json with or
+// without UseNumber.
+func numberValue(jsonVal interface{}) (float64, bool) {
+	switch number := jsonVal.(type) {
+	case float64:
+		return number, true
+	case json.Number:
+		f, err := number.Float64()
+		return f, err == nil
+	}
+
+	return 0, false
+}
+
+func convertNumber(number float64, t reflect.Type) (reflect.Value, error) {
+	converted := reflect.New(t).Elem()
+
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if number != math.Trunc(number) {
+			return reflect.Value{}, fractionError(number, t)
+		}
+		if number < math.MinInt64 || number >= math.MaxInt64 || converted.OverflowInt(int64(number)) {
+			return reflect.Value{}, overflowError(number, t)
+		}
+		converted.SetInt(int64(number))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if number != math.Trunc(number) {
+			return reflect.Value{}, fractionError(number, t)
+		}
+		if number < 0 || number >= math.MaxUint64 || converted.OverflowUint(uint64(number)) {
+			return reflect.Value{}, overflowError(number, t)
+		}
+		converted.SetUint(uint64(number))
+	case reflect.Float32, reflect.Float64:
+		if converted.OverflowFloat(number) {
+			return reflect.Value{}, overflowError(number, t)
+		}
+		converted.SetFloat(number)
+	}
+
+	return converted, nil
+}
+
+func convertTime(jsonVal interface{}) (reflect.Value, error) {
+	timestamp, err := ParseTime(jsonVal)
+	if err != nil {
+		return reflect.Value{}, err
+	}
+
+	return reflect.ValueOf(timestamp), nil
+}
+
+// ParseTime reads a timestamp the way values found in JSON are converted to
+// a `time.Time`: from a number of epoch seconds, or milliseconds for values
+// too large to be seconds, or from an ISO 8601 string, with a `T` or a space
+// between the date and time.
+func ParseTime(jsonVal interface{}) (time.Time, error) {
+	if number, ok := numberValue(jsonVal); ok {
+		if math.Abs(number) > maxEpochSeconds {
+			number = number / 1000
+		}
+
+		seconds, fraction := math.Modf(number)
+		return time.Unix(int64(seconds), int64(fraction*1e9)).UTC(), nil
+	}
+
+	if timestamp, ok := jsonVal.(string); ok {
+		for _, layout := range timeLayouts {
+			if parsed, err := time.Parse(layout, timestamp); err == nil {
+				return parsed, nil
+			}
+		}
+
+		return time.Time{}, fmt.Errorf("Could not parse timestamp found in JSON: %s", timestamp)
+	}
+
+	return time.Time{}, fmt.Errorf(
+		"Provided reference is to a value of type %s that cannot be assigned to type found in JSON: %s.",
+		timeType, reflect.TypeOf(jsonVal))
+}
+
+func fractionError(number float64, t reflect.Type) error {
+	return fmt.Errorf("Number found in JSON (%v) cannot be stored in type %s without losing its fractional part.",
+		number, t)
+}
+
+func overflowError(number float64, t reflect.Type) error {
+	return fmt.Errorf("Number found in JSON (%v) overflows type %s.", number, t)
+}
@@ -0,0 +1,147 @@
+package json
+
+import (
+	"encoding/json"
+	"github.com/Esri/geotrigger-go/geotrigger/test"
+	"strings"
+	"testing"
+	"time"
+)
+
+type testGeo struct {
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+	Distance  int     `json:"distance"`
+}
+
+type testDirection string
+
+func TestNumericConversion(t *testing.T) {
+	object := map[string]interface{}{
+		"driveTime": float64(600),
+		"big":       float64(1 << 40),
+		"negative":  float64(-3),
+		"fraction":  1.5,
+		"number":    json.Number("42"),
+	}
+
+	var driveTime int64
+	err := GetValueFromJSONObject(object, "driveTime", &driveTime)
+	test.Expect(t, err, nil)
+	test.Expect(t, driveTime, int64(600))
+
+	var small int
+	err = GetValueFromJSONObject(object, "driveTime", &small)
+	test.Expect(t, err, nil)
+	test.Expect(t, small, 600)
+
+	var unsigned uint16
+	err = GetValueFromJSONObject(object, "driveTime", &unsigned)
+	test.Expect(t, err, nil)
+	test.Expect(t, unsigned, uint16(600))
+
+	var single float32
+	err = GetValueFromJSONObject(object, "fraction", &single)
+	test.Expect(t, err, nil)
+	test.Expect(t, single, float32(1.5))
+
+	var fromNumber int
+	err = GetValueFromJSONObject(object, "number", &fromNumber)
+	test.Expect(t, err, nil)
+	test.Expect(t, fromNumber, 42)
+
+	// overflow and lossy conversions fail, leaving the value alone
+	var tiny int8 = 7
+	err = GetValueFromJSONObject(object, "driveTime", &tiny)
+	test.Expect(t, err.Error(), "Number found in JSON (600) overflows type int8.")
+	test.Expect(t, tiny, int8(7))
+
+	var big int32
+	err = GetValueFromJSONObject(object, "big", &big)
+	test.Expect(t, err.Error(), "Number found in JSON (1.099511627776e+12) overflows type int32.")
+
+	err = GetValueFromJSONObject(object, "negative", &unsigned)
+	test.Expect(t, err.Error(), "Number found in JSON (-3) overflows type uint16.")
+
+	err = GetValueFromJSONObject(object, "fraction", &small)
+	test.Expect(t, err.Error(), "Number found in JSON (1.5) cannot be stored in type int without losing its fractional part.")
+}
+
+func TestTimeConversion(t *testing.T) {
+	object := map[string]interface{}{
+		"iso":     "2014-04-22T17:30:00.5Z",
+		"date":    "2014-04-22",
+		"seconds": float64(1398187800),
+		"millis":  float64(1389531528000),
+		"junk":    "yesterday",
+	}
+
+	var timestamp time.Time
+	err := GetValueFromJSONObject(object, "iso", &timestamp)
+	test.Expect(t, err, nil)
+	test.Expect(t, timestamp.Equal(time.Date(2014, 4, 22, 17, 30, 0, 5e8, time.UTC)), true)
+
+	err = GetValueFromJSONObject(object, "date", &timestamp)
+	test.Expect(t, err, nil)
+	test.Expect(t, timestamp.Equal(time.Date(2014, 4, 22, 0, 0, 0, 0, time.UTC)), true)
+
+	err = GetValueFromJSONObject(object, "seconds", &timestamp)
+	test.Expect(t, err, nil)
+	test.Expect(t, timestamp.Equal(time.Date(2014, 4, 22, 17, 30, 0, 0, time.UTC)), true)
+
+	err = GetValueFromJSONObject(object, "millis", &timestamp)
+	test.Expect(t, err, nil)
+	test.Expect(t, timestamp.Equal(time.Unix(1389531528, 0)), true)
+
+	err = GetValueFromJSONObject(object, "junk", &timestamp)
+	test.Expect(t, err.Error(), "Could not parse timestamp found in JSON: yesterday")
+
+	timestamp, err = ParseTime("2014-04-22 17:30:00")
+	test.Expect(t, err, nil)
+	test.Expect(t, timestamp.Equal(time.Date(2014, 4, 22, 17, 30, 0, 0, time.UTC)), true)
+
+	_, err = ParseTime(true)
+	test.Expect(t, err.Error(), "Provided reference is to a value of type time.Time that cannot be assigned to type found in JSON: bool.")
+}
+
+func TestStructConversion(t *testing.T) {
+	var responseJSON map[string]interface{}
+	err := json.Unmarshal([]byte(`{"geo":{"latitude":45.5,"longitude":-122.6,"distance":100},"tags":["a","b"],"direction":"enter","nothing":null}`), &responseJSON)
+	test.Expect(t, err, nil)
+
+	var geo testGeo
+	err = GetValueFromJSONObject(responseJSON, "geo", &geo)
+	test.Expect(t, err, nil)
+	test.Expect(t, geo, testGeo{45.5, -122.6, 100})
+
+	var geoPointer *testGeo
+	err = GetValueFromJSONObject(responseJSON, "geo", &geoPointer)
+	test.Expect(t, err, nil)
+	test.Expect(t, geoPointer.Distance, 100)
+
+	var tags []string
+	err = GetValueFromJSONObject(responseJSON, "tags", &tags)
+	test.Expect(t, err, nil)
+	test.Expect(t, tags, []string{"a", "b"})
+
+	var direction testDirection
+	err = GetValueFromJSONObject(responseJSON, "direction", &direction)
+	test.Expect(t, err, nil)
+	test.Expect(t, direction, testDirection("enter"))
+
+	// null gives the zero value
+	direction = "leave"
+	err = GetValueFromJSONObject(responseJSON, "nothing", &direction)
+	test.Expect(t, err, nil)
+	test.Expect(t, direction, testDirection(""))
+
+	var numbers []int
+	err = GetValueFromJSONObject(responseJSON, "tags", &numbers)
+	test.Refute(t, err, nil)
+	test.Expect(t, strings.Index(err.Error(), "Provided reference is to a value of type []int"), 0)
+	test.Expect(t, numbers, nil)
+
+	var nilPointer *testGeo
+	err = GetValueFromJSONObject(responseJSON, "geo", nilPointer)
+	test.Expect(t, err.Error(), "Provided value is a nil pointer.")
+}
@@ -1,3 +1,13 @@
+// Package `json` provides helpers for reading values out of JSON that was
+// decoded into generic maps and slices, as with a `map[string]interface{}`
+// passed to `Client.Request`.
+//
+// Values are converted to the type asked for when they can be: JSON numbers
+// to any numeric type that can hold them exactly, numbers (epoch seconds, or
+// milliseconds for values too large to be seconds) and ISO 8601 strings to
+// `time.Time`, and objects and arrays to structs, maps and slices, following
+// the usual `encoding/json` rules. A JSON null gives the zero value of the
+// type asked for, as it does with `encoding/json`.
 package json
 
 import (
@@ -56,32 +66,37 @@ func setVal(value interface{}, jsonVal interface{}) (err error) {
 		return errors.New("Provided value is of invalid type (must be pointer).")
 	}
 
+	pv := reflect.ValueOf(value)
+	if pv.IsNil() {
+		return errors.New("Provided value is a nil pointer.")
+	}
+
 	// we know it's a pointer, so get the type of value being pointed to
 	expectedType = expectedType.Elem()
+	// Elem() gets the value being pointed to, which is what we will be setting.
+	v := pv.Elem()
+
+	// a JSON null leaves us with the zero value, as it would with encoding/json
+	if jsonVal == nil {
+		v.Set(reflect.Zero(expectedType))
+		return
+	}
 
 	// compare that type to the type pulled from the JSON
 	actualType := reflect.TypeOf(jsonVal)
 
-	if !actualType.AssignableTo(expectedType) {
-		return fmt.Errorf(
-			"Provided reference is to a value of type %s that cannot be assigned to type found in JSON: %s.",
-			expectedType, actualType)
+	if actualType.AssignableTo(expectedType) {
+		// we can set it directly to what we found in the JSON
+		v.Set(reflect.ValueOf(jsonVal))
+		return
 	}
 
-	// Time to set the new value being pointed to by the passed in interface.
-	// We know it's a pointer, so its value will be a reference to the value
-	// we are actually interested in changing.
-	pv := reflect.ValueOf(value)
-	if !pv.IsValid() {
-		// This should be a rare occurrence.
-		// the value provided could not be reflected, and we have an invalid Value here
-		// so just attempt to use the zero value for the destination type.
-		pv = reflect.Zero(actualType)
+	// otherwise, see if the JSON value can be converted, ie: a float64 into an int
+	converted, err := convert(jsonVal, expectedType)
+	if err != nil {
+		return err
 	}
-	// Elem() gets the value being pointed to,
-	v := pv.Elem()
-	// and we can set it directly to what we found in the JSON, since we
-	// have already checked that they the JSON value is assignable.
-	v.Set(reflect.ValueOf(jsonVal))
+
+	v.Set(converted)
 	return
 }
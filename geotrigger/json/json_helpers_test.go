@@ -2,10 +2,33 @@ package json
 
 import (
 	"encoding/json"
-	"testing"
 	"github.com/Esri/geotrigger-go/geotrigger/test"
+	"testing"
 )
 
+type Trigger struct {
+	TriggerID string    `json:"triggerId"`
+	Condition Condition `json:"condition"`
+	Tags      []string  `json:"tags"`
+}
+
+type Condition struct {
+	Direction string `json:"direction"`
+	Geo       Geo    `json:"geo"`
+}
+
+type Geo struct {
+	Geocode   string `json:"geocode"`
+	DriveTime int    `json:"driveTime"`
+}
+
+type BoundingBox struct {
+	Xmin float64 `json:"xmin"`
+	Ymin float64 `json:"ymin"`
+	Xmax float64 `json:"xmax"`
+	Ymax float64 `json:"ymax"`
+}
+
 /* editing these will break tests */
 var triggerListData = []byte(`{"triggers":[{"triggerId":"6fd01180fa1a012f27f1705681b27197","condition":{"direction":"enter","geo":{"geocode":"920 SW 3rd Ave, Portland, OR","driveTime":600,"context":{"locality":"Portland","region":"Oregon","country":"USA","zipcode":"97204"}}},"action":{"message":"Welcome to Portland - The Mayor","callback":"http://pdx.gov/welcome"},"tags":["foodcarts","citygreetings"]}],"boundingBox":{"xmin":-122.68,"ymin":45.53,"xmax":-122.45,"ymax":45.6}}`)
 
@@ -102,7 +125,7 @@ func TestValueGetters(t *testing.T) {
 	var wrongType1 BoundingBox
 	err = GetValueFromJSONObject(responseJSON, "triggers", &wrongType1)
 	test.Refute(t, err, nil)
-	test.Expect(t, err.Error(), "Provided reference is to a value of type geotrigger.BoundingBox that cannot be assigned to type found in JSON: []interface {}.")
+	test.Expect(t, err.Error(), "Provided reference is to a value of type json.BoundingBox that cannot be assigned to type found in JSON: []interface {}.")
 	test.Expect(t, notAPointer3.Xmin, float64(0))
 
 	var wrongType2 []interface{}
@@ -0,0 +1,179 @@
+package json
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// GetValueAtPath is like GetValueFromJSONObject, but reaches into nested
+// objects and arrays following a dot separated path, ie:
+// `triggers.0.condition.geo.latitude`. Numeric segments index arrays, other
+// segments are object keys; a literal dot in a key can be escaped as `\.`.
+// `root` is usually the `map[string]interface{}` a response was parsed into.
+// Errors name the path segment that could not be followed. A null at the end
+// of the path gives the zero value, as with GetValueFromJSONObject; a null
+// along the way is an error.
+func GetValueAtPath(root interface{}, path string, value interface{}) error {
+	segments, err := splitPath(path)
+	if err != nil {
+		return err
+	}
+
+	if root == nil {
+		return errors.New("Attempt to get value from a nil JSON value.")
+	}
+
+	current := root
+	for i, segment := range segments {
+		var err error
+		if current, err = lookup(current, segment); err != nil {
+			return pathError(path, segments[:i+1], err)
+		}
+	}
+
+	if err := setVal(value, current); err != nil {
+		return pathError(path, segments, err)
+	}
+
+	return nil
+}
+
+// SetValueAtPath sets a value inside `root` following a path in the format
+// used by GetValueAtPath, which is handy for building request params. Missing
+// objects along the way are created. Numeric segments index existing arrays;
+// an index equal to the length of the array appends to it.
+func SetValueAtPath(root map[string]interface{}, path string, value interface{}) error {
+	segments, err := splitPath(path)
+	if err != nil {
+		return err
+	}
+
+	if root == nil {
+		return errors.New("Attempt to set value in a nil JSON object.")
+	}
+
+	_, err = setAt(root, path, segments, 0, value)
+	return err
+}
+
+func lookup(container interface{}, segment string) (interface{}, error) {
+	switch container := container.(type) {
+	case map[string]interface{}:
+		value, ok := container[segment]
+		if !ok {
+			return nil, fmt.Errorf("No value found for key: %s", segment)
+		}
+		return value, nil
+	case []interface{}:
+		index, err := strconv.Atoi(segment)
+		if err != nil {
+			return nil, fmt.Errorf("Expected an array index, got: %s", segment)
+		}
+		if index < 0 || index >= len(container) {
+			return nil, fmt.Errorf("Provided index %d was out of range.", index)
+		}
+		return container[index], nil
+	case nil:
+		return nil, errors.New("Value is null.")
+	}
+
+	return nil, fmt.Errorf("Cannot look up %s in a value of type %T.", segment, container)
+}
+
+// setAt sets the value below `container`, returning the container to store in
+// its parent, as appending to an array may have moved it.
+func setAt(container interface{}, path string, segments []string, i int, value interface{}) (interface{}, error) {
+	segment := segments[i]
+	last := i == len(segments)-1
+
+	switch current := container.(type) {
+	case map[string]interface{}:
+		if last {
+			current[segment] = value
+			return current, nil
+		}
+
+		child, ok := current[segment]
+		if !ok || child == nil {
+			child = make(map[string]interface{})
+		}
+
+		child, err := setAt(child, path, segments, i+1, value)
+		if err != nil {
+			return nil, err
+		}
+		current[segment] = child
+		return current, nil
+	case []interface{}:
+		index, err := strconv.Atoi(segment)
+		if err != nil {
+			return nil, pathError(path, segments[:i+1], fmt.Errorf("Expected an array index, got: %s", segment))
+		}
+		if index < 0 || index > len(current) {
+			return nil, pathError(path, segments[:i+1], fmt.Errorf("Provided index %d was out of range.", index))
+		}
+		if index == len(current) {
+			current = append(current, nil)
+		}
+
+		if last {
+			current[index] = value
+			return current, nil
+		}
+
+		child := current[index]
+		if child == nil {
+			child = make(map[string]interface{})
+		}
+
+		child, err = setAt(child, path, segments, i+1, value)
+		if err != nil {
+			return nil, err
+		}
+		current[index] = child
+		return current, nil
+	}
+
+	return nil, pathError(path, segments[:i],
+		fmt.Errorf("Cannot set %s in a value of type %T.", segment, container))
+}
+
+// splitPath splits a path on unescaped dots.
+func splitPath(path string) ([]string, error) {
+	if len(path) == 0 {
+		return nil, errors.New("Attempt to use an empty JSON path.")
+	}
+
+	var segments []string
+	var segment strings.Builder
+	escaped := false
+	for _, r := range path {
+		switch {
+		case escaped:
+			segment.WriteRune(r)
+			escaped = false
+		case r == '\\':
+			escaped = true
+		case r == '.':
+			segments = append(segments, segment.String())
+			segment.Reset()
+		default:
+			segment.WriteRune(r)
+		}
+	}
+	segments = append(segments, segment.String())
+
+	for _, segment := range segments {
+		if len(segment) == 0 {
+			return nil, fmt.Errorf("Invalid JSON path (empty segment): %s", path)
+		}
+	}
+
+	return segments, nil
+}
+
+func pathError(path string, segments []string, err error) error {
+	return fmt.Errorf("Error at %s in path %s: %s", strings.Join(segments, "."), path, err)
+}
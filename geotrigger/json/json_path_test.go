@@ -0,0 +1,113 @@
+package json
+
+import (
+	"encoding/json"
+	"github.com/Esri/geotrigger-go/geotrigger/test"
+	"testing"
+)
+
+func TestGetValueAtPath(t *testing.T) {
+	var responseJSON map[string]interface{}
+	err := json.Unmarshal(triggerListData, &responseJSON)
+	test.Expect(t, err, nil)
+
+	var zipcode string
+	err = GetValueAtPath(responseJSON, "triggers.0.condition.geo.context.zipcode", &zipcode)
+	test.Expect(t, err, nil)
+	test.Expect(t, zipcode, "97204")
+
+	var tag string
+	err = GetValueAtPath(responseJSON, "triggers.0.tags.1", &tag)
+	test.Expect(t, err, nil)
+	test.Expect(t, tag, "citygreetings")
+
+	var xmax float64
+	err = GetValueAtPath(responseJSON, "boundingBox.xmax", &xmax)
+	test.Expect(t, err, nil)
+	test.Expect(t, xmax, -122.45)
+
+	// arrays work as a root too
+	var triggers []interface{}
+	err = GetValueAtPath(responseJSON, "triggers", &triggers)
+	test.Expect(t, err, nil)
+	var triggerID string
+	err = GetValueAtPath(triggers, "0.triggerId", &triggerID)
+	test.Expect(t, err, nil)
+	test.Expect(t, triggerID, "6fd01180fa1a012f27f1705681b27197")
+
+	// escaped dots
+	dotted := map[string]interface{}{"a.b": map[string]interface{}{"c": "d"}}
+	var c string
+	err = GetValueAtPath(dotted, `a\.b.c`, &c)
+	test.Expect(t, err, nil)
+	test.Expect(t, c, "d")
+
+	// errors name the failing segment
+	var failure interface{}
+	err = GetValueAtPath(responseJSON, "triggers.3.tags", &failure)
+	test.Expect(t, err.Error(), "Error at triggers.3 in path triggers.3.tags: Provided index 3 was out of range.")
+
+	err = GetValueAtPath(responseJSON, "triggers.first.tags", &failure)
+	test.Expect(t, err.Error(), "Error at triggers.first in path triggers.first.tags: Expected an array index, got: first")
+
+	err = GetValueAtPath(responseJSON, "triggers.0.condition.geo.latitude", &failure)
+	test.Expect(t, err.Error(), "Error at triggers.0.condition.geo.latitude in path triggers.0.condition.geo.latitude: No value found for key: latitude")
+
+	err = GetValueAtPath(responseJSON, "triggers.0.triggerId.derp", &failure)
+	test.Expect(t, err.Error(), "Error at triggers.0.triggerId.derp in path triggers.0.triggerId.derp: Cannot look up derp in a value of type string.")
+
+	var wrongType int
+	err = GetValueAtPath(responseJSON, "triggers.0.triggerId", &wrongType)
+	test.Expect(t, err.Error(), "Error at triggers.0.triggerId in path triggers.0.triggerId: Provided reference is to a value of type int that cannot be assigned to type found in JSON: string.")
+
+	err = GetValueAtPath(responseJSON, "triggers..tags", &failure)
+	test.Expect(t, err.Error(), "Invalid JSON path (empty segment): triggers..tags")
+
+	err = GetValueAtPath(nil, "triggers", &failure)
+	test.Expect(t, err.Error(), "Attempt to get value from a nil JSON value.")
+
+	// a null gives the zero value, as it does for GetValueFromJSONObject, but
+	// can't be looked into
+	nulls := map[string]interface{}{"derp": nil}
+	text := "herp"
+	err = GetValueAtPath(nulls, "derp", &text)
+	test.Expect(t, err, nil)
+	test.Expect(t, text, "")
+	text = "herp"
+	test.Expect(t, GetValueFromJSONObject(nulls, "derp", &text), nil)
+	test.Expect(t, text, "")
+
+	err = GetValueAtPath(nulls, "derp.herp", &failure)
+	test.Expect(t, err.Error(), "Error at derp.herp in path derp.herp: Value is null.")
+}
+
+func TestSetValueAtPath(t *testing.T) {
+	params := map[string]interface{}{}
+
+	err := SetValueAtPath(params, "condition.geo.latitude", 45.5)
+	test.Expect(t, err, nil)
+	err = SetValueAtPath(params, "condition.geo.longitude", -122.6)
+	test.Expect(t, err, nil)
+	err = SetValueAtPath(params, "condition.direction", "enter")
+	test.Expect(t, err, nil)
+
+	// append to, then change, an array
+	params["setTags"] = []interface{}{"herp"}
+	err = SetValueAtPath(params, "setTags.1", "derp")
+	test.Expect(t, err, nil)
+	err = SetValueAtPath(params, "setTags.0", "dorp")
+	test.Expect(t, err, nil)
+
+	encoded, err := json.Marshal(params)
+	test.Expect(t, err, nil)
+	test.Expect(t, string(encoded), `{"condition":{"direction":"enter","geo":{"latitude":45.5,"longitude":-122.6}},"setTags":["dorp","derp"]}`)
+
+	err = SetValueAtPath(params, "setTags.5", "derp")
+	test.Expect(t, err.Error(), "Error at setTags.5 in path setTags.5: Provided index 5 was out of range.")
+
+	err = SetValueAtPath(params, "condition.direction.derp", "derp")
+	test.Expect(t, err.Error(), "Error at condition.direction in path condition.direction.derp: Cannot set derp in a value of type string.")
+
+	err = SetValueAtPath(nil, "derp", "derp")
+	test.Expect(t, err.Error(), "Attempt to set value in a nil JSON object.")
+}
@@ -0,0 +1,40 @@
+package json
+
+// Get returns the value found at `key` in a JSON object, converted to type T
+// the same way as GetValueFromJSONObject would, ie:
+//
+//	triggerID, err := json.Get[string](trigger, "triggerId")
+//
+// The zero value of T is returned along with any error.
+func Get[T any](jsonObject map[string]interface{}, key string) (T, error) {
+	var value T
+	if err := GetValueFromJSONObject(jsonObject, key, &value); err != nil {
+		var zero T
+		return zero, err
+	}
+
+	return value, nil
+}
+
+// Index returns the value found at `index` in a JSON array, converted to type
+// T the same way as GetValueFromJSONArray would.
+func Index[T any](jsonArray []interface{}, index int) (T, error) {
+	var value T
+	if err := GetValueFromJSONArray(jsonArray, index, &value); err != nil {
+		var zero T
+		return zero, err
+	}
+
+	return value, nil
+}
+
+// GetOr is like Get, but returns `defaultValue` when the key is missing or
+// null, which suits optional fields. A value that is present but cannot be
+// converted to T is still an error.
+func GetOr[T any](jsonObject map[string]interface{}, key string, defaultValue T) (T, error) {
+	if jsonVal, ok := jsonObject[key]; !ok || jsonVal == nil {
+		return defaultValue, nil
+	}
+
+	return Get[T](jsonObject, key)
+}
@@ -0,0 +1,69 @@
+package json
+
+import (
+	"encoding/json"
+	"github.com/Esri/geotrigger-go/geotrigger/test"
+	"testing"
+)
+
+func TestGet(t *testing.T) {
+	var responseJSON map[string]interface{}
+	err := json.Unmarshal(triggerListData, &responseJSON)
+	test.Expect(t, err, nil)
+
+	triggers, err := Get[[]interface{}](responseJSON, "triggers")
+	test.Expect(t, err, nil)
+	test.Expect(t, len(triggers), 1)
+
+	trigger, err := Index[map[string]interface{}](triggers, 0)
+	test.Expect(t, err, nil)
+
+	triggerID, err := Get[string](trigger, "triggerId")
+	test.Expect(t, err, nil)
+	test.Expect(t, triggerID, "6fd01180fa1a012f27f1705681b27197")
+
+	tags, err := Get[[]string](trigger, "tags")
+	test.Expect(t, err, nil)
+	test.Expect(t, tags[1], "citygreetings")
+
+	boundingBox, err := Get[map[string]float64](responseJSON, "boundingBox")
+	test.Expect(t, err, nil)
+	test.Expect(t, boundingBox["xmax"], -122.45)
+
+	count, err := Get[int](responseJSON, "triggers")
+	test.Expect(t, err.Error(), "Provided reference is to a value of type int that cannot be assigned to type found in JSON: []interface {}.")
+	test.Expect(t, count, 0)
+
+	_, err = Get[string](trigger, "derp")
+	test.Expect(t, err.Error(), "No value found for key: derp")
+
+	_, err = Index[map[string]interface{}](triggers, 3)
+	test.Expect(t, err.Error(), "Provided index 3 was out of range.")
+}
+
+func TestGetOr(t *testing.T) {
+	object := map[string]interface{}{
+		"distance":  float64(100),
+		"direction": nil,
+		"tags":      "derp",
+	}
+
+	distance, err := GetOr(object, "distance", 50)
+	test.Expect(t, err, nil)
+	test.Expect(t, distance, 100)
+
+	direction, err := GetOr(object, "direction", "enter")
+	test.Expect(t, err, nil)
+	test.Expect(t, direction, "enter")
+
+	notes, err := GetOr(object, "notes", "none")
+	test.Expect(t, err, nil)
+	test.Expect(t, notes, "none")
+
+	_, err = GetOr(object, "tags", []string{})
+	test.Expect(t, err.Error(), "Provided reference is to a value of type []string that cannot be assigned to type found in JSON: string.")
+
+	notes, err = GetOr(nil, "notes", "none")
+	test.Expect(t, err, nil)
+	test.Expect(t, notes, "none")
+}
@@ -0,0 +1,19 @@
+package geotrigger
+
+import (
+	"time"
+)
+
+// Location is a single device location, as sent to `location/update`.
+type Location struct {
+	Latitude  float64   `json:"latitude"`
+	Longitude float64   `json:"longitude"`
+	Accuracy  float64   `json:"accuracy"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// LocationUpdateParams are the params for `location/update`, which devices use
+// to report where they've been, oldest first.
+type LocationUpdateParams struct {
+	Locations []Location `json:"locations"`
+}
@@ -0,0 +1,126 @@
+package geotrigger
+
+import (
+	"io"
+	"net/http"
+	"strings"
+)
+
+// Handler sends a request to the Geotrigger Service and returns its
+// response, as `http.Client.Do` does.
+type Handler func(req *http.Request) (*http.Response, error)
+
+// Middleware wraps the Handler that sends a client's requests to the
+// Geotrigger Service, to inject headers, log, record metrics, or answer
+// requests itself as a mock would. A middleware gets each request after its
+// params have been validated and its access token set, and can call `next`
+// to send it on, or return a response of its own; a response is read the
+// same way whichever it was.
+//
+// A request that comes back with an expired token is sent through the chain
+// again, with a fresh token. Requests to ArcGIS Online, for tokens and device
+// registration, don't pass through middleware.
+//
+//	logged := func(next geotrigger.Handler) geotrigger.Handler {
+//		return func(req *http.Request) (*http.Response, error) {
+//			started := time.Now()
+//			res, err := next(req)
+//			log.Printf("%s took %s", req.URL.Path, time.Since(started))
+//			return res, err
+//		}
+//	}
+//	client.Use(logged)
+type Middleware func(next Handler) Handler
+
+// defaultMiddleware comes first in every chain, identifying this library to
+// the service.
+var defaultMiddleware = ClientHeaders("geotrigger-go", version)
+
+// ClientHeaders is middleware adding a product to the `X-GT-Client-Name` and
+// `X-GT-Client-Version` headers each request carries, which already name
+// this library. Products are space separated, in the order their middleware
+// runs, as in a User-Agent:
+//
+//	client.Use(geotrigger.ClientHeaders("store-locator", "2.1.0"))
+//	// X-GT-Client-Name: geotrigger-go store-locator
+//	// X-GT-Client-Version: 1.0.0 2.1.0
+func ClientHeaders(name string, version string) Middleware {
+	return func(next Handler) Handler {
+		return func(req *http.Request) (*http.Response, error) {
+			// a retried request already has them
+			if !hasProduct(req.Header.Get("X-GT-Client-Name"), name) {
+				appendHeader(req.Header, "X-GT-Client-Name", name)
+				appendHeader(req.Header, "X-GT-Client-Version", version)
+			}
+
+			return next(req)
+		}
+	}
+}
+
+func appendHeader(header http.Header, key string, value string) {
+	if existing := header.Get(key); len(existing) > 0 {
+		value = existing + " " + value
+	}
+
+	header.Set(key, value)
+}
+
+func hasProduct(header string, name string) bool {
+	for _, product := range strings.Fields(header) {
+		if product == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Use adds middleware to the client, to run after any it already has. The
+// client gets its own copy of its environment, so other clients made with the
+// same Environment, or from the same ClientPool, are left as they were. Use
+// isn't safe to call while the client is making requests; add middleware
+// before sharing the client, or pass it in `Environment.Middleware`.
+func (client *Client) Use(middleware ...Middleware) {
+	env := *client.getEnv()
+	env.middleware = append(append([]Middleware(nil), env.middleware...), middleware...)
+	client.setEnv(&env)
+}
+
+// handler chains the environment's middleware around its HTTP client, with
+// identical reads deduplicated closest to the client if asked (see
+// Client.UseDedupe).
+func (env *environment) handler() Handler {
+	handler := env.client().Do
+	if env.flights != nil {
+		handler = env.flights.dedupe(env, handler)
+	}
+	for i := len(env.middleware) - 1; i >= 0; i-- {
+		handler = env.middleware[i](handler)
+	}
+
+	return defaultMiddleware(handler)
+}
+
+// keys of the values the requests made by geotriggerPost carry in their
+// context, for the middleware in this package
+type streamingKey struct{}
+type environmentKey struct{}
+
+// streaming reports whether a request's response is read with Stream, so
+// must be passed along as it arrives rather than buffered.
+func streaming(req *http.Request) bool {
+	streaming, _ := req.Context().Value(streamingKey{}).(bool)
+	return streaming
+}
+
+// limitBody caps how much of a response body can be read, as post does, for
+// middleware that reads a response before post gets it.
+func limitBody(req *http.Request, body io.Reader) io.Reader {
+	env, _ := req.Context().Value(environmentKey{}).(*environment)
+	if env == nil {
+		return body
+	}
+
+	return limitResponse(env, req.URL.Path, body)
+}
@@ -0,0 +1,124 @@
+package geotrigger
+
+import (
+	"context"
+	"fmt"
+	"github.com/Esri/geotrigger-go/geotrigger/test"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestMiddleware(t *testing.T) {
+	var headers http.Header
+	gtServer := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, r *http.Request) {
+		headers = r.Header
+		fmt.Fprintln(res, `{"triggers":[]}`)
+	}))
+	defer gtServer.Close()
+
+	var order []string
+	named := func(name string) Middleware {
+		return func(next Handler) Handler {
+			return func(req *http.Request) (*http.Response, error) {
+				order = append(order, name)
+				req.Header.Set("X-Middleware", name)
+				return next(req)
+			}
+		}
+	}
+
+	env := Environment{GeotriggerURL: gtServer.URL, Middleware: []Middleware{named("env")}}
+	client := ExistingDeviceWithEnvironment(env, "good_client_id", "device_id", "good_access_token", 1800,
+		"good_refresh_token")
+	other := ExistingDeviceWithEnvironment(env, "good_client_id", "device_id", "good_access_token", 1800,
+		"good_refresh_token")
+	client.Use(ClientHeaders("store-locator", "2.1.0"), named("use"))
+	ctx := context.Background()
+
+	_, err := Do[map[string]interface{}](ctx, client, "trigger/list", nil)
+	test.Expect(t, err, nil)
+	test.Expect(t, order, []string{"env", "use"})
+	test.Expect(t, headers.Get("X-Middleware"), "use")
+	test.Expect(t, headers.Get("X-GT-Client-Name"), "geotrigger-go store-locator")
+	test.Expect(t, headers.Get("X-GT-Client-Version"), version+" 2.1.0")
+	test.Expect(t, headers.Get("Authorization"), "Bearer good_access_token")
+
+	// clients sharing an environment don't share what Use adds
+	order = nil
+	_, err = Do[map[string]interface{}](ctx, other, "trigger/list", nil)
+	test.Expect(t, err, nil)
+	test.Expect(t, order, []string{"env"})
+	test.Expect(t, headers.Get("X-GT-Client-Name"), "geotrigger-go")
+	test.Expect(t, headers.Get("X-GT-Client-Version"), version)
+}
+
+func TestMiddlewareMock(t *testing.T) {
+	// nothing listens here; the mock answers every request
+	client := ExistingDeviceWithEnvironment(Environment{GeotriggerURL: "http://127.0.0.1:0"}, "good_client_id",
+		"device_id", "good_access_token", 1800, "good_refresh_token")
+
+	var routes []string
+	client.Use(func(next Handler) Handler {
+		return func(req *http.Request) (*http.Response, error) {
+			routes = append(routes, req.URL.Path)
+			body := `{"triggers":[{"triggerId":"mocked"}]}`
+			if req.URL.Path == "/trigger/delete" {
+				body = `{"error":{"code":403,"message":"Not today."}}`
+			}
+
+			return &http.Response{
+				StatusCode: 200,
+				Header:     http.Header{"Content-Type": {"application/json"}},
+				Body:       ioutil.NopCloser(strings.NewReader(body)),
+				Request:    req,
+			}, nil
+		}
+	})
+
+	type triggerList struct {
+		Triggers []Trigger `json:"triggers"`
+	}
+	response, err := Do[triggerList](context.Background(), client, "trigger/list", nil)
+	test.Expect(t, err, nil)
+	test.Expect(t, response.Triggers[0].TriggerID, "mocked")
+
+	_, err = Do[triggerList](context.Background(), client, "trigger/delete", nil)
+	test.Refute(t, err, nil)
+	test.Expect(t, err.Error(), "Error from /trigger/delete, code: 403. Message: Not today.")
+	test.Expect(t, routes, []string{"/trigger/list", "/trigger/delete"})
+}
+
+func TestMiddlewareSeesRetries(t *testing.T) {
+	agoServer := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(res, `{"access_token":"refreshed_access_token","expires_in":1800}`)
+	}))
+	defer agoServer.Close()
+
+	gtServer := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer refreshed_access_token" {
+			fmt.Fprintln(res, `{"error":{"code":498,"message":"Invalid token."}}`)
+			return
+		}
+		test.Expect(t, r.Header.Get("X-GT-Client-Name"), "geotrigger-go")
+		fmt.Fprintln(res, `{}`)
+	}))
+	defer gtServer.Close()
+
+	client := ExistingDeviceWithEnvironment(Environment{GeotriggerURL: gtServer.URL, AGOURL: agoServer.URL},
+		"good_client_id", "device_id", "old_access_token", 1800, "good_refresh_token")
+
+	var tokens []string
+	client.Use(func(next Handler) Handler {
+		return func(req *http.Request) (*http.Response, error) {
+			tokens = append(tokens, req.Header.Get("Authorization"))
+			return next(req)
+		}
+	})
+
+	err := client.Request("trigger/list", nil, &map[string]interface{}{})
+	test.Expect(t, err, nil)
+	test.Expect(t, tokens, []string{"Bearer old_access_token", "Bearer refreshed_access_token"})
+}
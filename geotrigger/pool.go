@@ -0,0 +1,200 @@
+package geotrigger
+
+import (
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// ClientPool hands out application clients keyed by client ID, for processes
+// that act on behalf of many applications. A client is created (and an access
+// token requested from ArcGIS Online) the first time its client ID is asked
+// for, then reused until it has gone unused for the pool's idle timeout, at
+// which point it is closed. All the clients in a pool share one HTTP client,
+// and so one pool of connections.
+//
+// Clients are meant to be fetched from the pool for the work at hand rather
+// than held on to, as an evicted client is closed.
+type ClientPool struct {
+	env         Environment
+	idleTimeout time.Duration
+	transport   *http.Transport
+	lock        sync.Mutex
+	clients     map[string]*pooledClient
+	closed      bool
+	done        chan struct{}
+}
+
+type pooledClient struct {
+	clientSecret string
+	// closed once client and err are set
+	ready    chan struct{}
+	client   *Client
+	err      error
+	lastUsed time.Time
+}
+
+// NewClientPool creates an empty pool of clients talking to the services
+// described by `env`. If `env.HTTPClient` is nil, the pool makes an HTTP client
+// of its own for its clients to share. Clients unused for `idleTimeout` are
+// closed and dropped from the pool; zero keeps them until the pool is closed.
+func NewClientPool(env Environment, idleTimeout time.Duration) *ClientPool {
+	pool := &ClientPool{
+		env:         env,
+		idleTimeout: idleTimeout,
+		clients:     make(map[string]*pooledClient),
+		done:        make(chan struct{}),
+	}
+
+	if pool.env.HTTPClient == nil {
+		// every client talks to the same two hosts, so keep more connections to them
+		pool.transport = http.DefaultTransport.(*http.Transport).Clone()
+		pool.transport.MaxIdleConnsPerHost = 64
+		pool.env.HTTPClient = &http.Client{Transport: pool.transport}
+	}
+
+	if idleTimeout > 0 {
+		go pool.evictIdle()
+	}
+
+	return pool
+}
+
+// Get returns the pooled client for an application, creating it first if
+// need be. Concurrent calls for a client ID that isn't pooled yet wait on a
+// single token request. A client secret that doesn't match the one the
+// pooled client was created with is an error; use Remove to replace a client
+// whose secret has changed.
+func (pool *ClientPool) Get(clientID string, clientSecret string) (*Client, error) {
+	pool.lock.Lock()
+	if pool.closed {
+		pool.lock.Unlock()
+		return nil, errors.New("Client pool has been closed.")
+	}
+
+	pooled, ok := pool.clients[clientID]
+	if !ok {
+		pooled = &pooledClient{clientSecret: clientSecret, ready: make(chan struct{})}
+		pool.clients[clientID] = pooled
+		go pool.create(clientID, pooled)
+	}
+
+	if subtle.ConstantTimeCompare([]byte(pooled.clientSecret), []byte(clientSecret)) != 1 {
+		pool.lock.Unlock()
+		return nil, fmt.Errorf("Client secret does not match the pooled client for %s.", clientID)
+	}
+
+	pooled.lastUsed = time.Now()
+	pool.lock.Unlock()
+
+	<-pooled.ready
+	return pooled.client, pooled.err
+}
+
+// Remove drops the pooled client for an application, if any, and closes it
+// before returning, waiting first for it to be created if a Get is still
+// creating it.
+func (pool *ClientPool) Remove(clientID string) {
+	pool.lock.Lock()
+	pooled, ok := pool.clients[clientID]
+	delete(pool.clients, clientID)
+	pool.lock.Unlock()
+
+	if ok {
+		pooled.close()
+	}
+}
+
+// Len returns the number of clients in the pool.
+func (pool *ClientPool) Len() int {
+	pool.lock.Lock()
+	defer pool.lock.Unlock()
+
+	return len(pool.clients)
+}
+
+// Close closes every client in the pool, and the pool itself.
+func (pool *ClientPool) Close() error {
+	pool.lock.Lock()
+	if pool.closed {
+		pool.lock.Unlock()
+		return nil
+	}
+	pool.closed = true
+	close(pool.done)
+	clients := pool.clients
+	pool.clients = make(map[string]*pooledClient)
+	pool.lock.Unlock()
+
+	for _, pooled := range clients {
+		pooled.close()
+	}
+
+	if pool.transport != nil {
+		pool.transport.CloseIdleConnections()
+	}
+
+	return nil
+}
+
+func (pool *ClientPool) create(clientID string, pooled *pooledClient) {
+	session, err := newApplication(pool.env.internal(), clientID, pooled.clientSecret)
+	if err == nil {
+		pooled.client = &Client{session}
+	} else {
+		pooled.err = err
+
+		// don't keep failures around, so the next Get tries again
+		pool.lock.Lock()
+		if pool.clients[clientID] == pooled {
+			delete(pool.clients, clientID)
+		}
+		pool.lock.Unlock()
+	}
+
+	close(pooled.ready)
+}
+
+// evictIdle runs in its own routine until the pool is closed, dropping
+// clients that haven't been asked for within the idle timeout.
+func (pool *ClientPool) evictIdle() {
+	ticker := time.NewTicker(pool.idleTimeout / 2)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-pool.done:
+			return
+		case now := <-ticker.C:
+			pool.evict(now)
+		}
+	}
+}
+
+func (pool *ClientPool) evict(now time.Time) {
+	var idle []*pooledClient
+
+	pool.lock.Lock()
+	for clientID, pooled := range pool.clients {
+		if now.Sub(pooled.lastUsed) >= pool.idleTimeout {
+			idle = append(idle, pooled)
+			delete(pool.clients, clientID)
+		}
+	}
+	pool.lock.Unlock()
+
+	for _, pooled := range idle {
+		pooled.close()
+	}
+}
+
+// close waits for the client to be created, then closes it.
+func (pooled *pooledClient) close() {
+	<-pooled.ready
+	if pooled.client != nil {
+		pooled.client.Close()
+	}
+}
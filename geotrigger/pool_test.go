@@ -0,0 +1,138 @@
+package geotrigger
+
+import (
+	"fmt"
+	"github.com/Esri/geotrigger-go/geotrigger/test"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func poolTestServer(t *testing.T, tokenRequests *int32) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/sharing/oauth2/token" {
+			fmt.Fprintln(res, `{}`)
+			return
+		}
+
+		atomic.AddInt32(tokenRequests, 1)
+		contents, _ := ioutil.ReadAll(r.Body)
+		vals, _ := url.ParseQuery(string(contents))
+		if vals.Get("client_secret") != "good_client_secret" {
+			fmt.Fprintln(res, `{"error":{"code":400,"message":"Invalid client_id"}}`)
+			return
+		}
+		fmt.Fprintln(res, `{"access_token":"good_access_token","expires_in":7200}`)
+	}))
+}
+
+func TestClientPool(t *testing.T) {
+	var tokenRequests int32
+	server := poolTestServer(t, &tokenRequests)
+	defer server.Close()
+
+	pool := NewClientPool(Environment{GeotriggerURL: server.URL, AGOURL: server.URL}, 0)
+	defer pool.Close()
+
+	// concurrent gets share one token request and one client
+	clients := make([]*Client, 10)
+	var wg sync.WaitGroup
+	for i := range clients {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			client, err := pool.Get("good_client_id", "good_client_secret")
+			test.Expect(t, err, nil)
+			clients[i] = client
+		}(i)
+	}
+	wg.Wait()
+
+	test.Expect(t, atomic.LoadInt32(&tokenRequests), int32(1))
+	test.Expect(t, pool.Len(), 1)
+	for _, client := range clients {
+		test.Expect(t, client == clients[0], true)
+	}
+
+	var response map[string]interface{}
+	err := clients[0].Request("trigger/list", nil, &response)
+	test.Expect(t, err, nil)
+
+	// the clients share the pool's HTTP client
+	other, err := pool.Get("other_client_id", "good_client_secret")
+	test.Expect(t, err, nil)
+	test.Expect(t, other.session.(*application).env.httpClient == pool.env.HTTPClient, true)
+	test.Expect(t, clients[0].session.(*application).env.httpClient == pool.env.HTTPClient, true)
+
+	_, err = pool.Get("good_client_id", "wrong_client_secret")
+	test.Expect(t, err.Error(), "Client secret does not match the pooled client for good_client_id.")
+
+	// failures aren't pooled
+	_, err = pool.Get("bad_client_id", "bad_client_secret")
+	test.Expect(t, err.Error(), "Error from /sharing/oauth2/token, code: 400. Message: Invalid client_id")
+	test.Expect(t, pool.Len(), 2)
+
+	// removed clients are closed
+	pool.Remove("good_client_id")
+	test.Expect(t, pool.Len(), 1)
+	replacement, err := pool.Get("good_client_id", "good_client_secret")
+	test.Expect(t, err, nil)
+	test.Refute(t, replacement == clients[0], true)
+
+	err = clients[0].Request("trigger/list", nil, &response)
+	test.Expect(t, err.Error(), "Error before hitting route: trigger/list. Client has been closed.")
+
+	pool.Close()
+	err = replacement.Request("trigger/list", nil, &response)
+	test.Expect(t, err.Error(), "Error before hitting route: trigger/list. Client has been closed.")
+	_, err = pool.Get("good_client_id", "good_client_secret")
+	test.Expect(t, err.Error(), "Client pool has been closed.")
+}
+
+func TestClientPoolEviction(t *testing.T) {
+	var tokenRequests int32
+	server := poolTestServer(t, &tokenRequests)
+	defer server.Close()
+
+	pool := NewClientPool(Environment{GeotriggerURL: server.URL, AGOURL: server.URL}, time.Minute)
+	defer pool.Close()
+
+	stale, err := pool.Get("stale_client_id", "good_client_secret")
+	test.Expect(t, err, nil)
+	pool.lock.Lock()
+	pool.clients["stale_client_id"].lastUsed = time.Now().Add(-2 * time.Minute)
+	pool.lock.Unlock()
+
+	fresh, err := pool.Get("fresh_client_id", "good_client_secret")
+	test.Expect(t, err, nil)
+
+	pool.evict(time.Now())
+	test.Expect(t, pool.Len(), 1)
+
+	var response map[string]interface{}
+	err = stale.Request("trigger/list", nil, &response)
+	test.Expect(t, strings.HasSuffix(err.Error(), "Client has been closed."), true)
+	err = fresh.Request("trigger/list", nil, &response)
+	test.Expect(t, err, nil)
+
+	// an evicted client is created again when next asked for
+	_, err = pool.Get("stale_client_id", "good_client_secret")
+	test.Expect(t, err, nil)
+	test.Expect(t, atomic.LoadInt32(&tokenRequests), int32(3))
+}
+
+func TestClientClose(t *testing.T) {
+	client := ExistingDevice("good_client_id", "device_id", "good_access_token", 1800, "good_refresh_token")
+	test.Expect(t, client.Close(), nil)
+	test.Expect(t, client.Close(), nil)
+
+	var response map[string]interface{}
+	err := client.Request("trigger/list", nil, &response)
+	test.Expect(t, err.Error(), "Error before hitting route: trigger/list. Client has been closed.")
+}
@@ -0,0 +1,129 @@
+package geotrigger
+
+import (
+	"net/url"
+)
+
+// Push platforms a device can register a token for, with
+// `DeviceUpdateParams.RegisterPushToken`.
+const (
+	PushAPNS        = "apns"
+	PushAPNSSandbox = "apns-sandbox"
+	PushGCM         = "gcm"
+)
+
+// NewNotification starts a push notification showing `text`, to be finished
+// with the With methods and put in a trigger's action:
+//
+//	trigger.Action = geotrigger.NewNotification("Welcome downtown.").
+//		WithURL("https://example.com/downtown").
+//		WithSound("chime.caf").
+//		WithData("store", "downtown").
+//		Action()
+func NewNotification(text string) *Notification {
+	return &Notification{Text: text}
+}
+
+// WithURL sets the URL opened when the notification is tapped.
+func (notification *Notification) WithURL(url string) *Notification {
+	notification.URL = url
+	return notification
+}
+
+// WithSound sets the sound played when the notification arrives.
+func (notification *Notification) WithSound(sound string) *Notification {
+	notification.Sound = sound
+	return notification
+}
+
+// WithIcon sets the icon shown with the notification, on platforms that show
+// one.
+func (notification *Notification) WithIcon(icon string) *Notification {
+	notification.Icon = icon
+	return notification
+}
+
+// WithData adds a custom value to the notification's payload, for the app
+// receiving it.
+func (notification *Notification) WithData(key string, value interface{}) *Notification {
+	if notification.Data == nil {
+		notification.Data = make(map[string]interface{})
+	}
+
+	notification.Data[key] = value
+	return notification
+}
+
+// Action returns an action sending this notification.
+func (notification *Notification) Action() Action {
+	return Action{Notification: notification}
+}
+
+func (notification *Notification) validate(v *validation, field string) {
+	if len(notification.Text) == 0 && len(notification.Data) == 0 {
+		v.add(field, "A notification needs text or data.")
+	}
+
+	if len(notification.URL) > 0 {
+		if parsed, err := url.Parse(notification.URL); err != nil || len(parsed.Scheme) == 0 {
+			v.add(field+".url", "Must be an absolute URL.")
+		}
+	}
+}
+
+// DeviceUpdateParams are the params for `device/update`. An application
+// selects the devices to update by ID or tag; a device always updates itself,
+// and needs neither. Fields left empty are left as they are.
+type DeviceUpdateParams struct {
+	DeviceIDs       []string               `json:"deviceIds,omitempty"`
+	Tags            []string               `json:"tags,omitempty"`
+	AddTags         []string               `json:"addTags,omitempty"`
+	RemoveTags      []string               `json:"removeTags,omitempty"`
+	SetTags         []string               `json:"setTags,omitempty"`
+	Properties      map[string]interface{} `json:"properties,omitempty"`
+	TrackingProfile string                 `json:"trackingProfile,omitempty"`
+	// push tokens, set with RegisterPushToken
+	APNSProdToken     string `json:"apnsProdToken,omitempty"`
+	APNSSandboxToken  string `json:"apnsSandboxToken,omitempty"`
+	GCMRegistrationID string `json:"gcmRegistrationId,omitempty"`
+
+	// a platform RegisterPushToken didn't know, reported by Validate
+	unknownPlatform string
+}
+
+// RegisterPushToken sets the token the device receives push notifications
+// with on a platform: PushAPNS, PushAPNSSandbox or PushGCM. An unknown
+// platform fails validation when the params are sent.
+func (params *DeviceUpdateParams) RegisterPushToken(platform string, token string) *DeviceUpdateParams {
+	switch platform {
+	case PushAPNS:
+		params.APNSProdToken = token
+	case PushAPNSSandbox:
+		params.APNSSandboxToken = token
+	case PushGCM:
+		params.GCMRegistrationID = token
+	default:
+		params.unknownPlatform = platform
+	}
+
+	return params
+}
+
+// Validate checks that tags aren't empty, that the tracking profile is one
+// the service knows, and that any push token was registered for a known
+// platform.
+func (params *DeviceUpdateParams) Validate() error {
+	var v validation
+	validateTags(&v, "tags", params.Tags)
+	validateTags(&v, "addTags", params.AddTags)
+	validateTags(&v, "removeTags", params.RemoveTags)
+	validateTags(&v, "setTags", params.SetTags)
+	validateTrackingProfile(&v, "trackingProfile", params.TrackingProfile)
+
+	if len(params.unknownPlatform) > 0 {
+		v.add("", "Unknown push platform: %s. Expected %s, %s or %s.",
+			params.unknownPlatform, PushAPNS, PushAPNSSandbox, PushGCM)
+	}
+
+	return v.result("params for device/update")
+}
@@ -0,0 +1,87 @@
+package geotrigger
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/Esri/geotrigger-go/geotrigger/test"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestNotificationBuilder(t *testing.T) {
+	action := NewNotification("Welcome downtown.").
+		WithURL("https://example.com/downtown").
+		WithSound("chime.caf").
+		WithIcon("pin").
+		WithData("store", "downtown").
+		WithData("visits", 3).
+		Action()
+
+	raw, err := json.Marshal(action)
+	test.Expect(t, err, nil)
+	test.Expect(t, string(raw), `{"notification":{"text":"Welcome downtown.","url":"https://example.com/downtown",`+
+		`"sound":"chime.caf","icon":"pin","data":{"store":"downtown","visits":3}}}`)
+
+	trigger := &Trigger{
+		Condition: Condition{Direction: DirectionEnter, Geo: Geo{Geocode: "Portland, OR"}},
+		Action:    action,
+	}
+	test.Expect(t, trigger.Validate(), nil)
+
+	trigger.Action = NewNotification("").Action()
+	err = trigger.Validate()
+	test.Refute(t, err, nil)
+	test.Expect(t, err.Error(), "Invalid trigger. action.notification: A notification needs text or data.")
+
+	// data alone is enough for a silent notification
+	trigger.Action = NewNotification("").WithData("refresh", true).Action()
+	test.Expect(t, trigger.Validate(), nil)
+
+	trigger.Action = NewNotification("hi").WithURL("downtown").Action()
+	err = trigger.Validate()
+	test.Refute(t, err, nil)
+	test.Expect(t, err.Error(), "Invalid trigger. action.notification.url: Must be an absolute URL.")
+}
+
+func TestDeviceUpdateParams(t *testing.T) {
+	var sent map[string]interface{}
+	requests := 0
+	gtServer := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, r *http.Request) {
+		requests++
+		test.Expect(t, r.URL.Path, "/device/update")
+		body, _ := ioutil.ReadAll(r.Body)
+		sent = nil
+		test.Expect(t, json.Unmarshal(body, &sent), nil)
+		res.Write([]byte(`{"devices":[]}`))
+	}))
+	defer gtServer.Close()
+
+	client := ExistingDeviceWithEnvironment(Environment{GeotriggerURL: gtServer.URL}, "good_client_id",
+		"device_id", "good_access_token", 1800, "good_refresh_token")
+	ctx := context.Background()
+
+	params := (&DeviceUpdateParams{AddTags: []string{"regulars"}}).
+		RegisterPushToken(PushAPNSSandbox, "apns_token").
+		RegisterPushToken(PushGCM, "gcm_id")
+	_, err := Do[map[string]interface{}](ctx, client, "device/update", params)
+	test.Expect(t, err, nil)
+	test.Expect(t, sent, map[string]interface{}{
+		"addTags":           []interface{}{"regulars"},
+		"apnsSandboxToken":  "apns_token",
+		"gcmRegistrationId": "gcm_id",
+	})
+
+	params = (&DeviceUpdateParams{}).RegisterPushToken(PushAPNS, "apns_token")
+	_, err = Do[map[string]interface{}](ctx, client, "device/update", params)
+	test.Expect(t, err, nil)
+	test.Expect(t, sent, map[string]interface{}{"apnsProdToken": "apns_token"})
+
+	params = (&DeviceUpdateParams{SetTags: []string{""}}).RegisterPushToken("wns", "token")
+	_, err = Do[map[string]interface{}](ctx, client, "device/update", params)
+	test.Refute(t, err, nil)
+	test.Expect(t, err.Error(), "Invalid params for device/update. setTags[0]: Tags can't be empty. "+
+		"Unknown push platform: wns. Expected apns, apns-sandbox or gcm.")
+	test.Expect(t, requests, 2)
+}
@@ -0,0 +1,91 @@
+package reconcile
+
+import (
+	"context"
+	"fmt"
+	"github.com/Esri/geotrigger-go/geotrigger"
+)
+
+// Apply makes the planned changes: tag permissions first, then trigger
+// creates, updates and deletes, in that order. It stops at the first change
+// that fails, and changes already made stay made; planning again picks up
+// where it left off.
+func (plan *Plan) Apply(ctx context.Context, client *geotrigger.Client) error {
+	for _, change := range plan.TagPermissions {
+		params := map[string]interface{}{"tags": change.Tag}
+		for name, granted := range change.Desired {
+			params[name] = granted
+		}
+
+		if _, err := geotrigger.Do[tagPermissionsResponse](ctx, client, "tag/permissions/update", params); err != nil {
+			return fmt.Errorf("Could not update permissions for tag %s. %s", change.Tag, err)
+		}
+	}
+
+	var deletes []string
+	for _, change := range plan.Triggers {
+		switch change.Kind {
+		case ChangeCreate:
+			params := change.Desired.CreateParams()
+			if _, err := geotrigger.Do[geotrigger.Trigger](ctx, client, "trigger/create", params); err != nil {
+				return fmt.Errorf("Could not create trigger %s. %s", change.Key, err)
+			}
+		case ChangeUpdate:
+			if _, err := geotrigger.Do[triggerListResponse](ctx, client, "trigger/update", updateParams(&change)); err != nil {
+				return fmt.Errorf("Could not update trigger %s. %s", change.Key, err)
+			}
+		case ChangeDelete:
+			deletes = append(deletes, change.TriggerID)
+		}
+	}
+
+	if len(deletes) > 0 {
+		params := map[string]interface{}{"triggerIds": deletes}
+		if _, err := geotrigger.Do[triggerListResponse](ctx, client, "trigger/delete", params); err != nil {
+			return fmt.Errorf("Could not delete triggers. %s", err)
+		}
+	}
+
+	return nil
+}
+
+// Reconcile plans the changes that bring the application in line with the
+// config, and applies them. The plan is returned even if applying it fails,
+// to show what was attempted.
+func Reconcile(ctx context.Context, client *geotrigger.Client, config *Config) (*Plan, error) {
+	plan, err := NewPlan(ctx, client, config)
+	if err != nil {
+		return nil, err
+	}
+
+	return plan, plan.Apply(ctx, client)
+}
+
+// updateParams are the `trigger/update` params setting the changed fields of
+// a trigger.
+func updateParams(change *Change) map[string]interface{} {
+	desired := change.Desired
+	params := map[string]interface{}{"triggerIds": change.TriggerID}
+	for _, field := range change.Fields {
+		switch field {
+		case "condition":
+			params["condition"] = desired.Condition
+		case "action":
+			params["action"] = desired.Action
+		case "tags":
+			params["setTags"] = append([]string{}, desired.Tags...)
+		case "properties":
+			properties := desired.Properties
+			if properties == nil {
+				properties = map[string]interface{}{}
+			}
+			params["properties"] = properties
+		case "times":
+			params["times"] = desired.Times
+		case "rateLimit":
+			params["rateLimit"] = desired.RateLimit
+		}
+	}
+
+	return params
+}
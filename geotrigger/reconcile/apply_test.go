@@ -0,0 +1,73 @@
+package reconcile
+
+import (
+	"context"
+	"github.com/Esri/geotrigger-go/geotrigger"
+	"github.com/Esri/geotrigger-go/geotrigger/test"
+	"strings"
+	"testing"
+)
+
+func TestApply(t *testing.T) {
+	server, client := newTestClient(t)
+	defer server.Close()
+
+	downtown := circle(geotrigger.DirectionEnter, "Hello downtown.")
+	downtown.TriggerID = "t1"
+	downtown.Tags = []string{"portland", "old"}
+	downtown.Properties = map[string]interface{}{"deployKey": "downtown"}
+	server.AddTrigger(downtown)
+
+	stale := circle(geotrigger.DirectionEnter, "Old.")
+	stale.TriggerID = "t2"
+	stale.Properties = map[string]interface{}{"deployKey": "stale"}
+	server.AddTrigger(stale)
+
+	other := circle(geotrigger.DirectionEnter, "Someone else's.")
+	other.TriggerID = "t3"
+	server.AddTrigger(other)
+
+	config, err := ReadConfig(strings.NewReader(markerConfigData))
+	test.Expect(t, err, nil)
+
+	plan, err := Reconcile(context.Background(), client, config)
+	test.Expect(t, err, nil)
+	test.Expect(t, len(plan.Triggers), 3)
+
+	triggers := server.Triggers()
+	test.Expect(t, len(triggers), 3)
+	test.Expect(t, triggers[0].TriggerID, "t1")
+	test.Expect(t, triggers[0].Action.Message, "Welcome downtown.")
+	test.Expect(t, triggers[0].Tags, []string{"portland"})
+	test.Expect(t, triggers[1].TriggerID, "t3")
+	test.Expect(t, triggers[2].Properties["deployKey"], "downtown-leave")
+	test.Expect(t, triggers[2].Tags, []string{"portland", "regulars"})
+	test.Expect(t, triggers[2].RateLimit, 3600)
+
+	// a second run has nothing left to do
+	plan, err = NewPlan(context.Background(), client, config)
+	test.Expect(t, err, nil)
+	test.Expect(t, plan.Empty(), true)
+}
+
+func TestApplyStopsAtFirstError(t *testing.T) {
+	server, client := newTestClient(t)
+	defer server.Close()
+
+	taken := circle(geotrigger.DirectionEnter, "Hi.")
+	taken.TriggerID = "taken"
+	server.AddTrigger(taken)
+
+	// creating a trigger with an ID that's in use fails
+	create := circle(geotrigger.DirectionEnter, "Hi.")
+	create.TriggerID = "taken"
+	plan := &Plan{Triggers: []Change{
+		{Kind: ChangeCreate, Key: "taken", Desired: &create},
+		{Kind: ChangeDelete, Key: "taken", TriggerID: "taken"},
+	}}
+
+	err := plan.Apply(context.Background(), client)
+	test.Refute(t, err, nil)
+	test.Expect(t, strings.HasPrefix(err.Error(), "Could not create trigger taken."), true)
+	test.Expect(t, len(server.Triggers()), 1)
+}
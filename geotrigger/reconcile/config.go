@@ -0,0 +1,168 @@
+// Package `reconcile` brings an application's triggers and tag permissions in
+// line with a config file describing how they should be. NewPlan compares the
+// config with what the Geotrigger Service has, Plan.Diff shows what would
+// change, and Plan.Apply makes the changes:
+//
+//	config, err := reconcile.LoadConfig("triggers.json")
+//	...
+//	plan, err := reconcile.NewPlan(ctx, client, config)
+//	...
+//	fmt.Print(plan.Diff())
+//	err = plan.Apply(ctx, client)
+//
+// A config is JSON, with the triggers in the same form `trigger/list`
+// returns them:
+//
+//	{
+//	  "marker": "deployKey",
+//	  "triggers": [
+//	    {
+//	      "condition": {"direction": "enter", "geo": {"latitude": 45.5165, "longitude": -122.6764, "distance": 100}},
+//	      "action": {"message": "Welcome downtown."},
+//	      "tags": ["portland"],
+//	      "properties": {"deployKey": "downtown-welcome"}
+//	    }
+//	  ],
+//	  "tagPermissions": {"portland": {"triggerList": true}}
+//	}
+//
+// Without a marker, the config describes every trigger of the application:
+// triggers are matched by trigger ID, so every trigger in the config needs
+// one, and triggers that aren't in the config are deleted. With a marker,
+// only triggers with that property are managed, matched by its value, and
+// any other trigger is left alone. Tag permissions are never removed, and
+// only the permissions listed for a tag are changed.
+package reconcile
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/Esri/geotrigger-go/geotrigger"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// permissionNames are the permissions a tag can grant the devices that have it.
+var permissionNames = []string{
+	"deviceTagging",
+	"deviceLocation",
+	"deviceToggleTracking",
+	"devicePermissions",
+	"deviceList",
+	"triggerApply",
+	"triggerDelete",
+	"triggerHistory",
+	"triggerList",
+	"triggerUpdate",
+}
+
+// Config is how an application's triggers and tag permissions should be.
+type Config struct {
+	// name of the property identifying managed triggers. If empty, every
+	// trigger is managed, matched by trigger ID.
+	Marker   string               `json:"marker,omitempty"`
+	Triggers []geotrigger.Trigger `json:"triggers"`
+	// permissions by tag name, such as `{"triggerList": true}`
+	TagPermissions map[string]Permissions `json:"tagPermissions,omitempty"`
+}
+
+// Permissions are the permissions a tag grants, by name, such as
+// `triggerList` or `deviceTagging`.
+type Permissions map[string]bool
+
+// LoadConfig reads a config from a JSON file.
+func LoadConfig(path string) (*Config, error) {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		// a YAML parser would be this module's first outside dependency
+		return nil, fmt.Errorf("YAML configs aren't supported, convert %s to JSON.", path)
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("Could not open config file. %s", err)
+	}
+	defer file.Close()
+
+	return ReadConfig(file)
+}
+
+// ReadConfig reads a config from JSON, and validates it. Unknown fields are
+// an error, to catch typos before they turn into changes.
+func ReadConfig(r io.Reader) (*Config, error) {
+	decoder := json.NewDecoder(r)
+	decoder.DisallowUnknownFields()
+
+	var config Config
+	if err := decoder.Decode(&config); err != nil {
+		return nil, fmt.Errorf("Could not parse config. %s", err)
+	}
+
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+
+	return &config, nil
+}
+
+// Validate checks that every trigger has a unique key, as described in the
+// package docs, and passes `Trigger.Validate`, and that every permission is
+// one the service knows.
+func (config *Config) Validate() error {
+	keys := make(map[string]bool)
+	for i, trigger := range config.Triggers {
+		key := config.key(&trigger)
+		if len(key) == 0 {
+			if len(config.Marker) > 0 {
+				return fmt.Errorf("Trigger %d has no %s property.", i+1, config.Marker)
+			}
+			return fmt.Errorf("Trigger %d has no triggerId.", i+1)
+		}
+		if keys[key] {
+			return fmt.Errorf("Trigger %s is in the config more than once.", key)
+		}
+		keys[key] = true
+
+		if err := trigger.Validate(); err != nil {
+			return fmt.Errorf("Trigger %s: %s", key, err)
+		}
+	}
+
+	for tag, permissions := range config.TagPermissions {
+		if len(tag) == 0 {
+			return errors.New("Tag permissions need a tag name.")
+		}
+		for name := range permissions {
+			if !knownPermission(name) {
+				return fmt.Errorf("Unknown permission for tag %s: %s.", tag, name)
+			}
+		}
+	}
+
+	return nil
+}
+
+// key returns what a trigger is matched by: the value of the marker
+// property, or the trigger ID without a marker. Triggers with no key aren't
+// managed.
+func (config *Config) key(trigger *geotrigger.Trigger) string {
+	if len(config.Marker) == 0 {
+		return trigger.TriggerID
+	}
+
+	key, _ := trigger.Properties[config.Marker].(string)
+	return key
+}
+
+func knownPermission(name string) bool {
+	for _, known := range permissionNames {
+		if name == known {
+			return true
+		}
+	}
+
+	return false
+}
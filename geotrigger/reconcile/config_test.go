@@ -0,0 +1,85 @@
+package reconcile
+
+import (
+	"github.com/Esri/geotrigger-go/geotrigger/test"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+/* editing these will break tests */
+var markerConfigData = `{
+  "marker": "deployKey",
+  "triggers": [
+    {
+      "condition": {"direction": "enter", "geo": {"latitude": 45.5165, "longitude": -122.6764, "distance": 100}},
+      "action": {"message": "Welcome downtown."},
+      "tags": ["portland"],
+      "properties": {"deployKey": "downtown"}
+    },
+    {
+      "condition": {"direction": "leave", "geo": {"latitude": 45.5165, "longitude": -122.6764, "distance": 100}},
+      "action": {"message": "Come back soon."},
+      "tags": ["portland", "regulars"],
+      "properties": {"deployKey": "downtown-leave"},
+      "rateLimit": 3600
+    }
+  ],
+  "tagPermissions": {"portland": {"triggerList": true, "deviceTagging": false}}
+}`
+
+func TestReadConfig(t *testing.T) {
+	config, err := ReadConfig(strings.NewReader(markerConfigData))
+	test.Expect(t, err, nil)
+	test.Expect(t, config.Marker, "deployKey")
+	test.Expect(t, len(config.Triggers), 2)
+	test.Expect(t, config.key(&config.Triggers[1]), "downtown-leave")
+	test.Expect(t, config.Triggers[1].RateLimit, 3600)
+	test.Expect(t, config.TagPermissions["portland"]["triggerList"], true)
+
+	_, err = ReadConfig(strings.NewReader(`{"triggers": [], "tagPermision": {}}`))
+	test.Refute(t, err, nil)
+	test.Expect(t, strings.HasPrefix(err.Error(), "Could not parse config."), true)
+}
+
+func validTrigger(id string) string {
+	return `{"triggerId": "` + id + `", "condition": {"direction": "enter", "geo": {"geocode": "Portland"}}, "action": {"message": "hi"}}`
+}
+
+func TestValidate(t *testing.T) {
+	cases := map[string]string{
+		`{"triggers": [{"condition": {"direction": "enter"}}]}`:                                                                             "Trigger 1 has no triggerId.",
+		`{"marker": "key", "triggers": [{"triggerId": "a", "condition": {"direction": "enter"}}]}`:                                          "Trigger 1 has no key property.",
+		`{"triggers": [` + validTrigger("a") + `, ` + validTrigger("a") + `]}`:                                                              "Trigger a is in the config more than once.",
+		`{"triggers": [{"triggerId": "a", "condition": {"direction": "in", "geo": {"geocode": "Portland"}}, "action": {"message": "hi"}}]}`: "Trigger a: Invalid trigger. condition.direction: Must be enter or leave, not in.",
+		`{"triggers": [], "tagPermissions": {"portland": {"triggerList": true, "flying": true}}}`:                                           "Unknown permission for tag portland: flying.",
+	}
+
+	for data, message := range cases {
+		_, err := ReadConfig(strings.NewReader(data))
+		test.Refute(t, err, nil)
+		test.Expect(t, err.Error(), message)
+	}
+}
+
+func TestLoadConfig(t *testing.T) {
+	dir, err := ioutil.TempDir("", "reconcile")
+	test.Expect(t, err, nil)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "triggers.json")
+	test.Expect(t, ioutil.WriteFile(path, []byte(markerConfigData), 0600), nil)
+	config, err := LoadConfig(path)
+	test.Expect(t, err, nil)
+	test.Expect(t, len(config.Triggers), 2)
+
+	_, err = LoadConfig(filepath.Join(dir, "triggers.yaml"))
+	test.Refute(t, err, nil)
+	test.Expect(t, strings.HasPrefix(err.Error(), "YAML configs aren't supported"), true)
+
+	_, err = LoadConfig(filepath.Join(dir, "missing.json"))
+	test.Refute(t, err, nil)
+	test.Expect(t, strings.HasPrefix(err.Error(), "Could not open config file."), true)
+}
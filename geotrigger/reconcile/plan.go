@@ -0,0 +1,302 @@
+package reconcile
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/Esri/geotrigger-go/geotrigger"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// Kinds of trigger changes, used for `Change.Kind`.
+const (
+	ChangeCreate = "create"
+	ChangeUpdate = "update"
+	ChangeDelete = "delete"
+)
+
+// Change is a trigger to create, update or delete.
+type Change struct {
+	Kind string `json:"kind"`
+	// the trigger's key, as described in the package docs
+	Key string `json:"key"`
+	// the ID of the trigger to update or delete
+	TriggerID string `json:"triggerId,omitempty"`
+	// for updates, the fields that differ: condition, action, tags,
+	// properties, times or rateLimit
+	Fields []string `json:"fields,omitempty"`
+	// the trigger as it is, for updates and deletes
+	Current *geotrigger.Trigger `json:"current,omitempty"`
+	// the trigger as it should be, for creates and updates
+	Desired *geotrigger.Trigger `json:"desired,omitempty"`
+}
+
+// PermissionChange is a set of permissions to change for a tag. Current
+// and Desired only hold the permissions that differ.
+type PermissionChange struct {
+	Tag     string      `json:"tag"`
+	Current Permissions `json:"current"`
+	Desired Permissions `json:"desired"`
+}
+
+// Plan is the changes that bring an application in line with a config.
+type Plan struct {
+	Triggers       []Change           `json:"triggers"`
+	TagPermissions []PermissionChange `json:"tagPermissions"`
+}
+
+type triggerListResponse struct {
+	Triggers []geotrigger.Trigger `json:"triggers"`
+}
+
+type tagPermissionsResponse struct {
+	Tags []map[string]interface{} `json:"tags"`
+}
+
+// NewPlan lists the application's triggers, and the permissions of the tags
+// in the config, and works out what needs to change to match the config. The
+// client must be an application client.
+func NewPlan(ctx context.Context, client *geotrigger.Client, config *Config) (*Plan, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+
+	list, err := geotrigger.Do[triggerListResponse](ctx, client, "trigger/list", map[string]interface{}{})
+	if err != nil {
+		return nil, fmt.Errorf("Could not list triggers. %s", err)
+	}
+
+	plan := &Plan{Triggers: []Change{}, TagPermissions: []PermissionChange{}}
+	if err := plan.addTriggers(config, list.Triggers); err != nil {
+		return nil, err
+	}
+
+	if len(config.TagPermissions) > 0 {
+		tags := make([]string, 0, len(config.TagPermissions))
+		for tag := range config.TagPermissions {
+			tags = append(tags, tag)
+		}
+		sort.Strings(tags)
+
+		current, err := geotrigger.Do[tagPermissionsResponse](ctx, client, "tag/permissions",
+			map[string]interface{}{"tags": tags})
+		if err != nil {
+			return nil, fmt.Errorf("Could not list tag permissions. %s", err)
+		}
+		plan.addPermissions(config, tags, current.Tags)
+	}
+
+	return plan, nil
+}
+
+// addTriggers plans creates and updates in config order, then deletes in key
+// order.
+func (plan *Plan) addTriggers(config *Config, triggers []geotrigger.Trigger) error {
+	current := make(map[string]*geotrigger.Trigger)
+	for i := range triggers {
+		trigger := &triggers[i]
+		key := config.key(trigger)
+		if len(key) == 0 {
+			continue
+		}
+		if existing, ok := current[key]; ok {
+			return fmt.Errorf("Triggers %s and %s have the same %s: %s.", existing.TriggerID,
+				trigger.TriggerID, config.Marker, key)
+		}
+		current[key] = trigger
+	}
+
+	for i := range config.Triggers {
+		desired := &config.Triggers[i]
+		key := config.key(desired)
+
+		existing, ok := current[key]
+		if !ok {
+			plan.Triggers = append(plan.Triggers, Change{Kind: ChangeCreate, Key: key, Desired: desired})
+			continue
+		}
+		delete(current, key)
+
+		if fields := changedFields(existing, desired); len(fields) > 0 {
+			plan.Triggers = append(plan.Triggers, Change{
+				Kind:      ChangeUpdate,
+				Key:       key,
+				TriggerID: existing.TriggerID,
+				Fields:    fields,
+				Current:   existing,
+				Desired:   desired,
+			})
+		}
+	}
+
+	keys := make([]string, 0, len(current))
+	for key := range current {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		plan.Triggers = append(plan.Triggers, Change{
+			Kind:      ChangeDelete,
+			Key:       key,
+			TriggerID: current[key].TriggerID,
+			Current:   current[key],
+		})
+	}
+
+	return nil
+}
+
+func (plan *Plan) addPermissions(config *Config, tags []string, current []map[string]interface{}) {
+	byTag := make(map[string]map[string]interface{})
+	for _, tag := range current {
+		if name, ok := tag["name"].(string); ok {
+			byTag[name] = tag
+		}
+	}
+
+	for _, tag := range tags {
+		change := PermissionChange{Tag: tag, Current: Permissions{}, Desired: Permissions{}}
+		for name, desired := range config.TagPermissions[tag] {
+			// a permission the service doesn't list for a tag hasn't been granted
+			granted, _ := byTag[tag][name].(bool)
+			if granted != desired {
+				change.Current[name] = granted
+				change.Desired[name] = desired
+			}
+		}
+
+		if len(change.Desired) > 0 {
+			plan.TagPermissions = append(plan.TagPermissions, change)
+		}
+	}
+}
+
+// changedFields compares the fields of two triggers that a config sets.
+// Fields left empty, or at zero, are the same however they're written.
+func changedFields(current *geotrigger.Trigger, desired *geotrigger.Trigger) []string {
+	var fields []string
+	compare := func(name string, a interface{}, b interface{}) {
+		if !reflect.DeepEqual(normalize(a), normalize(b)) {
+			fields = append(fields, name)
+		}
+	}
+
+	compare("condition", current.Condition, desired.Condition)
+	compare("action", current.Action, desired.Action)
+	compare("tags", sortedTags(current.Tags), sortedTags(desired.Tags))
+	compare("properties", current.Properties, desired.Properties)
+	compare("times", current.Times, desired.Times)
+	compare("rateLimit", current.RateLimit, desired.RateLimit)
+
+	return fields
+}
+
+// normalize round trips a value through JSON, so it can be compared with
+// reflect.DeepEqual however it was built, and turns empty values into nil.
+func normalize(value interface{}) interface{} {
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return value
+	}
+
+	var normalized interface{}
+	if err := json.Unmarshal(raw, &normalized); err != nil {
+		return value
+	}
+
+	switch v := normalized.(type) {
+	case map[string]interface{}:
+		if len(v) == 0 {
+			return nil
+		}
+	case []interface{}:
+		if len(v) == 0 {
+			return nil
+		}
+	case float64:
+		if v == 0 {
+			return nil
+		}
+	}
+
+	return normalized
+}
+
+func sortedTags(tags []string) []string {
+	sorted := append([]string(nil), tags...)
+	sort.Strings(sorted)
+	return sorted
+}
+
+// Empty reports whether the plan has nothing to change.
+func (plan *Plan) Empty() bool {
+	return len(plan.Triggers) == 0 && len(plan.TagPermissions) == 0
+}
+
+// Diff describes the plan for people: a line for each trigger created (+),
+// updated (~) or deleted (-), with the old and new values of updated
+// fields, then a line for each tag whose permissions change.
+func (plan *Plan) Diff() string {
+	if plan.Empty() {
+		return "No changes.\n"
+	}
+
+	var diff strings.Builder
+	for _, change := range plan.Triggers {
+		label := change.Key
+		if len(change.TriggerID) > 0 && change.TriggerID != change.Key {
+			label = fmt.Sprintf("%s (%s)", change.Key, change.TriggerID)
+		}
+
+		switch change.Kind {
+		case ChangeCreate:
+			fmt.Fprintf(&diff, "+ trigger %s\n", label)
+		case ChangeDelete:
+			fmt.Fprintf(&diff, "- trigger %s\n", label)
+		case ChangeUpdate:
+			fmt.Fprintf(&diff, "~ trigger %s\n", label)
+			for _, field := range change.Fields {
+				fmt.Fprintf(&diff, "    %s: %s -> %s\n", field,
+					fieldJSON(change.Current, field), fieldJSON(change.Desired, field))
+			}
+		}
+	}
+
+	for _, change := range plan.TagPermissions {
+		fmt.Fprintf(&diff, "~ tag %s\n", change.Tag)
+
+		names := make([]string, 0, len(change.Desired))
+		for name := range change.Desired {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		for _, name := range names {
+			fmt.Fprintf(&diff, "    %s: %t -> %t\n", name, change.Current[name], change.Desired[name])
+		}
+	}
+
+	return diff.String()
+}
+
+// fieldJSON returns a field of a trigger as compact JSON, for Diff.
+func fieldJSON(trigger *geotrigger.Trigger, field string) string {
+	values := map[string]interface{}{
+		"condition":  trigger.Condition,
+		"action":     trigger.Action,
+		"tags":       sortedTags(trigger.Tags),
+		"properties": trigger.Properties,
+		"times":      trigger.Times,
+		"rateLimit":  trigger.RateLimit,
+	}
+
+	raw, err := json.Marshal(normalize(values[field]))
+	if err != nil {
+		return err.Error()
+	}
+
+	return string(raw)
+}
@@ -0,0 +1,128 @@
+package reconcile
+
+import (
+	"context"
+	"github.com/Esri/geotrigger-go/geotrigger"
+	"github.com/Esri/geotrigger-go/geotrigger/geotriggertest"
+	"github.com/Esri/geotrigger-go/geotrigger/test"
+	"strings"
+	"testing"
+)
+
+func newTestClient(t *testing.T) (*geotriggertest.Server, *geotrigger.Client) {
+	server := geotriggertest.NewServer()
+	server.AddApplication("good_client_id", "good_client_secret")
+
+	client, err := geotrigger.NewApplicationWithEnvironment(server.Environment(), "good_client_id", "good_client_secret")
+	test.Expect(t, err, nil)
+
+	return server, client
+}
+
+func circle(direction string, message string) geotrigger.Trigger {
+	return geotrigger.Trigger{
+		Condition: geotrigger.Condition{
+			Direction: direction,
+			Geo:       geotrigger.Geo{Latitude: 45.5165, Longitude: -122.6764, Distance: 100},
+		},
+		Action: geotrigger.Action{Message: message},
+	}
+}
+
+func TestPlanByMarker(t *testing.T) {
+	server, client := newTestClient(t)
+	defer server.Close()
+
+	// matches the first trigger in the config, with a different message
+	downtown := circle(geotrigger.DirectionEnter, "Hello downtown.")
+	downtown.TriggerID = "t1"
+	downtown.Tags = []string{"portland"}
+	downtown.Properties = map[string]interface{}{"deployKey": "downtown"}
+	server.AddTrigger(downtown)
+
+	// managed, but no longer in the config
+	stale := circle(geotrigger.DirectionEnter, "Old.")
+	stale.TriggerID = "t2"
+	stale.Properties = map[string]interface{}{"deployKey": "stale"}
+	server.AddTrigger(stale)
+
+	// not managed
+	other := circle(geotrigger.DirectionEnter, "Someone else's.")
+	other.TriggerID = "t3"
+	server.AddTrigger(other)
+
+	config, err := ReadConfig(strings.NewReader(markerConfigData))
+	test.Expect(t, err, nil)
+
+	plan, err := NewPlan(context.Background(), client, config)
+	test.Expect(t, err, nil)
+	test.Expect(t, len(plan.Triggers), 3)
+	test.Expect(t, plan.Triggers[0].Kind, ChangeUpdate)
+	test.Expect(t, plan.Triggers[0].TriggerID, "t1")
+	test.Expect(t, plan.Triggers[0].Fields, []string{"action"})
+	test.Expect(t, plan.Triggers[1].Kind, ChangeCreate)
+	test.Expect(t, plan.Triggers[1].Key, "downtown-leave")
+	test.Expect(t, plan.Triggers[2].Kind, ChangeDelete)
+	test.Expect(t, plan.Triggers[2].TriggerID, "t2")
+
+	// deviceTagging is already false
+	test.Expect(t, len(plan.TagPermissions), 1)
+	test.Expect(t, plan.TagPermissions[0].Desired, Permissions{"triggerList": true})
+
+	test.Expect(t, plan.Diff(), `~ trigger downtown (t1)
+    action: {"message":"Hello downtown."} -> {"message":"Welcome downtown."}
++ trigger downtown-leave
+- trigger stale (t2)
+~ tag portland
+    triggerList: false -> true
+`)
+}
+
+func TestPlanByTriggerID(t *testing.T) {
+	server, client := newTestClient(t)
+	defer server.Close()
+
+	// the same as the config, written differently
+	same := circle(geotrigger.DirectionEnter, "Hi.")
+	same.TriggerID = "same"
+	same.Tags = []string{"b", "a"}
+	same.Properties = map[string]interface{}{}
+	server.AddTrigger(same)
+
+	unlisted := circle(geotrigger.DirectionLeave, "Bye.")
+	unlisted.TriggerID = "unlisted"
+	server.AddTrigger(unlisted)
+
+	desired := circle(geotrigger.DirectionEnter, "Hi.")
+	desired.TriggerID = "same"
+	desired.Tags = []string{"a", "b"}
+	config := &Config{Triggers: []geotrigger.Trigger{desired}}
+
+	plan, err := NewPlan(context.Background(), client, config)
+	test.Expect(t, err, nil)
+	test.Expect(t, len(plan.Triggers), 1)
+	test.Expect(t, plan.Triggers[0].Kind, ChangeDelete)
+	test.Expect(t, plan.Diff(), "- trigger unlisted\n")
+
+	config.Triggers = append(config.Triggers, unlisted)
+	plan, err = NewPlan(context.Background(), client, config)
+	test.Expect(t, err, nil)
+	test.Expect(t, plan.Empty(), true)
+	test.Expect(t, plan.Diff(), "No changes.\n")
+}
+
+func TestPlanDuplicateMarkers(t *testing.T) {
+	server, client := newTestClient(t)
+	defer server.Close()
+
+	for _, id := range []string{"t1", "t2"} {
+		trigger := circle(geotrigger.DirectionEnter, "Hi.")
+		trigger.TriggerID = id
+		trigger.Properties = map[string]interface{}{"deployKey": "downtown"}
+		server.AddTrigger(trigger)
+	}
+
+	_, err := NewPlan(context.Background(), client, &Config{Marker: "deployKey"})
+	test.Refute(t, err, nil)
+	test.Expect(t, err.Error(), "Triggers t1 and t2 have the same deployKey: downtown.")
+}
@@ -0,0 +1,39 @@
+package geotrigger
+
+import (
+	"context"
+	"encoding/json"
+)
+
+// RunParams selects the triggers `trigger/run` fires, and the devices it
+// fires them for, by device ID or tag.
+type RunParams struct {
+	TriggerIDs []string `json:"triggerIds"`
+	DeviceIDs  []string `json:"deviceIds,omitempty"`
+	Tags       []string `json:"tags,omitempty"`
+}
+
+// RunResponse is the response to `trigger/run`: the triggers that were run.
+// Anything else the service sends back is in Extra.
+type RunResponse struct {
+	Triggers []Trigger              `json:"triggers"`
+	Raw      json.RawMessage        `json:"-"`
+	Extra    map[string]interface{} `json:"-"`
+}
+
+// RunTrigger fires the actions of triggers through `trigger/run`, as if the
+// selected devices had crossed their fences, for testing what a trigger does
+// without moving a device. The params are checked, as with any Validator,
+// before any request is made.
+func (client *Client) RunTrigger(ctx context.Context, params *RunParams) (*RunResponse, error) {
+	if params == nil {
+		params = &RunParams{}
+	}
+
+	response, err := Do[RunResponse](ctx, client, "trigger/run", params)
+	if err != nil {
+		return nil, err
+	}
+
+	return &response, nil
+}
@@ -0,0 +1,45 @@
+package geotrigger
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/Esri/geotrigger-go/geotrigger/test"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRunTrigger(t *testing.T) {
+	requests := 0
+	var params map[string]interface{}
+	gtServer := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, r *http.Request) {
+		requests++
+		test.Expect(t, r.URL.Path, "/trigger/run")
+		body, _ := ioutil.ReadAll(r.Body)
+		test.Expect(t, json.Unmarshal(body, &params), nil)
+		res.Write([]byte(`{"triggers":[{"triggerId":"cafe","condition":{"direction":"enter"}}],"runs":2}`))
+	}))
+	defer gtServer.Close()
+
+	client := ExistingDeviceWithEnvironment(Environment{GeotriggerURL: gtServer.URL}, "good_client_id",
+		"device_id", "good_access_token", 1800, "good_refresh_token")
+	ctx := context.Background()
+
+	response, err := client.RunTrigger(ctx, &RunParams{TriggerIDs: []string{"cafe"}, Tags: []string{"regulars"}})
+	test.Expect(t, err, nil)
+	test.Expect(t, params["triggerIds"], []interface{}{"cafe"})
+	test.Expect(t, params["tags"], []interface{}{"regulars"})
+	test.Expect(t, response.Triggers[0].TriggerID, "cafe")
+	test.Expect(t, response.Extra["runs"], float64(2))
+
+	_, err = client.RunTrigger(ctx, nil)
+	test.Refute(t, err, nil)
+	test.Expect(t, err.Error(), "Invalid params for trigger/run. triggerIds: At least one trigger ID is required. "+
+		"deviceIds: Device IDs or tags are required, to run the triggers for.")
+
+	_, err = client.RunTrigger(ctx, &RunParams{TriggerIDs: []string{"cafe"}, Tags: []string{" "}})
+	test.Refute(t, err, nil)
+	test.Expect(t, err.Error(), "Invalid params for trigger/run. tags[0]: Tags can't be empty.")
+	test.Expect(t, requests, 1)
+}
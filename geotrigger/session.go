@@ -2,6 +2,7 @@ package geotrigger
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -20,26 +21,38 @@ const (
 )
 
 var defEnv = &environment{
-	geotrigger_base_url,
-	ago_base_url,
+	geotriggerURL: geotrigger_base_url,
+	agoURL:        ago_base_url,
 }
 
 // The Session interface obfuscates whether we are a device or an application,
 // both of which implement the interface slightly differently.
 type session interface {
-	request(string, interface{}, interface{}) error
+	request(context.Context, string, interface{}, responseDecoder) error
 	info() map[string]string
 	// A session is also a TokenManager
 	tokenManager
 	// used internally when token expires
 	refresh(string) error
-	// used internally for changing URLs at runtime for testing
+	// used internally for changing URLs at runtime for testing, and for
+	// adding middleware
+	getEnv() *environment
 	setEnv(*environment)
 }
 
 type environment struct {
 	geotriggerURL string
 	agoURL        string
+	// nil means http.DefaultClient
+	httpClient *http.Client
+	// 0 means no limit
+	maxResponseSize int64
+	maxRequestSize  int64
+	// run after defaultMiddleware, in order. never appended to in place, see
+	// Client.Use
+	middleware []Middleware
+	// nil unless Client.UseDedupe has been called
+	flights *flightGroup
 }
 
 type errorResponse struct {
@@ -51,9 +64,15 @@ type errorJSON struct {
 	Message string `json:"message"`
 }
 
+var errClosed = errors.New("Client has been closed.")
+
 // func type for passing in to `post`. called when we get a 498 invalid token
 type refreshHandler func() (string, error)
 
+// func type for passing in to `post`. reads a successful (200) response body,
+// returning the error envelope instead if the server sent one.
+type responseDecoder func(path string, body io.Reader) (*errorResponse, error)
+
 // funcs below manage http specifically for geotrigger service and AGO credentials
 func doRefresh(session session, token string) (string, error) {
 	error := session.refresh(token)
@@ -71,19 +90,30 @@ func doRefresh(session session, token string) (string, error) {
 	return accessToken, error
 }
 
-func geotriggerPost(env *environment, session session, route string, params interface{},
-	responseJSON interface{}) error {
+func geotriggerPost(ctx context.Context, env *environment, session session, route string,
+	params interface{}, decode responseDecoder) error {
+	if err := validateParams(route, params); err != nil {
+		return err
+	}
+
 	body, err := json.Marshal(params)
 	if err != nil {
 		return fmt.Errorf("Error while marshaling params into JSON for route: %s. %s", route, err)
 	}
 
+	if err := validateSize(env, route, body); err != nil {
+		return err
+	}
+
 	// This func gets a blocking call if we get a 498 from the geotrigger server
 	refreshFunc := func() (string, error) {
 		tr := newTokenRequest(refreshNeeded, true)
 		go session.tokenRequest(tr)
 
 		tokenResp := <-tr.tokenResponses
+		if tokenResp == nil {
+			return "", errClosed
+		}
 
 		if tokenResp.isAccessToken {
 			// refresh request denied, another routine has already refreshed!
@@ -99,6 +129,9 @@ func geotriggerPost(env *environment, session session, route string, params inte
 	go session.tokenRequest(tr)
 
 	tokenResp := <-tr.tokenResponses
+	if tokenResp == nil {
+		return fmt.Errorf("Error before hitting route: %s. %s", route, errClosed)
+	}
 
 	var token string
 	if tokenResp.isAccessToken {
@@ -114,17 +147,17 @@ func geotriggerPost(env *environment, session session, route string, params inte
 			route, err)
 	}
 
-	req, err := http.NewRequest("POST", routeConcat(env.geotriggerURL, route), bytes.NewReader(body))
+	// for middleware in this package, see limitBody
+	ctx = context.WithValue(ctx, environmentKey{}, env)
+	req, err := http.NewRequestWithContext(ctx, "POST", routeConcat(env.geotriggerURL, route), bytes.NewReader(body))
 	if err != nil {
 		return fmt.Errorf("Error creating GeotriggerPost for route %s. %s", route, err)
 	}
 
 	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
 	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("X-GT-Client-Name", "geotrigger-go")
-	req.Header.Set("X-GT-Client-Version", version)
 
-	return post(req, body, responseJSON, refreshFunc)
+	return post(env, env.handler(), req, body, decode, refreshFunc)
 }
 
 func agoPost(env *environment, route string, body []byte, responseJSON interface{}) error {
@@ -134,15 +167,16 @@ func agoPost(env *environment, route string, body []byte, responseJSON interface
 	}
 
 	req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
-	return post(req, body, responseJSON, func() (string, error) {
+	return post(env, env.client().Do, req, body, bufferedDecoder(responseJSON), func() (string, error) {
 		return "", errors.New("Expired token response from AGO. This is basically a 500.")
 	})
 }
 
-func post(req *http.Request, body []byte, responseJSON interface{}, refreshFunc refreshHandler) error {
+func post(env *environment, send Handler, req *http.Request, body []byte, decode responseDecoder,
+	refreshFunc refreshHandler) error {
 	path := req.URL.Path
 
-	resp, err := http.DefaultClient.Do(req)
+	resp, err := send(req)
 	if err != nil {
 		return fmt.Errorf("Error while posting to: %s. Error: %s", path, err)
 	}
@@ -152,12 +186,12 @@ func post(req *http.Request, body []byte, responseJSON interface{}, refreshFunc
 	}
 
 	defer resp.Body.Close()
-	contents, err := ioutil.ReadAll(resp.Body)
+	errResponse, err := decode(path, limitResponse(env, path, resp.Body))
 	if err != nil {
-		return fmt.Errorf("Could not read response body from %s. %s", path, err)
+		return err
 	}
 
-	if errResponse := errorCheck(contents); errResponse != nil {
+	if errResponse != nil {
 		if errResponse.Error.Code == 498 {
 			if token, err := refreshFunc(); err == nil {
 				// time to refresh!
@@ -174,7 +208,7 @@ func post(req *http.Request, body []byte, responseJSON interface{}, refreshFunc
 				}
 				req.Body = rc
 				req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
-				return post(req, body, responseJSON, refreshFunc)
+				return post(env, send, req, body, decode, refreshFunc)
 			} else {
 				return err
 			}
@@ -184,7 +218,65 @@ func post(req *http.Request, body []byte, responseJSON interface{}, refreshFunc
 		}
 	}
 
-	return parseJSONResponse(contents, responseJSON)
+	return nil
+}
+
+// bufferedDecoder reads the whole response before checking it for an error
+// and parsing it into `responseJSON`.
+func bufferedDecoder(responseJSON interface{}) responseDecoder {
+	return func(path string, body io.Reader) (*errorResponse, error) {
+		contents, err := ioutil.ReadAll(body)
+		if err != nil {
+			return nil, fmt.Errorf("Could not read response body from %s. %s", path, err)
+		}
+
+		if errResponse := errorCheck(contents); errResponse != nil {
+			return errResponse, nil
+		}
+
+		return nil, parseJSONResponse(contents, responseJSON)
+	}
+}
+
+// limitResponse caps how much of a response body can be read, if the
+// environment asks for it.
+func limitResponse(env *environment, path string, body io.Reader) io.Reader {
+	if env.maxResponseSize <= 0 {
+		return body
+	}
+
+	return &limitedReader{reader: body, remaining: env.maxResponseSize, max: env.maxResponseSize, path: path}
+}
+
+// limitedReader is like io.LimitedReader, but fails rather than stopping
+// quietly at the limit, so a truncated response is never mistaken for a
+// whole one.
+type limitedReader struct {
+	reader    io.Reader
+	remaining int64
+	max       int64
+	path      string
+}
+
+func (limitedReader *limitedReader) Read(p []byte) (int, error) {
+	if limitedReader.remaining <= 0 {
+		// only an error if there was more to read
+		var probe [1]byte
+		if n, err := limitedReader.reader.Read(probe[:]); n == 0 {
+			return 0, err
+		}
+
+		return 0, fmt.Errorf("Response from %s exceeded the maximum size of %d bytes.",
+			limitedReader.path, limitedReader.max)
+	}
+
+	if int64(len(p)) > limitedReader.remaining {
+		p = p[:limitedReader.remaining]
+	}
+
+	n, err := limitedReader.reader.Read(p)
+	limitedReader.remaining -= int64(n)
+	return n, err
 }
 
 func errorCheck(resp []byte) *errorResponse {
@@ -215,6 +307,9 @@ func parseJSONResponse(resp []byte, responseJSON interface{}) error {
 		return fmt.Errorf("Error parsing response: %s  Error: %s", string(resp), err)
 	}
 
+	// see extra.go
+	fillExtras(reflect.ValueOf(responseJSON), resp)
+
 	return nil
 }
 
@@ -231,6 +326,42 @@ func routeConcat(baseURL, route string) string {
 	return buffer.String()
 }
 
+// internal fills in any URLs missing from a public Environment with the defaults.
+func (env Environment) internal() *environment {
+	internal := *defEnv
+	if len(env.GeotriggerURL) > 0 {
+		internal.geotriggerURL = env.GeotriggerURL
+	}
+	if len(env.AGOURL) > 0 {
+		internal.agoURL = env.AGOURL
+	}
+	if env.HTTPClient != nil {
+		internal.httpClient = env.HTTPClient
+	}
+	if env.MaxResponseSize > 0 {
+		internal.maxResponseSize = env.MaxResponseSize
+	}
+	if env.MaxRequestSize > 0 {
+		internal.maxRequestSize = env.MaxRequestSize
+	}
+	if len(env.Middleware) > 0 {
+		internal.middleware = append([]Middleware(nil), env.Middleware...)
+	}
+
+	return &internal
+}
+
+func (env *environment) client() *http.Client {
+	if env.httpClient != nil {
+		return env.httpClient
+	}
+
+	return http.DefaultClient
+}
+
 func testEnv(gtURL, agoURL string) *environment {
-	return &environment{gtURL, agoURL}
+	return &environment{
+		geotriggerURL: gtURL,
+		agoURL:        agoURL,
+	}
 }
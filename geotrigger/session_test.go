@@ -6,23 +6,23 @@ import (
 )
 
 type TriggerList struct {
-	Triggers    []Trigger   `json:"triggers"`
-	BoundingBox BoundingBox `json:"boundingBox"`
+	Triggers    []TriggerTest `json:"triggers"`
+	BoundingBox BoundingBox   `json:"boundingBox"`
 }
 
-type Trigger struct {
-	TriggerID string    `json:"triggerId"`
-	Condition Condition `json:"condition"`
-	Action    Action    `json:"action"`
-	Tags      []string  `json:"tags"`
+type TriggerTest struct {
+	TriggerID string        `json:"triggerId"`
+	Condition ConditionTest `json:"condition"`
+	Action    ActionTest    `json:"action"`
+	Tags      []string      `json:"tags"`
 }
 
-type Condition struct {
-	Direction string `json:"direction"`
-	Geo       Geo    `json:"geo"`
+type ConditionTest struct {
+	Direction string  `json:"direction"`
+	Geo       GeoTest `json:"geo"`
 }
 
-type Geo struct {
+type GeoTest struct {
 	Geocode   string  `json:"geocode"`
 	DriveTime int     `json:"drivetime"`
 	Context   Context `json:"context"`
@@ -35,7 +35,7 @@ type Context struct {
 	Zipcode  string `json:"zipcode"`
 }
 
-type Action struct {
+type ActionTest struct {
 	Message  string `json:"message"`
 	Callback string `json:"callback"`
 }
@@ -48,9 +48,9 @@ type BoundingBox struct {
 }
 
 type WrongJSON struct {
-	Derp   []string `json"derp"`
-	Dorp   int      `json:"dorp"`
-	Action Action   `json:"action"`
+	Derp   []string   `json"derp"`
+	Dorp   int        `json:"dorp"`
+	Action ActionTest `json:"action"`
 }
 
 /* editing these will break tests */
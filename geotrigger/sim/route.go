@@ -0,0 +1,203 @@
+package sim
+
+import (
+	"errors"
+	"github.com/Esri/geotrigger-go/geotrigger/geofence"
+	"math"
+	"math/rand"
+)
+
+// Point is a position in WGS84 degrees.
+type Point struct {
+	Latitude  float64
+	Longitude float64
+}
+
+// Route moves a single simulated device. Routes keep their own position, so
+// each device needs a Route of its own.
+type Route interface {
+	// Position returns where the device currently is.
+	Position() Point
+	// Advance moves the device `meters` further along, returning false if
+	// the device was already at the end of the route and could not move.
+	Advance(meters float64) bool
+}
+
+// Path is a Route following a line through a list of points, as loaded from
+// a GeoJSON or GPX file.
+type Path struct {
+	points []Point
+	// index of the segment the device is on, and the meters traveled along it
+	segment int
+	along   float64
+	loop    bool
+}
+
+// NewPath creates a route along `points`, starting `offset` meters from the
+// first point. If `loop` is true, the device goes back to the first point
+// when it reaches the last, and the route never ends.
+func NewPath(points []Point, offset float64, loop bool) (*Path, error) {
+	if len(points) < 2 {
+		return nil, errors.New("A path needs at least two points.")
+	}
+
+	path := &Path{points: points, loop: loop}
+	path.Advance(offset)
+	return path, nil
+}
+
+// Length returns the length of the path in meters, not counting the way back
+// to the start of a looping path.
+func (path *Path) Length() float64 {
+	length := 0.0
+	for i := 1; i < len(path.points); i++ {
+		length += distance(path.points[i-1], path.points[i])
+	}
+
+	return length
+}
+
+func (path *Path) Position() Point {
+	from, to := path.segmentEnds()
+	length := distance(from, to)
+	if length == 0 {
+		return from
+	}
+
+	fraction := path.along / length
+	return Point{
+		Latitude:  from.Latitude + (to.Latitude-from.Latitude)*fraction,
+		Longitude: from.Longitude + (to.Longitude-from.Longitude)*fraction,
+	}
+}
+
+func (path *Path) Advance(meters float64) bool {
+	if path.atEnd() {
+		return false
+	}
+
+	path.along += meters
+	for {
+		from, to := path.segmentEnds()
+		length := distance(from, to)
+		if path.along < length {
+			return true
+		}
+
+		if path.segment == path.segments()-1 && !path.loop {
+			path.along = length
+			return true
+		}
+
+		path.along -= length
+		path.segment = (path.segment + 1) % path.segments()
+
+		// a loop of zero length would never get anywhere
+		if path.segment == 0 && path.Length() == 0 {
+			path.along = 0
+			return true
+		}
+	}
+}
+
+func (path *Path) atEnd() bool {
+	if path.loop || path.segment < path.segments()-1 {
+		return false
+	}
+
+	from, to := path.segmentEnds()
+	return path.along >= distance(from, to)
+}
+
+// segments counts the segments of the path, including the one back to the
+// start of a looping path.
+func (path *Path) segments() int {
+	if path.loop {
+		return len(path.points)
+	}
+
+	return len(path.points) - 1
+}
+
+func (path *Path) segmentEnds() (Point, Point) {
+	return path.points[path.segment], path.points[(path.segment+1)%len(path.points)]
+}
+
+// RandomWalk is a Route that wanders at random, turning up to 45 degrees
+// between steps and heading back whenever it would stray further than a set
+// radius from where it started. It never ends.
+type RandomWalk struct {
+	center   Point
+	radius   float64
+	position Point
+	heading  float64
+	random   *rand.Rand
+}
+
+// NewRandomWalk creates a walk starting at `center` and staying within
+// `radius` meters of it. Walks created with the same seed take the same steps.
+func NewRandomWalk(center Point, radius float64, seed int64) *RandomWalk {
+	random := rand.New(rand.NewSource(seed))
+	return &RandomWalk{
+		center:   center,
+		radius:   radius,
+		position: center,
+		heading:  random.Float64() * 2 * math.Pi,
+		random:   random,
+	}
+}
+
+func (walk *RandomWalk) Position() Point {
+	return walk.position
+}
+
+func (walk *RandomWalk) Advance(meters float64) bool {
+	walk.heading += (walk.random.Float64() - 0.5) * math.Pi / 2
+
+	next := destination(walk.position, walk.heading, meters)
+	if distance(walk.center, next) > walk.radius {
+		walk.heading = bearing(walk.position, walk.center)
+		next = destination(walk.position, walk.heading, meters)
+	}
+
+	walk.position = next
+	return true
+}
+
+func distance(from, to Point) float64 {
+	return geofence.Distance(from.Latitude, from.Longitude, to.Latitude, to.Longitude)
+}
+
+// destination moves `meters` from a point along a heading, in radians
+// clockwise from north.
+func destination(from Point, heading float64, meters float64) Point {
+	latitude := radians(from.Latitude)
+	longitude := radians(from.Longitude)
+	angle := meters / geofence.EarthRadius
+
+	toLatitude := math.Asin(math.Sin(latitude)*math.Cos(angle) +
+		math.Cos(latitude)*math.Sin(angle)*math.Cos(heading))
+	toLongitude := longitude + math.Atan2(math.Sin(heading)*math.Sin(angle)*math.Cos(latitude),
+		math.Cos(angle)-math.Sin(latitude)*math.Sin(toLatitude))
+
+	return Point{Latitude: degrees(toLatitude), Longitude: degrees(toLongitude)}
+}
+
+// bearing returns the initial heading from one point to another, in radians
+// clockwise from north.
+func bearing(from, to Point) float64 {
+	fromLatitude := radians(from.Latitude)
+	toLatitude := radians(to.Latitude)
+	deltaLongitude := radians(to.Longitude - from.Longitude)
+
+	return math.Atan2(math.Sin(deltaLongitude)*math.Cos(toLatitude),
+		math.Cos(fromLatitude)*math.Sin(toLatitude)-math.Sin(fromLatitude)*math.Cos(toLatitude)*math.Cos(deltaLongitude))
+}
+
+func radians(degrees float64) float64 {
+	return degrees * math.Pi / 180
+}
+
+func degrees(radians float64) float64 {
+	return radians * 180 / math.Pi
+}
@@ -0,0 +1,79 @@
+package sim
+
+import (
+	"github.com/Esri/geotrigger-go/geotrigger/test"
+	"math"
+	"testing"
+)
+
+// about 1112 meters apart, along a line of longitude
+var line = []Point{{45.50, -122.6}, {45.51, -122.6}, {45.52, -122.6}}
+
+func near(a, b Point) bool {
+	return distance(a, b) < 0.01
+}
+
+func TestPath(t *testing.T) {
+	path, err := NewPath(line, 0, false)
+	test.Expect(t, err, nil)
+	test.Expect(t, math.Round(path.Length()), float64(2224))
+	test.Expect(t, near(path.Position(), line[0]), true)
+
+	test.Expect(t, path.Advance(path.Length()/4), true)
+	test.Expect(t, near(path.Position(), Point{45.505, -122.6}), true)
+
+	// crossing into the next segment
+	test.Expect(t, path.Advance(path.Length()/2), true)
+	test.Expect(t, near(path.Position(), Point{45.515, -122.6}), true)
+
+	// overshooting stops at the end, then the path is over
+	test.Expect(t, path.Advance(5000), true)
+	test.Expect(t, near(path.Position(), line[2]), true)
+	test.Expect(t, path.Advance(1), false)
+	test.Expect(t, near(path.Position(), line[2]), true)
+
+	// starting partway along
+	path, err = NewPath(line, path.Length()/2, false)
+	test.Expect(t, err, nil)
+	test.Expect(t, near(path.Position(), line[1]), true)
+
+	_, err = NewPath(line[:1], 0, false)
+	test.Expect(t, err.Error(), "A path needs at least two points.")
+}
+
+func TestLoopingPath(t *testing.T) {
+	path, err := NewPath(line, 0, true)
+	test.Expect(t, err, nil)
+
+	// out and back again, then a quarter of the way out
+	length := path.Length()
+	test.Expect(t, path.Advance(length), true)
+	test.Expect(t, near(path.Position(), line[2]), true)
+	test.Expect(t, path.Advance(length+length/4), true)
+	test.Expect(t, near(path.Position(), Point{45.505, -122.6}), true)
+
+	still, err := NewPath([]Point{line[0], line[0]}, 0, true)
+	test.Expect(t, err, nil)
+	test.Expect(t, still.Advance(10), true)
+	test.Expect(t, near(still.Position(), line[0]), true)
+}
+
+func TestRandomWalk(t *testing.T) {
+	center := Point{45.5, -122.6}
+	walk := NewRandomWalk(center, 500, 42)
+	same := NewRandomWalk(center, 500, 42)
+	test.Expect(t, walk.Position(), center)
+
+	for i := 0; i < 1000; i++ {
+		previous := walk.Position()
+		test.Expect(t, walk.Advance(50), true)
+		same.Advance(50)
+
+		test.Expect(t, math.Round(distance(previous, walk.Position())), float64(50))
+		// a step back toward the center can start just outside the radius
+		test.Expect(t, distance(center, walk.Position()) <= 550, true)
+	}
+
+	// the same seed takes the same steps
+	test.Expect(t, walk.Position(), same.Position())
+}
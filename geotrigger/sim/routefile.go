@@ -0,0 +1,166 @@
+package sim
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+type geoJSONObject struct {
+	Type        string            `json:"type"`
+	Coordinates json.RawMessage   `json:"coordinates"`
+	Geometry    *geoJSONObject    `json:"geometry"`
+	Features    []json.RawMessage `json:"features"`
+}
+
+// ReadGeoJSONRoute reads the points of a route from GeoJSON: a LineString or
+// MultiLineString geometry, or a Feature or FeatureCollection containing one.
+// The lines of a MultiLineString are joined end to end, and the first line
+// found in a FeatureCollection is used.
+func ReadGeoJSONRoute(r io.Reader) ([]Point, error) {
+	var object geoJSONObject
+	if err := json.NewDecoder(r).Decode(&object); err != nil {
+		return nil, fmt.Errorf("Could not parse GeoJSON route. %s", err)
+	}
+
+	return geoJSONPoints(&object)
+}
+
+func geoJSONPoints(object *geoJSONObject) ([]Point, error) {
+	switch object.Type {
+	case "LineString":
+		var line [][]float64
+		if err := json.Unmarshal(object.Coordinates, &line); err != nil {
+			return nil, fmt.Errorf("Could not read LineString coordinates. %s", err)
+		}
+		return positionPoints(line)
+	case "MultiLineString":
+		var lines [][][]float64
+		if err := json.Unmarshal(object.Coordinates, &lines); err != nil {
+			return nil, fmt.Errorf("Could not read MultiLineString coordinates. %s", err)
+		}
+		var points []Point
+		for _, line := range lines {
+			linePoints, err := positionPoints(line)
+			if err != nil {
+				return nil, err
+			}
+			points = append(points, linePoints...)
+		}
+		return points, nil
+	case "Feature":
+		if object.Geometry == nil {
+			return nil, errors.New("GeoJSON Feature has no geometry.")
+		}
+		return geoJSONPoints(object.Geometry)
+	case "FeatureCollection":
+		for _, raw := range object.Features {
+			var feature geoJSONObject
+			if err := json.Unmarshal(raw, &feature); err != nil {
+				return nil, fmt.Errorf("Could not read GeoJSON feature. %s", err)
+			}
+			if points, err := geoJSONPoints(&feature); err == nil {
+				return points, nil
+			}
+		}
+		return nil, errors.New("No LineString found in GeoJSON FeatureCollection.")
+	}
+
+	return nil, fmt.Errorf("Unsupported GeoJSON type for a route: %s.", object.Type)
+}
+
+// positionPoints turns GeoJSON [longitude, latitude] positions into points.
+func positionPoints(positions [][]float64) ([]Point, error) {
+	points := make([]Point, 0, len(positions))
+	for _, position := range positions {
+		if len(position) < 2 {
+			return nil, fmt.Errorf("Invalid GeoJSON position: %v", position)
+		}
+		points = append(points, Point{Latitude: position[1], Longitude: position[0]})
+	}
+
+	return points, nil
+}
+
+type gpxFile struct {
+	Tracks []struct {
+		Segments []struct {
+			Points []gpxPoint `xml:"trkpt"`
+		} `xml:"trkseg"`
+	} `xml:"trk"`
+	Routes []struct {
+		Points []gpxPoint `xml:"rtept"`
+	} `xml:"rte"`
+}
+
+type gpxPoint struct {
+	Latitude  float64 `xml:"lat,attr"`
+	Longitude float64 `xml:"lon,attr"`
+	Time      string  `xml:"time"`
+}
+
+// ReadGPXRoute reads the points of a route from GPX. The points of every
+// track segment are joined end to end; if the file has no tracks, its routes
+// are used instead.
+func ReadGPXRoute(r io.Reader) ([]Point, error) {
+	gpxPoints, err := readGPX(r)
+	if err != nil {
+		return nil, err
+	}
+
+	points := make([]Point, 0, len(gpxPoints))
+	for _, point := range gpxPoints {
+		points = append(points, Point{Latitude: point.Latitude, Longitude: point.Longitude})
+	}
+
+	return points, nil
+}
+
+// readGPX reads the track points of a GPX file, or its route points if it
+// has no tracks.
+func readGPX(r io.Reader) ([]gpxPoint, error) {
+	var gpx gpxFile
+	if err := xml.NewDecoder(r).Decode(&gpx); err != nil {
+		return nil, fmt.Errorf("Could not parse GPX. %s", err)
+	}
+
+	var points []gpxPoint
+	for _, track := range gpx.Tracks {
+		for _, segment := range track.Segments {
+			points = append(points, segment.Points...)
+		}
+	}
+
+	if len(points) == 0 {
+		for _, route := range gpx.Routes {
+			points = append(points, route.Points...)
+		}
+	}
+
+	if len(points) == 0 {
+		return nil, errors.New("No track or route points found in GPX.")
+	}
+
+	return points, nil
+}
+
+// LoadRoute reads the points of a route from a file, as GPX if its name ends
+// in `.gpx` and as GeoJSON otherwise.
+func LoadRoute(path string) ([]Point, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("Could not open route file. %s", err)
+	}
+	defer file.Close()
+
+	if strings.EqualFold(filepath.Ext(path), ".gpx") {
+		return ReadGPXRoute(file)
+	}
+
+	return ReadGeoJSONRoute(file)
+}
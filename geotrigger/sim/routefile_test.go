@@ -0,0 +1,82 @@
+package sim
+
+import (
+	"github.com/Esri/geotrigger-go/geotrigger/test"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const gpxRoute = `<?xml version="1.0" encoding="UTF-8"?>
+<gpx version="1.1" creator="test" xmlns="http://www.topografix.com/GPX/1/1">
+  <trk><name>walk</name>
+    <trkseg>
+      <trkpt lat="45.50" lon="-122.6"><ele>10</ele><time>2014-04-22T17:30:00Z</time></trkpt>
+      <trkpt lat="45.51" lon="-122.6"><time>2014-04-22T17:40:00Z</time></trkpt>
+    </trkseg>
+    <trkseg>
+      <trkpt lat="45.52" lon="-122.6"></trkpt>
+    </trkseg>
+  </trk>
+</gpx>`
+
+func TestReadGeoJSONRoute(t *testing.T) {
+	points, err := ReadGeoJSONRoute(strings.NewReader(`{"type":"LineString","coordinates":[[-122.6,45.50],[-122.6,45.51]]}`))
+	test.Expect(t, err, nil)
+	test.Expect(t, points, []Point{{45.50, -122.6}, {45.51, -122.6}})
+
+	points, err = ReadGeoJSONRoute(strings.NewReader(`{"type":"MultiLineString","coordinates":[[[-122.6,45.50],[-122.6,45.51]],[[-122.6,45.52]]]}`))
+	test.Expect(t, err, nil)
+	test.Expect(t, points, line)
+
+	points, err = ReadGeoJSONRoute(strings.NewReader(`{"type":"FeatureCollection","features":[
+		{"type":"Feature","geometry":{"type":"Point","coordinates":[0,0]}},
+		{"type":"Feature","properties":{},"geometry":{"type":"LineString","coordinates":[[-122.6,45.50],[-122.6,45.51],[-122.6,45.52]]}}]}`))
+	test.Expect(t, err, nil)
+	test.Expect(t, points, line)
+
+	_, err = ReadGeoJSONRoute(strings.NewReader(`{"type":"Polygon","coordinates":[]}`))
+	test.Expect(t, err.Error(), "Unsupported GeoJSON type for a route: Polygon.")
+
+	_, err = ReadGeoJSONRoute(strings.NewReader(`{"type":"LineString","coordinates":[[-122.6]]}`))
+	test.Expect(t, err.Error(), "Invalid GeoJSON position: [-122.6]")
+
+	_, err = ReadGeoJSONRoute(strings.NewReader(`{"type":"FeatureCollection","features":[]}`))
+	test.Expect(t, err.Error(), "No LineString found in GeoJSON FeatureCollection.")
+}
+
+func TestReadGPXRoute(t *testing.T) {
+	points, err := ReadGPXRoute(strings.NewReader(gpxRoute))
+	test.Expect(t, err, nil)
+	test.Expect(t, points, line)
+
+	points, err = ReadGPXRoute(strings.NewReader(`<gpx><rte><rtept lat="45.5" lon="-122.6"/><rtept lat="45.51" lon="-122.6"/></rte></gpx>`))
+	test.Expect(t, err, nil)
+	test.Expect(t, points, line[:2])
+
+	_, err = ReadGPXRoute(strings.NewReader(`<gpx></gpx>`))
+	test.Expect(t, err.Error(), "No track or route points found in GPX.")
+}
+
+func TestLoadRoute(t *testing.T) {
+	dir, err := ioutil.TempDir("", "geotrigger-sim")
+	test.Expect(t, err, nil)
+	defer os.RemoveAll(dir)
+
+	gpxPath := filepath.Join(dir, "walk.GPX")
+	test.Expect(t, ioutil.WriteFile(gpxPath, []byte(gpxRoute), 0600), nil)
+	points, err := LoadRoute(gpxPath)
+	test.Expect(t, err, nil)
+	test.Expect(t, points, line)
+
+	geojsonPath := filepath.Join(dir, "walk.geojson")
+	test.Expect(t, ioutil.WriteFile(geojsonPath, []byte(`{"type":"LineString","coordinates":[[-122.6,45.50],[-122.6,45.51]]}`), 0600), nil)
+	points, err = LoadRoute(geojsonPath)
+	test.Expect(t, err, nil)
+	test.Expect(t, len(points), 2)
+
+	_, err = LoadRoute(filepath.Join(dir, "missing.gpx"))
+	test.Refute(t, err, nil)
+}
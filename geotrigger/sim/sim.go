@@ -0,0 +1,258 @@
+// Package `sim` simulates a fleet of devices moving along routes and
+// reporting their locations to the Geotrigger Service through
+// `location/update`, for load testing trigger configurations.
+//
+// Devices either come from RegisterDevices or are existing device clients.
+// Each follows a Route of its own, such as a Path loaded from a GPX or
+// GeoJSON file, or a RandomWalk. A `geofence.Engine` can be fed the same
+// locations to collect the trigger events they are expected to fire:
+//
+//	clients, err := sim.RegisterDevices(env, "client_id", 50)
+//	...
+//	simulator := &sim.Simulator{Interval: 10 * time.Second, Duration: time.Hour, Engine: engine}
+//	for i, client := range clients {
+//		walk := sim.NewRandomWalk(sim.Point{Latitude: 45.52, Longitude: -122.68}, 2000, int64(i))
+//		simulator.Devices = append(simulator.Devices, &sim.Device{Client: client, Route: walk, Speed: 1.4})
+//	}
+//	report, err := simulator.Run(ctx)
+//
+// Recorded tracks, from GPX or CSV files, can be replayed as the locations
+// of a single device with Replay, to reproduce what the service made of them.
+package sim
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/Esri/geotrigger-go/geotrigger"
+	"github.com/Esri/geotrigger-go/geotrigger/geofence"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	defaultInterval = 10 * time.Second
+	defaultAccuracy = 10
+)
+
+// Device is a simulated device: a device client, and how it moves.
+type Device struct {
+	Client *geotrigger.Client
+	Route  Route
+	// meters per second
+	Speed float64
+	// accuracy in meters reported with every location, 10 if zero
+	Accuracy float64
+	// added to the device before the simulation starts
+	Tags []string
+
+	deviceID string
+}
+
+// Report sums up a simulation run.
+type Report struct {
+	// locations reported successfully
+	Locations int
+	// location updates that failed, in the order they happened
+	Errors []error
+	// events fired by the simulator's Engine, ordered by time
+	Events []geofence.Event
+	// wall clock time the run took
+	Elapsed time.Duration
+}
+
+// Simulator moves a fleet of devices and reports their locations. Every
+// Interval of simulated time, each device moves along its route and reports
+// where it is.
+type Simulator struct {
+	Devices []*Device
+	// simulated time between location reports, 10 seconds if zero
+	Interval time.Duration
+	// simulated time to run for. If zero the simulation runs until every
+	// route has ended, or until the context passed to Run is done.
+	Duration time.Duration
+	// how much faster than the wall clock the simulation runs: 1 is real time.
+	// If zero, locations are reported as fast as the service takes them.
+	Speedup float64
+	// simulated time of the first report, now if zero
+	Start time.Time
+	// if set, fed every location reported, with the events it fires collected
+	// in the report
+	Engine *geofence.Engine
+
+	lock   sync.Mutex
+	report *Report
+}
+
+type locationUpdateResponse struct {
+	ProcessedLocations int `json:"processedLocations"`
+}
+
+// RegisterDevices registers `count` new devices for the application with the
+// provided client ID. If any registration fails, the clients already created
+// are closed and the error is returned.
+func RegisterDevices(env geotrigger.Environment, clientID string, count int) ([]*geotrigger.Client, error) {
+	clients := make([]*geotrigger.Client, 0, count)
+	for i := 0; i < count; i++ {
+		client, err := geotrigger.NewDeviceWithEnvironment(env, clientID)
+		if err != nil {
+			for _, created := range clients {
+				created.Close()
+			}
+			return nil, fmt.Errorf("Could not register device %d of %d. %s", i+1, count, err)
+		}
+		clients = append(clients, client)
+	}
+
+	return clients, nil
+}
+
+// Run tags the devices, then runs the simulation until it is over or `ctx`
+// is done, whichever comes first. Failed location updates don't stop the
+// run; they are collected in the report. An error is only returned if the
+// simulation could not be started.
+func (simulator *Simulator) Run(ctx context.Context) (*Report, error) {
+	if err := simulator.setup(ctx); err != nil {
+		return nil, err
+	}
+
+	simulator.report = &Report{}
+	began := time.Now()
+	start := simulator.Start
+	if start.IsZero() {
+		start = began
+	}
+
+	var wg sync.WaitGroup
+	for _, device := range simulator.Devices {
+		wg.Add(1)
+		go func(device *Device) {
+			defer wg.Done()
+			simulator.move(ctx, device, start, began)
+		}(device)
+	}
+	wg.Wait()
+
+	report := simulator.report
+	report.Elapsed = time.Since(began)
+	sort.SliceStable(report.Events, func(i, j int) bool {
+		return report.Events[i].Location.Timestamp.Before(report.Events[j].Location.Timestamp)
+	})
+
+	return report, nil
+}
+
+// setup checks the devices, and adds their tags.
+func (simulator *Simulator) setup(ctx context.Context) error {
+	if len(simulator.Devices) == 0 {
+		return errors.New("No devices to simulate.")
+	}
+
+	for i, device := range simulator.Devices {
+		if device.Client == nil || device.Route == nil {
+			return fmt.Errorf("Device %d needs both a client and a route.", i)
+		}
+
+		device.deviceID = device.Client.Info()["device_id"]
+		if len(device.deviceID) == 0 {
+			return fmt.Errorf("Device %d has a client that isn't a device.", i)
+		}
+
+		if len(device.Tags) > 0 {
+			params := &geotrigger.DeviceUpdateParams{AddTags: device.Tags}
+			if _, err := geotrigger.Do[map[string]interface{}](ctx, device.Client, "device/update", params); err != nil {
+				return fmt.Errorf("Could not tag device %s. %s", device.deviceID, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// move runs a single device through the simulation.
+func (simulator *Simulator) move(ctx context.Context, device *Device, start time.Time, began time.Time) {
+	interval := simulator.Interval
+	if interval <= 0 {
+		interval = defaultInterval
+	}
+	accuracy := device.Accuracy
+	if accuracy <= 0 {
+		accuracy = defaultAccuracy
+	}
+
+	for step := 0; ; step++ {
+		offset := time.Duration(step) * interval
+		if simulator.Duration > 0 && offset > simulator.Duration {
+			return
+		}
+
+		if step > 0 && !device.Route.Advance(device.Speed*interval.Seconds()) {
+			return
+		}
+
+		if !simulator.wait(ctx, began, offset) {
+			return
+		}
+
+		position := device.Route.Position()
+		location := geotrigger.Location{
+			Latitude:  position.Latitude,
+			Longitude: position.Longitude,
+			Accuracy:  accuracy,
+			Timestamp: start.Add(offset),
+		}
+
+		params := LocationUpdateParams([]geotrigger.Location{location})
+		_, err := geotrigger.Do[locationUpdateResponse](ctx, device.Client, "location/update", params)
+		if ctx.Err() != nil {
+			// the run is over, this isn't the service's fault
+			return
+		}
+
+		// the engine only sees what the service saw
+		var events []geofence.Event
+		if err == nil && simulator.Engine != nil {
+			events = simulator.Engine.Update(device.deviceID, device.Tags, location)
+		}
+
+		simulator.record(device, err, events)
+	}
+}
+
+// wait paces the simulation against the wall clock, returning false if the
+// context is done first.
+func (simulator *Simulator) wait(ctx context.Context, began time.Time, offset time.Duration) bool {
+	if simulator.Speedup <= 0 {
+		return ctx.Err() == nil
+	}
+
+	return waitUntil(ctx, began.Add(time.Duration(float64(offset)/simulator.Speedup)))
+}
+
+// waitUntil returns true at `due`, or false if the context is done first.
+func waitUntil(ctx context.Context, due time.Time) bool {
+	timer := time.NewTimer(time.Until(due))
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return ctx.Err() == nil
+	}
+}
+
+func (simulator *Simulator) record(device *Device, err error, events []geofence.Event) {
+	simulator.lock.Lock()
+	defer simulator.lock.Unlock()
+
+	if err != nil {
+		simulator.report.Errors = append(simulator.report.Errors,
+			fmt.Errorf("Location update for device %s failed. %s", device.deviceID, err))
+		return
+	}
+
+	simulator.report.Locations++
+	simulator.report.Events = append(simulator.report.Events, events...)
+}
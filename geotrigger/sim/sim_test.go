@@ -0,0 +1,125 @@
+package sim
+
+import (
+	"context"
+	"github.com/Esri/geotrigger-go/geotrigger"
+	"github.com/Esri/geotrigger-go/geotrigger/geofence"
+	"github.com/Esri/geotrigger-go/geotrigger/geotriggertest"
+	"github.com/Esri/geotrigger-go/geotrigger/test"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSimulator(t *testing.T) {
+	server := geotriggertest.NewServer()
+	defer server.Close()
+	server.AddApplication("good_client_id", "good_client_secret")
+
+	// a fence around the middle point of the line
+	trigger := geotrigger.Trigger{
+		TriggerID: "middle",
+		Condition: geotrigger.Condition{
+			Direction: geotrigger.DirectionEnter,
+			Geo:       geotrigger.Geo{Latitude: 45.51, Longitude: -122.6, Distance: 200},
+		},
+		Action: geotrigger.Action{Message: "Halfway there."},
+		Tags:   []string{"walkers"},
+	}
+	engine, err := geofence.NewEngine([]geotrigger.Trigger{trigger})
+	test.Expect(t, err, nil)
+
+	clients, err := RegisterDevices(server.Environment(), "good_client_id", 2)
+	test.Expect(t, err, nil)
+	test.Expect(t, len(server.Devices()), 2)
+
+	start := time.Date(2014, 4, 22, 17, 30, 0, 0, time.UTC)
+	simulator := &Simulator{Interval: time.Minute, Start: start, Engine: engine}
+	for i, client := range clients {
+		defer client.Close()
+		// 5 m/s covers 300 meters a minute, about 8 minutes for the whole line
+		path, err := NewPath(line, 0, false)
+		test.Expect(t, err, nil)
+		device := &Device{Client: client, Route: path, Speed: 5}
+		// only the first device is tagged to match the trigger
+		if i == 0 {
+			device.Tags = []string{"walkers"}
+		}
+		simulator.Devices = append(simulator.Devices, device)
+	}
+
+	// one update fails, and is reported as such
+	server.AddFault(geotriggertest.Fault{Route: "location/update", Skip: 4, Status: 500})
+
+	report, err := simulator.Run(context.Background())
+	test.Expect(t, err, nil)
+
+	// the start, a report each minute, and one at the very end
+	test.Expect(t, report.Locations+len(report.Errors), 2*9)
+	test.Expect(t, len(report.Errors), 1)
+	test.Expect(t, strings.Contains(report.Errors[0].Error(), "Received status code 500"), true)
+
+	tagged := clients[0].Info()["device_id"]
+	test.Expect(t, len(report.Events), 1)
+	test.Expect(t, report.Events[0].DeviceID, tagged)
+	test.Expect(t, report.Events[0].Trigger.Action.Message, "Halfway there.")
+	test.Expect(t, report.Events[0].Location.Timestamp.After(start), true)
+
+	sent := server.Locations(tagged)
+	test.Expect(t, len(sent) >= 8, true)
+	test.Expect(t, sent[0].Timestamp.Equal(start), true)
+	test.Expect(t, sent[0].Accuracy, float64(10))
+
+	for _, device := range server.Devices() {
+		if device.DeviceID == tagged {
+			test.Expect(t, strings.Join(device.Tags, ","), "device:"+tagged+",walkers")
+		}
+	}
+}
+
+func TestSimulatorStops(t *testing.T) {
+	server := geotriggertest.NewServer()
+	defer server.Close()
+	server.AddApplication("good_client_id", "good_client_secret")
+
+	clients, err := RegisterDevices(server.Environment(), "good_client_id", 1)
+	test.Expect(t, err, nil)
+	defer clients[0].Close()
+
+	// a walk never ends, so the duration does
+	walk := NewRandomWalk(Point{45.5, -122.6}, 1000, 1)
+	simulator := &Simulator{
+		Devices:  []*Device{{Client: clients[0], Route: walk, Speed: 1.4}},
+		Interval: 10 * time.Second,
+		Duration: 5 * time.Minute,
+	}
+	report, err := simulator.Run(context.Background())
+	test.Expect(t, err, nil)
+	test.Expect(t, report.Locations, 31)
+
+	// or the context does, with the simulation paced in real time
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	simulator.Duration = 0
+	simulator.Speedup = 1
+	report, err = simulator.Run(ctx)
+	test.Expect(t, err, nil)
+	test.Expect(t, report.Locations, 1)
+	test.Expect(t, len(report.Errors), 0)
+
+	_, err = (&Simulator{}).Run(context.Background())
+	test.Expect(t, err.Error(), "No devices to simulate.")
+
+	_, err = (&Simulator{Devices: []*Device{{Route: walk}}}).Run(context.Background())
+	test.Expect(t, err.Error(), "Device 0 needs both a client and a route.")
+}
+
+func TestRegisterDevicesFails(t *testing.T) {
+	server := geotriggertest.NewServer()
+	defer server.Close()
+	server.AddApplication("good_client_id", "good_client_secret")
+	server.AddFault(geotriggertest.Fault{Route: "/sharing/oauth2/registerDevice", Skip: 1, Status: 500})
+
+	_, err := RegisterDevices(server.Environment(), "good_client_id", 3)
+	test.Expect(t, err.Error(), "Could not register device 2 of 3. Received status code 500 from /sharing/oauth2/registerDevice.")
+}
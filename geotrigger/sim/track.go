@@ -0,0 +1,329 @@
+package sim
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"github.com/Esri/geotrigger-go/geotrigger"
+	"github.com/Esri/geotrigger-go/geotrigger/geofence"
+	"github.com/Esri/geotrigger-go/geotrigger/json"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// UpdateParams returns the params for a `location/update` request reporting
+// the provided locations. As a map, they aren't checked before they're sent;
+// LocationUpdateParams returns params that are.
+func UpdateParams(locations []geotrigger.Location) map[string]interface{} {
+	return map[string]interface{}{"locations": locations}
+}
+
+// LocationUpdateParams returns the params for a `location/update` request
+// reporting the provided locations, which are validated before they're sent.
+func LocationUpdateParams(locations []geotrigger.Location) *geotrigger.LocationUpdateParams {
+	return &geotrigger.LocationUpdateParams{Locations: locations}
+}
+
+// ReadGPXTrack reads a recorded GPX track as locations. Every track point
+// needs a time. GPX has no accuracy, so every location gets the default of 10
+// meters.
+func ReadGPXTrack(r io.Reader) ([]geotrigger.Location, error) {
+	points, err := readGPX(r)
+	if err != nil {
+		return nil, err
+	}
+
+	locations := make([]geotrigger.Location, 0, len(points))
+	for i, point := range points {
+		if len(point.Time) == 0 {
+			return nil, fmt.Errorf("Track point %d has no time.", i+1)
+		}
+
+		timestamp, err := time.Parse(time.RFC3339Nano, strings.TrimSpace(point.Time))
+		if err != nil {
+			return nil, fmt.Errorf("Invalid time for track point %d: %s", i+1, point.Time)
+		}
+
+		locations = append(locations, geotrigger.Location{
+			Latitude:  point.Latitude,
+			Longitude: point.Longitude,
+			Accuracy:  defaultAccuracy,
+			Timestamp: timestamp,
+		})
+	}
+
+	return locations, nil
+}
+
+// ReadCSVTrack reads a recorded track from CSV. Without a header, the
+// columns are latitude, longitude, timestamp and, optionally, accuracy in
+// meters. With a header, they can come in any order, named `lat` or
+// `latitude`, `lng`, `lon` or `longitude`, `timestamp` or `time`, and
+// `accuracy`; other columns are ignored. Timestamps are ISO 8601, or epoch
+// seconds or milliseconds. A missing accuracy is taken to be 10 meters.
+func ReadCSVTrack(r io.Reader) ([]geotrigger.Location, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("Could not read CSV track. %s", err)
+	}
+	if len(records) == 0 {
+		return nil, errors.New("CSV track is empty.")
+	}
+
+	columns := map[string]int{"latitude": 0, "longitude": 1, "timestamp": 2, "accuracy": 3}
+	line := 1
+	if _, err := strconv.ParseFloat(records[0][0], 64); err != nil {
+		if columns, err = csvColumns(records[0]); err != nil {
+			return nil, err
+		}
+		records = records[1:]
+		line++
+	}
+
+	locations := make([]geotrigger.Location, 0, len(records))
+	for i, record := range records {
+		location, err := csvLocation(record, columns)
+		if err != nil {
+			return nil, fmt.Errorf("Error on line %d of CSV track. %s", line+i, err)
+		}
+		locations = append(locations, location)
+	}
+
+	return locations, nil
+}
+
+// csvColumns finds the columns of a track from a header row.
+func csvColumns(header []string) (map[string]int, error) {
+	names := map[string]string{
+		"lat":       "latitude",
+		"latitude":  "latitude",
+		"lng":       "longitude",
+		"lon":       "longitude",
+		"longitude": "longitude",
+		"time":      "timestamp",
+		"timestamp": "timestamp",
+		"accuracy":  "accuracy",
+	}
+
+	columns := make(map[string]int)
+	for i, name := range header {
+		if column, ok := names[strings.ToLower(strings.TrimSpace(name))]; ok {
+			columns[column] = i
+		}
+	}
+
+	for _, required := range []string{"latitude", "longitude", "timestamp"} {
+		if _, ok := columns[required]; !ok {
+			return nil, fmt.Errorf("CSV track header has no %s column.", required)
+		}
+	}
+
+	return columns, nil
+}
+
+func csvLocation(record []string, columns map[string]int) (geotrigger.Location, error) {
+	field := func(column string) (string, bool) {
+		index, ok := columns[column]
+		if !ok || index >= len(record) || len(strings.TrimSpace(record[index])) == 0 {
+			return "", false
+		}
+		return strings.TrimSpace(record[index]), true
+	}
+
+	location := geotrigger.Location{Accuracy: defaultAccuracy}
+	for _, column := range []string{"latitude", "longitude", "accuracy"} {
+		value, ok := field(column)
+		if !ok {
+			if column == "accuracy" {
+				continue
+			}
+			return location, fmt.Errorf("Missing %s.", column)
+		}
+
+		number, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return location, fmt.Errorf("Invalid %s: %s", column, value)
+		}
+
+		switch column {
+		case "latitude":
+			location.Latitude = number
+		case "longitude":
+			location.Longitude = number
+		case "accuracy":
+			location.Accuracy = number
+		}
+	}
+
+	value, ok := field("timestamp")
+	if !ok {
+		return location, errors.New("Missing timestamp.")
+	}
+
+	timestamp, err := parseTimestamp(value)
+	if err != nil {
+		return location, err
+	}
+	location.Timestamp = timestamp
+
+	return location, nil
+}
+
+// parseTimestamp reads a CSV timestamp as the json package reads one found in
+// JSON, with epoch numbers given as text.
+func parseTimestamp(value string) (time.Time, error) {
+	var timestamp interface{} = value
+	if number, err := strconv.ParseFloat(value, 64); err == nil {
+		timestamp = number
+	}
+
+	parsed, err := json.ParseTime(timestamp)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("Invalid timestamp: %s", value)
+	}
+
+	return parsed, nil
+}
+
+// LoadTrack reads a recorded track from a `.gpx` or `.csv` file.
+func LoadTrack(path string) ([]geotrigger.Location, error) {
+	read := ReadCSVTrack
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".gpx":
+		read = ReadGPXTrack
+	case ".csv":
+	default:
+		return nil, fmt.Errorf("Unknown track format: %s. Expected a .gpx or .csv file.", path)
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("Could not open track file. %s", err)
+	}
+	defer file.Close()
+
+	return read(file)
+}
+
+// ScaleTrack returns a copy of a track moved in time to begin at `start`,
+// with the time between locations divided by `speedup`: 2 replays a track in
+// half the time it took to record. A speedup of 0 or less keeps the time
+// between locations as recorded.
+func ScaleTrack(track []geotrigger.Location, start time.Time, speedup float64) []geotrigger.Location {
+	scaled := make([]geotrigger.Location, len(track))
+	if len(track) == 0 {
+		return scaled
+	}
+	if speedup <= 0 {
+		speedup = 1
+	}
+
+	first := track[0].Timestamp
+	for i, location := range track {
+		location.Timestamp = start.Add(time.Duration(float64(location.Timestamp.Sub(first)) / speedup))
+		scaled[i] = location
+	}
+
+	return scaled
+}
+
+// Replay sends a recorded track as the locations of a device, paced as they
+// were recorded, to reproduce what the Geotrigger Service made of them.
+type Replay struct {
+	// a device client
+	Client *geotrigger.Client
+	// locations in time order, as read by LoadTrack
+	Track []geotrigger.Location
+	// how much faster than recorded the track is sent: 1 is as recorded. If
+	// zero, locations are sent as fast as the service takes them.
+	Speedup float64
+	// if true, timestamps are moved to begin when the replay does (and scaled
+	// by Speedup), so the service treats the track as live. Otherwise the
+	// recorded timestamps are sent.
+	Retime bool
+	// locations per `location/update` request, 1 if zero
+	BatchSize int
+	// if set, fed every location sent, with the events it fires collected in
+	// the report. Tags are the device's tags, for matching triggers.
+	Engine *geofence.Engine
+	Tags   []string
+}
+
+// Run sends the track, until it is done or `ctx` is. As with Simulator.Run,
+// failed updates are collected in the report, and an error is only returned
+// if the replay could not be started.
+func (replay *Replay) Run(ctx context.Context) (*Report, error) {
+	if replay.Client == nil {
+		return nil, errors.New("Replay needs a client.")
+	}
+
+	deviceID := replay.Client.Info()["device_id"]
+	if len(deviceID) == 0 {
+		return nil, errors.New("Replay needs a device client.")
+	}
+
+	if len(replay.Track) == 0 {
+		return nil, errors.New("No locations to replay.")
+	}
+
+	batchSize := replay.BatchSize
+	if batchSize <= 0 {
+		batchSize = 1
+	}
+
+	report := &Report{}
+	began := time.Now()
+
+	sent := replay.Track
+	if replay.Retime {
+		sent = ScaleTrack(replay.Track, began, replay.Speedup)
+	}
+
+	first := replay.Track[0].Timestamp
+	for start := 0; start < len(sent); start += batchSize {
+		end := start + batchSize
+		if end > len(sent) {
+			end = len(sent)
+		}
+
+		// a batch goes when its last location is due
+		due := time.Duration(0)
+		if replay.Speedup > 0 {
+			due = time.Duration(float64(replay.Track[end-1].Timestamp.Sub(first)) / replay.Speedup)
+		}
+		if !waitUntil(ctx, began.Add(due)) {
+			break
+		}
+
+		batch := sent[start:end]
+		_, err := geotrigger.Do[locationUpdateResponse](ctx, replay.Client, "location/update", LocationUpdateParams(batch))
+		if ctx.Err() != nil {
+			break
+		}
+
+		if err != nil {
+			report.Errors = append(report.Errors,
+				fmt.Errorf("Location update for device %s failed. %s", deviceID, err))
+			continue
+		}
+
+		report.Locations += len(batch)
+		if replay.Engine != nil {
+			for _, location := range batch {
+				report.Events = append(report.Events, replay.Engine.Update(deviceID, replay.Tags, location)...)
+			}
+		}
+	}
+
+	report.Elapsed = time.Since(began)
+	return report, nil
+}
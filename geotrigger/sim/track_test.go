@@ -0,0 +1,169 @@
+package sim
+
+import (
+	"context"
+	"github.com/Esri/geotrigger-go/geotrigger"
+	"github.com/Esri/geotrigger-go/geotrigger/geofence"
+	"github.com/Esri/geotrigger-go/geotrigger/geotriggertest"
+	"github.com/Esri/geotrigger-go/geotrigger/test"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+var trackStart = time.Date(2014, 4, 22, 17, 30, 0, 0, time.UTC)
+
+func TestReadGPXTrack(t *testing.T) {
+	_, err := ReadGPXTrack(strings.NewReader(gpxRoute))
+	test.Expect(t, err.Error(), "Track point 3 has no time.")
+
+	track, err := ReadGPXTrack(strings.NewReader(`<gpx><trk><trkseg>
+		<trkpt lat="45.5" lon="-122.6"><time>2014-04-22T17:30:00Z</time></trkpt>
+		<trkpt lat="45.51" lon="-122.6"><time> 2014-04-22T17:40:00.5Z </time></trkpt>
+	</trkseg></trk></gpx>`))
+	test.Expect(t, err, nil)
+	test.Expect(t, len(track), 2)
+	test.Expect(t, track[0].Timestamp.Equal(trackStart), true)
+	test.Expect(t, track[1].Timestamp.Equal(trackStart.Add(10*time.Minute+500*time.Millisecond)), true)
+	test.Expect(t, track[1].Latitude, 45.51)
+	test.Expect(t, track[1].Accuracy, float64(10))
+
+	_, err = ReadGPXTrack(strings.NewReader(`<gpx><trk><trkseg><trkpt lat="45.5" lon="-122.6"><time>noon</time></trkpt></trkseg></trk></gpx>`))
+	test.Expect(t, err.Error(), "Invalid time for track point 1: noon")
+}
+
+func TestReadCSVTrack(t *testing.T) {
+	// no header: lat, lng, timestamp, accuracy
+	track, err := ReadCSVTrack(strings.NewReader("45.5,-122.6,2014-04-22T17:30:00Z,5\n45.51,-122.6,1398188400\n"))
+	test.Expect(t, err, nil)
+	test.Expect(t, len(track), 2)
+	test.Expect(t, track[0], geotrigger.Location{Latitude: 45.5, Longitude: -122.6, Accuracy: 5, Timestamp: trackStart})
+	test.Expect(t, track[1].Timestamp.Equal(trackStart.Add(10*time.Minute)), true)
+	test.Expect(t, track[1].Accuracy, float64(10))
+
+	// a header, in any order, with extra columns and epoch milliseconds
+	track, err = ReadCSVTrack(strings.NewReader("Time, Speed, Lon, Lat\n1398187800000, 3, -122.6, 45.5\n2014-04-22 17:31:00, 3, -122.61, 45.51\n"))
+	test.Expect(t, err, nil)
+	test.Expect(t, track[0], geotrigger.Location{Latitude: 45.5, Longitude: -122.6, Accuracy: 10, Timestamp: trackStart})
+	test.Expect(t, track[1].Longitude, -122.61)
+	test.Expect(t, track[1].Timestamp.Equal(trackStart.Add(time.Minute)), true)
+
+	_, err = ReadCSVTrack(strings.NewReader("lat,lng\n45.5,-122.6\n"))
+	test.Expect(t, err.Error(), "CSV track header has no timestamp column.")
+
+	_, err = ReadCSVTrack(strings.NewReader("lat,lng,time\n45.5,-122.6,noon\n"))
+	test.Expect(t, err.Error(), "Error on line 2 of CSV track. Invalid timestamp: noon")
+
+	_, err = ReadCSVTrack(strings.NewReader("45.5,-122.6,1398187800\n45.5,west,1398187800\n"))
+	test.Expect(t, err.Error(), "Error on line 2 of CSV track. Invalid longitude: west")
+
+	_, err = ReadCSVTrack(strings.NewReader("45.5,,1398187800\n"))
+	test.Expect(t, err.Error(), "Error on line 1 of CSV track. Missing longitude.")
+
+	_, err = ReadCSVTrack(strings.NewReader(""))
+	test.Expect(t, err.Error(), "CSV track is empty.")
+}
+
+func TestLoadTrack(t *testing.T) {
+	dir, err := ioutil.TempDir("", "geotrigger-sim")
+	test.Expect(t, err, nil)
+	defer os.RemoveAll(dir)
+
+	csvPath := filepath.Join(dir, "track.csv")
+	test.Expect(t, ioutil.WriteFile(csvPath, []byte("45.5,-122.6,1398187800\n"), 0600), nil)
+	track, err := LoadTrack(csvPath)
+	test.Expect(t, err, nil)
+	test.Expect(t, len(track), 1)
+
+	_, err = LoadTrack(filepath.Join(dir, "track.kml"))
+	test.Expect(t, strings.Index(err.Error(), "Unknown track format: "), 0)
+}
+
+func TestUpdateParams(t *testing.T) {
+	track := []geotrigger.Location{{Latitude: 45.5, Longitude: -122.6, Accuracy: 10, Timestamp: trackStart}}
+
+	test.Expect(t, UpdateParams(track), map[string]interface{}{"locations": track})
+	params := LocationUpdateParams(track)
+	test.Expect(t, params.Locations, track)
+	test.Expect(t, params.Validate(), nil)
+}
+
+func TestScaleTrack(t *testing.T) {
+	track := []geotrigger.Location{
+		{Latitude: 45.5, Timestamp: trackStart},
+		{Latitude: 45.51, Timestamp: trackStart.Add(10 * time.Minute)},
+	}
+
+	now := time.Now()
+	scaled := ScaleTrack(track, now, 60)
+	test.Expect(t, scaled[0].Timestamp.Equal(now), true)
+	test.Expect(t, scaled[1].Timestamp.Equal(now.Add(10*time.Second)), true)
+	test.Expect(t, scaled[1].Latitude, 45.51)
+	// the original is left alone
+	test.Expect(t, track[1].Timestamp.Equal(trackStart.Add(10*time.Minute)), true)
+
+	// without a speedup, the time between locations is kept
+	scaled = ScaleTrack(track, now, 0)
+	test.Expect(t, scaled[1].Timestamp.Equal(now.Add(10*time.Minute)), true)
+}
+
+func TestReplay(t *testing.T) {
+	server := geotriggertest.NewServer()
+	defer server.Close()
+	server.AddApplication("good_client_id", "good_client_secret")
+
+	clients, err := RegisterDevices(server.Environment(), "good_client_id", 1)
+	test.Expect(t, err, nil)
+	client := clients[0]
+	defer client.Close()
+	deviceID := client.Info()["device_id"]
+
+	engine, err := geofence.NewEngine([]geotrigger.Trigger{{
+		TriggerID: "middle",
+		Condition: geotrigger.Condition{
+			Direction: geotrigger.DirectionEnter,
+			Geo:       geotrigger.Geo{Latitude: 45.51, Longitude: -122.6, Distance: 200},
+		},
+		Tags: []string{"device:" + deviceID},
+	}})
+	test.Expect(t, err, nil)
+
+	// three locations a minute apart, sent a minute a millisecond
+	track := []geotrigger.Location{
+		{Latitude: 45.50, Longitude: -122.6, Accuracy: 5, Timestamp: trackStart},
+		{Latitude: 45.51, Longitude: -122.6, Accuracy: 5, Timestamp: trackStart.Add(time.Minute)},
+		{Latitude: 45.52, Longitude: -122.6, Accuracy: 5, Timestamp: trackStart.Add(2 * time.Minute)},
+	}
+	replay := &Replay{Client: client, Track: track, Speedup: 60000, BatchSize: 2, Engine: engine}
+	report, err := replay.Run(context.Background())
+	test.Expect(t, err, nil)
+	test.Expect(t, report.Locations, 3)
+	test.Expect(t, len(report.Errors), 0)
+	test.Expect(t, len(report.Events), 1)
+	test.Expect(t, report.Events[0].Location.Timestamp.Equal(trackStart.Add(time.Minute)), true)
+	test.Expect(t, report.Elapsed >= 2*time.Millisecond, true)
+
+	sent := server.Locations(deviceID)
+	test.Expect(t, len(sent), 3)
+	test.Expect(t, sent[2].Timestamp.Equal(track[2].Timestamp), true)
+
+	// retimed, the track starts now
+	before := time.Now()
+	server.AddFault(geotriggertest.Fault{Route: "location/update", Status: 500})
+	replay = &Replay{Client: client, Track: track, Retime: true}
+	report, err = replay.Run(context.Background())
+	test.Expect(t, err, nil)
+	test.Expect(t, report.Locations, 2)
+	test.Expect(t, len(report.Errors), 1)
+
+	sent = server.Locations(deviceID)
+	test.Expect(t, len(sent), 5)
+	test.Expect(t, sent[3].Timestamp.Sub(before) < time.Minute+time.Second, true)
+	test.Expect(t, sent[4].Timestamp.Sub(sent[3].Timestamp), time.Minute)
+
+	_, err = (&Replay{Client: client}).Run(context.Background())
+	test.Expect(t, err.Error(), "No locations to replay.")
+}
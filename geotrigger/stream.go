@@ -0,0 +1,134 @@
+package geotrigger
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+)
+
+// Stream performs an API request like Do, but rather than holding the whole
+// response in memory, decodes the records in the array found at `key` in the
+// response one at a time, handing each to `handle`. This suits large exports
+// such as `device/locations` or `trigger/history`. An empty `key` means the
+// response itself is the array. Any other values in the response are skipped.
+//
+// Returning an error from `handle` stops reading the response, and that error
+// is returned from Stream. Records handled before an error are not rolled
+// back, so `handle` may have seen part of a response that then fails.
+//
+// Streamed responses are read as they arrive even with a ResponseCache, and
+// are never shared with identical requests in flight (see flight.go), so
+// neither holds the whole export in memory.
+func Stream[Record any](ctx context.Context, client *Client, route string, params interface{}, key string,
+	handle func(Record) error) error {
+	ctx = context.WithValue(ctx, streamingKey{}, true)
+	return client.request(ctx, route, params, streamDecoder(key, func(path string, decoder *json.Decoder) error {
+		var raw json.RawMessage
+		if err := decoder.Decode(&raw); err != nil {
+			return fmt.Errorf("Error parsing record from %s. %s", path, err)
+		}
+
+		var record Record
+		if err := json.Unmarshal(raw, &record); err != nil {
+			return fmt.Errorf("Error parsing record from %s. %s", path, err)
+		}
+		fillExtras(reflect.ValueOf(&record), raw)
+
+		return handle(record)
+	}))
+}
+
+// streamDecoder walks the response with a json.Decoder, calling `next` with
+// the decoder positioned at each element of the array found at `key`.
+func streamDecoder(key string, next func(string, *json.Decoder) error) responseDecoder {
+	return func(path string, body io.Reader) (*errorResponse, error) {
+		decoder := json.NewDecoder(body)
+
+		token, err := decoder.Token()
+		if err != nil {
+			return nil, fmt.Errorf("Error parsing response from %s. %s", path, err)
+		}
+
+		if token == json.Delim('[') && len(key) == 0 {
+			return nil, streamElements(path, decoder, next)
+		}
+
+		if token != json.Delim('{') {
+			return nil, fmt.Errorf("Error parsing response from %s. Unexpected %v at the start of the response.",
+				path, token)
+		}
+
+		found := false
+		for decoder.More() {
+			token, err := decoder.Token()
+			if err != nil {
+				return nil, fmt.Errorf("Error parsing response from %s. %s", path, err)
+			}
+
+			switch name, _ := token.(string); {
+			case name == "error":
+				var errResponse errorResponse
+				if err := decoder.Decode(&errResponse.Error); err != nil {
+					return nil, fmt.Errorf("Error parsing response from %s. %s", path, err)
+				}
+				// as with errorCheck, only a message marks a real error
+				if len(errResponse.Error.Message) > 0 {
+					return &errResponse, nil
+				}
+			case name == key:
+				found = true
+				if err := streamArray(path, decoder, next); err != nil {
+					return nil, err
+				}
+			default:
+				var skipped json.RawMessage
+				if err := decoder.Decode(&skipped); err != nil {
+					return nil, fmt.Errorf("Error parsing response from %s. %s", path, err)
+				}
+			}
+		}
+
+		if !found {
+			return nil, fmt.Errorf("No value found for key %s in response from %s.", key, path)
+		}
+
+		return nil, nil
+	}
+}
+
+// streamArray reads the array the decoder is positioned at, treating null as
+// an empty array.
+func streamArray(path string, decoder *json.Decoder, next func(string, *json.Decoder) error) error {
+	token, err := decoder.Token()
+	if err != nil {
+		return fmt.Errorf("Error parsing response from %s. %s", path, err)
+	}
+
+	if token == nil {
+		return nil
+	}
+
+	if token != json.Delim('[') {
+		return fmt.Errorf("Error parsing response from %s. Expected an array, got: %v", path, token)
+	}
+
+	return streamElements(path, decoder, next)
+}
+
+// streamElements calls `next` for each element of an array whose opening
+// bracket has already been read, then reads the closing bracket.
+func streamElements(path string, decoder *json.Decoder, next func(string, *json.Decoder) error) error {
+	for decoder.More() {
+		if err := next(path, decoder); err != nil {
+			return err
+		}
+	}
+
+	if _, err := decoder.Token(); err != nil {
+		return fmt.Errorf("Error parsing response from %s. %s", path, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,113 @@
+package geotrigger
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/Esri/geotrigger-go/geotrigger/test"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func streamTestClient(t *testing.T, env Environment, responses map[string]string) (*Client, func()) {
+	gtServer := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, r *http.Request) {
+		response, ok := responses[r.URL.Path]
+		test.Expect(t, ok, true)
+		fmt.Fprint(res, response)
+	}))
+
+	env.GeotriggerURL = gtServer.URL
+	client := ExistingDeviceWithEnvironment(env, "good_client_id", "device_id", "good_access_token",
+		1800, "good_refresh_token")
+
+	return client, gtServer.Close
+}
+
+func TestStream(t *testing.T) {
+	client, done := streamTestClient(t, Environment{}, map[string]string{
+		"/device/locations": `{"deviceId":"derp","locations":[{"latitude":45.5,"longitude":-122.6},{"latitude":45.6,"longitude":-122.7}],"boundingBox":{"xmin":-122.7}}`,
+		"/empty":            `{"locations":null}`,
+		"/array":            `[{"latitude":1,"longitude":2}]`,
+		"/missing":          `{"deviceId":"derp"}`,
+		"/error":            `{"error":{"code":400,"message":"Invalid parameters."}}`,
+		"/broken":           `{"locations":[{"latitude":"north"}]}`,
+	})
+	defer done()
+	ctx := context.Background()
+
+	var locations []Location
+	err := Stream(ctx, client, "device/locations", nil, "locations", func(location Location) error {
+		locations = append(locations, location)
+		return nil
+	})
+	test.Expect(t, err, nil)
+	test.Expect(t, len(locations), 2)
+	test.Expect(t, locations[1].Latitude, 45.6)
+
+	count := 0
+	countRecords := func(record map[string]interface{}) error {
+		count++
+		return nil
+	}
+
+	err = Stream(ctx, client, "empty", nil, "locations", countRecords)
+	test.Expect(t, err, nil)
+	test.Expect(t, count, 0)
+
+	err = Stream(ctx, client, "array", nil, "", countRecords)
+	test.Expect(t, err, nil)
+	test.Expect(t, count, 1)
+
+	err = Stream(ctx, client, "missing", nil, "locations", countRecords)
+	test.Expect(t, err.Error(), "No value found for key locations in response from /missing.")
+
+	err = Stream(ctx, client, "error", nil, "locations", countRecords)
+	test.Expect(t, err.Error(), "Error from /error, code: 400. Message: Invalid parameters.")
+
+	err = Stream(ctx, client, "broken", nil, "locations", func(location Location) error {
+		return nil
+	})
+	test.Expect(t, strings.Index(err.Error(), "Error parsing record from /broken."), 0)
+
+	// the handler can stop the stream early
+	stop := errors.New("Seen enough.")
+	count = 0
+	err = Stream(ctx, client, "device/locations", nil, "locations", func(location Location) error {
+		count++
+		return stop
+	})
+	test.Expect(t, err, stop)
+	test.Expect(t, count, 1)
+}
+
+func TestMaxResponseSize(t *testing.T) {
+	locations := `{"locations":[{"latitude":45.5,"longitude":-122.6},{"latitude":45.6,"longitude":-122.7}]}`
+	client, done := streamTestClient(t, Environment{MaxResponseSize: int64(len(locations))}, map[string]string{
+		"/fits":   locations,
+		"/toobig": locations + " ",
+	})
+	defer done()
+
+	var response map[string]interface{}
+	err := client.Request("fits", nil, &response)
+	test.Expect(t, err, nil)
+
+	err = client.Request("toobig", nil, &response)
+	test.Expect(t, err.Error(), "Could not read response body from /toobig. Response from /toobig exceeded the maximum size of 89 bytes.")
+
+	// a streamed response fails once it goes past the limit, as it is read
+	client, done = streamTestClient(t, Environment{MaxResponseSize: 60}, map[string]string{
+		"/device/locations": locations,
+	})
+	defer done()
+
+	count := 0
+	err = Stream(context.Background(), client, "device/locations", nil, "locations", func(location Location) error {
+		count++
+		return nil
+	})
+	test.Expect(t, err.Error(), "Error parsing record from /device/locations. Response from /device/locations exceeded the maximum size of 60 bytes.")
+	test.Expect(t, count, 1)
+}
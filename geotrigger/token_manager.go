@@ -2,6 +2,7 @@ package geotrigger
 
 import (
 	"fmt"
+	"sync"
 	"time"
 )
 
@@ -18,13 +19,18 @@ type tokenManager interface {
 	// used safely when refreshing the access token
 	setAccessToken(string)
 	setExpiresAt(int64)
+	// ends the manageTokens() routine. later token requests get a nil response
+	stop()
 }
 
 type tknManager struct {
 	tokenRequests chan *tokenRequest
-	accessToken   string
-	refreshToken  string
-	expiresAt     int64
+	// closed once manageTokens() has returned
+	stopped      chan struct{}
+	stopOnce     sync.Once
+	accessToken  string
+	refreshToken string
+	expiresAt    int64
 }
 
 /* consts and structs for channel coordination */
@@ -33,6 +39,7 @@ const (
 	refreshNeeded
 	refreshComplete
 	refreshFailed
+	stopManaging
 )
 
 type tokenRequest struct {
@@ -48,6 +55,7 @@ type tokenResponse struct {
 func newTokenManager(accessToken string, refreshToken string, expiresIn int64) tokenManager {
 	tm := &tknManager{
 		tokenRequests: make(chan *tokenRequest),
+		stopped:       make(chan struct{}),
 		accessToken:   accessToken,
 		refreshToken:  refreshToken,
 	}
@@ -69,7 +77,17 @@ func newTokenRequest(purpose int, makeChan bool) *tokenRequest {
 }
 
 func (tm *tknManager) tokenRequest(tr *tokenRequest) {
-	tm.tokenRequests <- tr
+	select {
+	case tm.tokenRequests <- tr:
+	case <-tm.stopped:
+		tokenDenied(tr)
+	}
+}
+
+func (tm *tknManager) stop() {
+	tm.stopOnce.Do(func() {
+		tm.tokenRequest(newTokenRequest(stopManaging, false))
+	})
 }
 
 func (tm *tknManager) getAccessToken() string {
@@ -99,6 +117,12 @@ func (tm *tknManager) manageTokens() {
 		tr := <-tm.tokenRequests
 
 		switch {
+		case tr.purpose == stopManaging:
+			close(tm.stopped)
+			for _, waitingReq := range waitingRequests {
+				go tokenDenied(waitingReq)
+			}
+			return
 		case tr.purpose == refreshFailed:
 			if len(waitingRequests) > 0 {
 				nextRequest := waitingRequests[0]
@@ -136,13 +160,18 @@ func (tm *tknManager) manageTokens() {
 				go tokenApproved(tr, tm.accessToken, true)
 			}
 		default:
-			go func() {
-				tr.tokenResponses <- nil
-			}()
+			go tokenDenied(tr)
 		}
 	}
 }
 
+// tokenDenied answers a request with nil, for when there is no token to give.
+func tokenDenied(tr *tokenRequest) {
+	if tr.tokenResponses != nil {
+		tr.tokenResponses <- nil
+	}
+}
+
 func tokenApproved(tr *tokenRequest, token string, isAccessToken bool) {
 	tr.tokenResponses <- &tokenResponse{
 		token:         token,
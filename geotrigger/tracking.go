@@ -0,0 +1,67 @@
+package geotrigger
+
+import (
+	"context"
+)
+
+// Tracking profiles, for `DeviceUpdateParams.TrackingProfile` and
+// `Action.TrackingProfile`. A profile sets how often, and how precisely, a
+// device reports its location: from not at all, through battery-friendly
+// rough tracking, to fine tracking for when every fence crossing counts.
+// Adaptive lets the device choose, by how fast it is moving.
+const (
+	TrackingOff      = "off"
+	TrackingAdaptive = "adaptive"
+	TrackingRough    = "rough"
+	TrackingFine     = "fine"
+)
+
+// Device models a device as returned by `device/list` and `device/update`.
+// Fields the service adds later are kept in Extra.
+type Device struct {
+	DeviceID        string                 `json:"deviceId"`
+	Tags            []string               `json:"tags"`
+	Properties      map[string]interface{} `json:"properties,omitempty"`
+	TrackingProfile string                 `json:"trackingProfile,omitempty"`
+	Extra           map[string]interface{} `json:"-"`
+}
+
+type devicesResponse struct {
+	Devices []Device `json:"devices"`
+}
+
+// TrackingAction returns an action switching the device that fired the
+// trigger to a tracking profile, such as TrackingFine on entering a venue.
+func TrackingAction(profile string) Action {
+	return Action{TrackingProfile: profile}
+}
+
+// SetTrackingProfile switches every device with any of the tags to a
+// tracking profile, in a single `device/update`, and returns the devices
+// that were switched. A device client can leave out the tags to switch
+// itself.
+func (client *Client) SetTrackingProfile(ctx context.Context, profile string, tags ...string) ([]Device, error) {
+	if len(profile) == 0 {
+		// an empty profile would be left out, and change nothing
+		var v validation
+		v.add("trackingProfile", "Missing.")
+		return nil, v.result("params for device/update")
+	}
+
+	params := &DeviceUpdateParams{Tags: tags, TrackingProfile: profile}
+	response, err := Do[devicesResponse](ctx, client, "device/update", params)
+	if err != nil {
+		return nil, err
+	}
+
+	return response.Devices, nil
+}
+
+func validateTrackingProfile(v *validation, field string, profile string) {
+	switch profile {
+	case "", TrackingOff, TrackingAdaptive, TrackingRough, TrackingFine:
+	default:
+		v.add(field, "Must be %s, %s, %s or %s, not %s.",
+			TrackingOff, TrackingAdaptive, TrackingRough, TrackingFine, profile)
+	}
+}
@@ -0,0 +1,61 @@
+package geotrigger
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/Esri/geotrigger-go/geotrigger/test"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestSetTrackingProfile(t *testing.T) {
+	requests := 0
+	var params map[string]interface{}
+	gtServer := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, r *http.Request) {
+		requests++
+		test.Expect(t, r.URL.Path, "/device/update")
+		body, _ := ioutil.ReadAll(r.Body)
+		test.Expect(t, json.Unmarshal(body, &params), nil)
+		res.Write([]byte(`{"devices":[{"deviceId":"dev1","tags":["device:dev1","staff"],"trackingProfile":"fine","lastSeen":"2014-05-14T18:00:00Z"}]}`))
+	}))
+	defer gtServer.Close()
+
+	client := ExistingDeviceWithEnvironment(Environment{GeotriggerURL: gtServer.URL}, "good_client_id",
+		"device_id", "good_access_token", 1800, "good_refresh_token")
+	ctx := context.Background()
+
+	devices, err := client.SetTrackingProfile(ctx, TrackingFine, "staff", "volunteers")
+	test.Expect(t, err, nil)
+	test.Expect(t, params, map[string]interface{}{
+		"tags":            []interface{}{"staff", "volunteers"},
+		"trackingProfile": "fine",
+	})
+	test.Expect(t, len(devices), 1)
+	test.Expect(t, devices[0].DeviceID, "dev1")
+	test.Expect(t, devices[0].TrackingProfile, TrackingFine)
+	test.Expect(t, devices[0].Extra["lastSeen"], "2014-05-14T18:00:00Z")
+
+	_, err = client.SetTrackingProfile(ctx, "")
+	test.Refute(t, err, nil)
+	test.Expect(t, err.Error(), "Invalid params for device/update. trackingProfile: Missing.")
+
+	_, err = client.SetTrackingProfile(ctx, "precise", "staff")
+	test.Refute(t, err, nil)
+	test.Expect(t, err.Error(), "Invalid params for device/update. trackingProfile: Must be off, adaptive, rough or fine, not precise.")
+	test.Expect(t, requests, 1)
+}
+
+func TestTrackingAction(t *testing.T) {
+	trigger := &Trigger{
+		Condition: Condition{Direction: DirectionEnter, Geo: Geo{Geocode: "Portland, OR"}},
+		Action:    TrackingAction(TrackingRough),
+	}
+	test.Expect(t, trigger.Validate(), nil)
+
+	trigger.Action = TrackingAction("everything")
+	err := trigger.Validate()
+	test.Refute(t, err, nil)
+	test.Expect(t, err.Error(), "Invalid trigger. action.trackingProfile: Must be off, adaptive, rough or fine, not everything.")
+}
@@ -0,0 +1,164 @@
+package geotrigger
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Trigger directions, used for `Condition.Direction`.
+const (
+	DirectionEnter = "enter"
+	DirectionLeave = "leave"
+)
+
+// Trigger models a trigger as returned by `trigger/list`. Fields the service
+// returns that aren't modeled here end up in `Extra`, here and on the nested
+// Condition, Geo and Action.
+type Trigger struct {
+	TriggerID  string                 `json:"triggerId,omitempty"`
+	Condition  Condition              `json:"condition"`
+	Action     Action                 `json:"action"`
+	Tags       []string               `json:"tags,omitempty"`
+	Properties map[string]interface{} `json:"properties,omitempty"`
+	Times      int                    `json:"times,omitempty"`
+	RateLimit  int                    `json:"rateLimit,omitempty"`
+	Extra      map[string]interface{} `json:"-"`
+}
+
+// TriggerCreateParams are the params for `trigger/create`. They are the
+// fields of a trigger, except that `trigger/create` sets its tags from
+// `setTags`, and ignores `tags`.
+type TriggerCreateParams struct {
+	TriggerID  string                 `json:"triggerId,omitempty"`
+	Condition  Condition              `json:"condition"`
+	Action     Action                 `json:"action"`
+	SetTags    []string               `json:"setTags,omitempty"`
+	Properties map[string]interface{} `json:"properties,omitempty"`
+	Times      int                    `json:"times,omitempty"`
+	RateLimit  int                    `json:"rateLimit,omitempty"`
+}
+
+// CreateParams returns the `trigger/create` params that create the trigger,
+// tags included.
+func (trigger *Trigger) CreateParams() *TriggerCreateParams {
+	return &TriggerCreateParams{
+		TriggerID:  trigger.TriggerID,
+		Condition:  trigger.Condition,
+		Action:     trigger.Action,
+		SetTags:    trigger.Tags,
+		Properties: trigger.Properties,
+		Times:      trigger.Times,
+		RateLimit:  trigger.RateLimit,
+	}
+}
+
+// Condition describes when a trigger fires: the direction of travel across the
+// fence, the fence itself, and an optional time window.
+type Condition struct {
+	Direction     string                 `json:"direction"`
+	Geo           Geo                    `json:"geo"`
+	FromTimestamp *time.Time             `json:"fromTimestamp,omitempty"`
+	ToTimestamp   *time.Time             `json:"toTimestamp,omitempty"`
+	Extra         map[string]interface{} `json:"-"`
+}
+
+// Geo is the fence of a trigger. Exactly one of the following should be set:
+// a circle (`Latitude`, `Longitude` and `Distance` in meters), a `GeoJSON`
+// geometry, an `EsriJSON` geometry, or a `Geocode` with an optional
+// `DriveTime` in seconds.
+type Geo struct {
+	Latitude  float64                `json:"latitude"`
+	Longitude float64                `json:"longitude"`
+	Distance  float64                `json:"distance,omitempty"`
+	GeoJSON   *GeoJSON               `json:"geojson,omitempty"`
+	EsriJSON  *EsriJSON              `json:"esrijson,omitempty"`
+	Geocode   string                 `json:"geocode,omitempty"`
+	DriveTime int                    `json:"driveTime,omitempty"`
+	Extra     map[string]interface{} `json:"-"`
+}
+
+// GeoJSON is a GeoJSON geometry. The service only works with `Polygon` and
+// `MultiPolygon` geometries. `Coordinates` is kept as raw JSON, as its shape
+// depends on `Type`; use `Polygons` to read it.
+type GeoJSON struct {
+	Type        string          `json:"type"`
+	Coordinates json.RawMessage `json:"coordinates"`
+}
+
+// EsriJSON is an Esri polygon geometry.
+type EsriJSON struct {
+	Rings            [][][]float64     `json:"rings"`
+	SpatialReference *SpatialReference `json:"spatialReference,omitempty"`
+}
+
+// SpatialReference identifies the coordinate system of an Esri geometry.
+type SpatialReference struct {
+	WKID       int `json:"wkid,omitempty"`
+	LatestWKID int `json:"latestWkid,omitempty"`
+}
+
+// Action describes what happens when a trigger fires.
+type Action struct {
+	Message         string                 `json:"message,omitempty"`
+	CallbackURL     string                 `json:"callbackUrl,omitempty"`
+	Notification    *Notification          `json:"notification,omitempty"`
+	TrackingProfile string                 `json:"trackingProfile,omitempty"`
+	Extra           map[string]interface{} `json:"-"`
+}
+
+// Notification is a push notification sent to a device when a trigger fires.
+type Notification struct {
+	Text  string                 `json:"text,omitempty"`
+	URL   string                 `json:"url,omitempty"`
+	Sound string                 `json:"sound,omitempty"`
+	Icon  string                 `json:"icon,omitempty"`
+	Data  map[string]interface{} `json:"data,omitempty"`
+}
+
+// MarshalJSON writes the center of a circle even where it is 0, on the
+// equator or the prime meridian, and leaves it out of other fences.
+func (geo Geo) MarshalJSON() ([]byte, error) {
+	type plain Geo
+	fence := struct {
+		Latitude  *float64 `json:"latitude,omitempty"`
+		Longitude *float64 `json:"longitude,omitempty"`
+		plain
+	}{plain: plain(geo)}
+
+	if geo.Distance != 0 || geo.Latitude != 0 || geo.Longitude != 0 {
+		fence.Latitude, fence.Longitude = &geo.Latitude, &geo.Longitude
+	}
+
+	return json.Marshal(fence)
+}
+
+// IsCircle reports whether the fence is described by a center point and radius.
+func (geo *Geo) IsCircle() bool {
+	return geo.Distance > 0
+}
+
+// Polygons returns the rings of a `Polygon` or `MultiPolygon` geometry, as a
+// list of polygons, each a list of rings, each a list of [longitude, latitude]
+// positions.
+func (geojson *GeoJSON) Polygons() ([][][][]float64, error) {
+	switch geojson.Type {
+	case "Polygon":
+		var polygon [][][]float64
+		if err := json.Unmarshal(geojson.Coordinates, &polygon); err != nil {
+			return nil, fmt.Errorf("Could not read Polygon coordinates. %s", err)
+		}
+		return [][][][]float64{polygon}, nil
+	case "MultiPolygon":
+		var polygons [][][][]float64
+		if err := json.Unmarshal(geojson.Coordinates, &polygons); err != nil {
+			return nil, fmt.Errorf("Could not read MultiPolygon coordinates. %s", err)
+		}
+		return polygons, nil
+	case "":
+		return nil, errors.New("GeoJSON geometry is missing a type.")
+	}
+
+	return nil, fmt.Errorf("Unsupported GeoJSON geometry type: %s.", geojson.Type)
+}
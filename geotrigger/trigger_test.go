@@ -0,0 +1,57 @@
+package geotrigger
+
+import (
+	"encoding/json"
+	"github.com/Esri/geotrigger-go/geotrigger/test"
+	"testing"
+)
+
+func TestTriggerJSON(t *testing.T) {
+	data := []byte(`{"triggerId":"abc","condition":{"direction":"leave","geo":{"latitude":45.5,"longitude":-122.6,"distance":50},"fromTimestamp":"2014-01-01T00:00:00Z"},"action":{"callbackUrl":"http://pdx.gov/bye","notification":{"text":"bye","data":{"a":1}}},"tags":["derp"],"properties":{"color":"red"}}`)
+
+	var trigger Trigger
+	err := json.Unmarshal(data, &trigger)
+	test.Expect(t, err, nil)
+	test.Expect(t, trigger.TriggerID, "abc")
+	test.Expect(t, trigger.Condition.Direction, DirectionLeave)
+	test.Expect(t, trigger.Condition.Geo.IsCircle(), true)
+	test.Expect(t, trigger.Condition.FromTimestamp.Year(), 2014)
+	test.Expect(t, trigger.Condition.ToTimestamp, nil)
+	test.Expect(t, trigger.Action.CallbackURL, "http://pdx.gov/bye")
+	test.Expect(t, trigger.Action.Notification.Text, "bye")
+	test.Expect(t, trigger.Properties["color"], "red")
+
+	// a circle's center is written even when it is 0, other fences have none
+	raw, err := json.Marshal(Geo{Latitude: 0, Longitude: 0, Distance: 100})
+	test.Expect(t, err, nil)
+	test.Expect(t, string(raw), `{"latitude":0,"longitude":0,"distance":100}`)
+	raw, err = json.Marshal(&Condition{Direction: DirectionEnter, Geo: Geo{Geocode: "Portland"}})
+	test.Expect(t, err, nil)
+	test.Expect(t, string(raw), `{"direction":"enter","geo":{"geocode":"Portland"}}`)
+}
+
+func TestGeoJSONPolygons(t *testing.T) {
+	polygon := &GeoJSON{
+		Type:        "Polygon",
+		Coordinates: json.RawMessage(`[[[0,0],[1,0],[1,1],[0,0]]]`),
+	}
+	polygons, err := polygon.Polygons()
+	test.Expect(t, err, nil)
+	test.Expect(t, len(polygons), 1)
+	test.Expect(t, polygons[0][0][1], []float64{1, 0})
+
+	multi := &GeoJSON{
+		Type:        "MultiPolygon",
+		Coordinates: json.RawMessage(`[[[[0,0],[1,0],[1,1],[0,0]]],[[[5,5],[6,5],[6,6],[5,5]]]]`),
+	}
+	polygons, err = multi.Polygons()
+	test.Expect(t, err, nil)
+	test.Expect(t, len(polygons), 2)
+
+	bad := &GeoJSON{Type: "Polygon", Coordinates: json.RawMessage(`[1, 2]`)}
+	_, err = bad.Polygons()
+	test.Refute(t, err, nil)
+
+	_, err = (&GeoJSON{}).Polygons()
+	test.Expect(t, err.Error(), "GeoJSON geometry is missing a type.")
+}
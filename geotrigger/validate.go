@@ -0,0 +1,394 @@
+package geotrigger
+
+import (
+	"fmt"
+	"net/url"
+	"reflect"
+	"strings"
+)
+
+// Validator is implemented by params that can check themselves before they
+// are sent. Client.Request, Do and Stream call Validate on params that
+// implement it, passed by value or by pointer, and return its error without
+// making a request. Params built as maps aren't checked.
+//
+// The params types of this package are only checked when sent to the route
+// they describe, such as TriggerCreateParams to `trigger/create`; sent
+// anywhere else, they are left to the service. A Trigger sent to
+// `trigger/create` is refused if it has tags, which the service would drop.
+type Validator interface {
+	Validate() error
+}
+
+// validatedRoutes are the routes this package's params types are checked for.
+var validatedRoutes = map[reflect.Type]string{
+	reflect.TypeOf(TriggerCreateParams{}):  "trigger/create",
+	reflect.TypeOf(Trigger{}):              "trigger/create",
+	reflect.TypeOf(LocationUpdateParams{}): "location/update",
+	reflect.TypeOf(DeviceUpdateParams{}):   "device/update",
+	reflect.TypeOf(HistoryParams{}):        "trigger/history",
+	reflect.TypeOf(RunParams{}):            "trigger/run",
+}
+
+// FieldError is a problem with a single field of a request's params, named by
+// its path in the JSON sent, such as `condition.geo.latitude` or
+// `locations[2].timestamp`. An empty Field is a problem with the params as a
+// whole.
+type FieldError struct {
+	Field   string
+	Message string
+}
+
+func (fieldError FieldError) Error() string {
+	if len(fieldError.Field) == 0 {
+		return fieldError.Message
+	}
+
+	return fieldError.Field + ": " + fieldError.Message
+}
+
+// ValidationError lists every problem found with a request's params before
+// the request was made. Use `errors.As` to get at the individual fields.
+type ValidationError struct {
+	// what was checked, such as `trigger`, or `params for location/update`
+	Subject string
+	Fields  []FieldError
+}
+
+func (validationError *ValidationError) Error() string {
+	messages := make([]string, len(validationError.Fields))
+	for i, fieldError := range validationError.Fields {
+		messages[i] = fieldError.Error()
+	}
+
+	return fmt.Sprintf("Invalid %s. %s", validationError.Subject, strings.Join(messages, " "))
+}
+
+// validation collects field errors as params are checked.
+type validation struct {
+	fields []FieldError
+}
+
+func (validation *validation) add(field string, format string, args ...interface{}) {
+	validation.fields = append(validation.fields, FieldError{Field: field, Message: fmt.Sprintf(format, args...)})
+}
+
+// result returns the collected errors as a ValidationError, or nil if there
+// were none.
+func (validation *validation) result(subject string) error {
+	if len(validation.fields) == 0 {
+		return nil
+	}
+
+	return &ValidationError{Subject: subject, Fields: validation.fields}
+}
+
+// Validate checks a trigger the way the Geotrigger Service would, without a
+// request: the condition's direction, the fence's geometry, the order of the
+// time window, that the action does something, and that tags aren't empty.
+// A trigger ID isn't needed, as the service generates one on create. Every
+// problem found is listed in the returned ValidationError. For a dry run of
+// `trigger/create`, validate the trigger's CreateParams instead.
+func (trigger *Trigger) Validate() error {
+	var v validation
+	validateTrigger(&v, &trigger.Condition, &trigger.Action, trigger.Times, trigger.RateLimit)
+	validateTags(&v, "tags", trigger.Tags)
+
+	return v.result("trigger")
+}
+
+// Validate checks the trigger being created as Trigger.Validate does, along
+// with its setTags. It is a dry run of `trigger/create`.
+func (params *TriggerCreateParams) Validate() error {
+	var v validation
+	validateTrigger(&v, &params.Condition, &params.Action, params.Times, params.RateLimit)
+	validateTags(&v, "setTags", params.SetTags)
+
+	return v.result("trigger")
+}
+
+// validateCreate checks a Trigger sent as is to `trigger/create`, which would
+// drop its tags, as it only reads setTags.
+func (trigger *Trigger) validateCreate() error {
+	var v validation
+	validateTrigger(&v, &trigger.Condition, &trigger.Action, trigger.Times, trigger.RateLimit)
+	if len(trigger.Tags) > 0 {
+		v.add("tags", "Ignored by trigger/create, send the trigger's CreateParams instead.")
+	}
+
+	return v.result("trigger")
+}
+
+// validateTrigger checks the fields a trigger and the params creating one
+// share.
+func validateTrigger(v *validation, condition *Condition, action *Action, times int, rateLimit int) {
+	switch condition.Direction {
+	case DirectionEnter, DirectionLeave:
+	case "":
+		v.add("condition.direction", "Missing.")
+	default:
+		v.add("condition.direction", "Must be %s or %s, not %s.", DirectionEnter, DirectionLeave, condition.Direction)
+	}
+
+	condition.Geo.validate(v, "condition.geo")
+
+	if condition.FromTimestamp != nil && condition.ToTimestamp != nil &&
+		!condition.FromTimestamp.Before(*condition.ToTimestamp) {
+		v.add("condition.fromTimestamp", "Must be before condition.toTimestamp.")
+	}
+
+	if len(action.Message) == 0 && len(action.CallbackURL) == 0 && action.Notification == nil &&
+		len(action.TrackingProfile) == 0 {
+		v.add("action", "Needs a message, callbackUrl, notification or trackingProfile.")
+	}
+	if len(action.CallbackURL) > 0 {
+		callback, err := url.Parse(action.CallbackURL)
+		if err != nil || (callback.Scheme != "http" && callback.Scheme != "https") || len(callback.Host) == 0 {
+			v.add("action.callbackUrl", "Must be an absolute http or https URL.")
+		}
+	}
+	if action.Notification != nil {
+		action.Notification.validate(v, "action.notification")
+	}
+	validateTrackingProfile(v, "action.trackingProfile", action.TrackingProfile)
+
+	if times < 0 {
+		v.add("times", "Can't be negative.")
+	}
+	if rateLimit < 0 {
+		v.add("rateLimit", "Can't be negative.")
+	}
+}
+
+// validate checks a fence: it must be exactly one of a circle, a GeoJSON or
+// Esri polygon, or a geocode.
+func (geo *Geo) validate(v *validation, field string) {
+	kinds := 0
+	if geo.Distance != 0 || geo.Latitude != 0 || geo.Longitude != 0 {
+		kinds++
+		if geo.Distance <= 0 {
+			v.add(field+".distance", "A circle needs a distance greater than 0.")
+		}
+		validatePosition(v, field, geo.Longitude, geo.Latitude)
+	}
+	if geo.GeoJSON != nil {
+		kinds++
+		geo.GeoJSON.validate(v, field+".geojson")
+	}
+	if geo.EsriJSON != nil {
+		kinds++
+		validateRings(v, field+".esrijson.rings", geo.EsriJSON.Rings)
+	}
+	if len(geo.Geocode) > 0 {
+		kinds++
+	}
+	if geo.DriveTime != 0 && len(geo.Geocode) == 0 {
+		v.add(field+".driveTime", "Only applies to a geocode.")
+	}
+	if geo.DriveTime < 0 {
+		v.add(field+".driveTime", "Can't be negative.")
+	}
+
+	switch {
+	case kinds == 0:
+		v.add(field, "Missing a fence: a circle, geojson, esrijson or geocode.")
+	case kinds > 1:
+		v.add(field, "Only one of a circle, geojson, esrijson or geocode can be set.")
+	}
+}
+
+func (geojson *GeoJSON) validate(v *validation, field string) {
+	polygons, err := geojson.Polygons()
+	if err != nil {
+		v.add(field, "%s", err)
+		return
+	}
+	if len(polygons) == 0 {
+		v.add(field+".coordinates", "No polygons.")
+		return
+	}
+
+	for i, rings := range polygons {
+		path := field + ".coordinates"
+		if geojson.Type == "MultiPolygon" {
+			path = fmt.Sprintf("%s[%d]", path, i)
+		}
+		validateRings(v, path, rings)
+	}
+}
+
+// validateRings checks polygon rings of [longitude, latitude] positions: each
+// needs at least four positions, and must end where it starts.
+func validateRings(v *validation, field string, rings [][][]float64) {
+	if len(rings) == 0 {
+		v.add(field, "A polygon needs at least one ring.")
+		return
+	}
+
+	for i, ring := range rings {
+		path := fmt.Sprintf("%s[%d]", field, i)
+		if len(ring) < 4 {
+			v.add(path, "A ring needs at least 4 positions, this has %d.", len(ring))
+			continue
+		}
+
+		for j, position := range ring {
+			if len(position) < 2 {
+				v.add(fmt.Sprintf("%s[%d]", path, j), "Missing a longitude or latitude.")
+				return
+			}
+		}
+		for j, position := range ring {
+			before := len(v.fields)
+			validatePosition(v, fmt.Sprintf("%s[%d]", path, j), position[0], position[1])
+			if len(v.fields) > before {
+				// one bad position is enough to point at the ring
+				break
+			}
+		}
+
+		first, last := ring[0], ring[len(ring)-1]
+		if first[0] != last[0] || first[1] != last[1] {
+			v.add(path, "A ring must end where it starts.")
+		}
+	}
+}
+
+func validatePosition(v *validation, field string, longitude float64, latitude float64) {
+	if latitude < -90 || latitude > 90 {
+		v.add(field, "Latitude %v is out of range.", latitude)
+	}
+	if longitude < -180 || longitude > 180 {
+		v.add(field, "Longitude %v is out of range.", longitude)
+	}
+}
+
+func validateTags(v *validation, field string, tags []string) {
+	for i, tag := range tags {
+		if len(strings.TrimSpace(tag)) == 0 {
+			v.add(fmt.Sprintf("%s[%d]", field, i), "Tags can't be empty.")
+		}
+	}
+}
+
+// Validate checks a location's coordinates, accuracy and timestamp.
+func (location *Location) Validate() error {
+	var v validation
+	location.validate(&v, "")
+	return v.result("location")
+}
+
+func (location *Location) validate(v *validation, prefix string) {
+	if location.Latitude < -90 || location.Latitude > 90 {
+		v.add(prefix+"latitude", "%v is out of range.", location.Latitude)
+	}
+	if location.Longitude < -180 || location.Longitude > 180 {
+		v.add(prefix+"longitude", "%v is out of range.", location.Longitude)
+	}
+	if location.Accuracy < 0 {
+		v.add(prefix+"accuracy", "Can't be negative.")
+	}
+	if location.Timestamp.IsZero() {
+		v.add(prefix+"timestamp", "Missing.")
+	}
+}
+
+// Validate checks every location, and that they are in time order, as the
+// service expects.
+func (params *LocationUpdateParams) Validate() error {
+	var v validation
+	if params == nil || len(params.Locations) == 0 {
+		v.add("locations", "At least one location is required.")
+		return v.result("params for location/update")
+	}
+
+	for i := range params.Locations {
+		location := &params.Locations[i]
+		prefix := fmt.Sprintf("locations[%d].", i)
+		location.validate(&v, prefix)
+		if i > 0 && location.Timestamp.Before(params.Locations[i-1].Timestamp) {
+			v.add(prefix+"timestamp", "Locations must be in time order.")
+		}
+	}
+
+	return v.result("params for location/update")
+}
+
+// Validate checks that the time window is in order, and that tags aren't
+// empty.
+func (params *HistoryParams) Validate() error {
+	var v validation
+	if params == nil {
+		return nil
+	}
+
+	if params.FromTimestamp != nil && params.ToTimestamp != nil && params.ToTimestamp.Before(*params.FromTimestamp) {
+		v.add("fromTimestamp", "Must not be after toTimestamp.")
+	}
+	validateTags(&v, "tags", params.Tags)
+
+	return v.result("params for trigger/history")
+}
+
+// Validate checks that there are triggers to run, and devices to run them
+// for.
+func (params *RunParams) Validate() error {
+	var v validation
+	if params == nil {
+		params = &RunParams{}
+	}
+
+	if len(params.TriggerIDs) == 0 {
+		v.add("triggerIds", "At least one trigger ID is required.")
+	}
+	if len(params.DeviceIDs) == 0 && len(params.Tags) == 0 {
+		v.add("deviceIds", "Device IDs or tags are required, to run the triggers for.")
+	}
+	validateTags(&v, "tags", params.Tags)
+
+	return v.result("params for trigger/run")
+}
+
+// validateParams runs the params' own checks, if they have any and they
+// apply to the route.
+func validateParams(route string, params interface{}) error {
+	value := reflect.ValueOf(params)
+	switch {
+	case !value.IsValid():
+		return nil
+	case value.Kind() == reflect.Ptr && value.IsNil():
+		// a nil pointer to params is sent as null, and left to the service
+		return nil
+	case value.Kind() != reflect.Ptr:
+		// params passed by value are checked as a pointer to them would be
+		pointer := reflect.New(value.Type())
+		pointer.Elem().Set(value)
+		value = pointer
+	}
+
+	validator, ok := value.Interface().(Validator)
+	if !ok {
+		return nil
+	}
+
+	if validated, known := validatedRoutes[value.Elem().Type()]; known && validated != strings.Trim(route, "/") {
+		return nil
+	}
+	if trigger, ok := validator.(*Trigger); ok {
+		return trigger.validateCreate()
+	}
+
+	return validator.Validate()
+}
+
+// validateSize checks the size of the JSON the params make against the
+// environment's limit.
+func validateSize(env *environment, route string, body []byte) error {
+	if env.maxRequestSize <= 0 || int64(len(body)) <= env.maxRequestSize {
+		return nil
+	}
+
+	var v validation
+	v.add("", "Params are %d bytes, over the maximum of %d.", len(body), env.maxRequestSize)
+	return v.result("params for " + route)
+}
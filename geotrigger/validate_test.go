@@ -0,0 +1,168 @@
+package geotrigger
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/Esri/geotrigger-go/geotrigger/test"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestValidateTrigger(t *testing.T) {
+	valid := []string{
+		`{"condition":{"direction":"enter","geo":{"latitude":45.5,"longitude":-122.6,"distance":50}},"action":{"message":"hi"}}`,
+		`{"condition":{"direction":"leave","geo":{"geojson":{"type":"Polygon","coordinates":[[[0,0],[1,0],[1,1],[0,0]]]}}},"action":{"callbackUrl":"https://pdx.gov/bye"}}`,
+		`{"condition":{"direction":"enter","geo":{"esrijson":{"rings":[[[0,0],[0,1],[1,1],[0,0]]]}}},"action":{"notification":{"text":"hi"}}}`,
+		`{"condition":{"direction":"enter","geo":{"geocode":"Portland, OR","driveTime":600},"fromTimestamp":"2014-01-01T00:00:00Z","toTimestamp":"2014-01-02T00:00:00Z"},"action":{"trackingProfile":"rough"},"tags":["pdx"]}`,
+	}
+	for _, data := range valid {
+		var trigger Trigger
+		test.Expect(t, json.Unmarshal([]byte(data), &trigger), nil)
+		test.Expect(t, trigger.Validate(), nil)
+	}
+
+	invalid := map[string]string{
+		`{"condition":{"geo":{"geocode":"Portland"}},"action":{"message":"hi"}}`:                                                                                                            "condition.direction: Missing.",
+		`{"condition":{"direction":"in","geo":{"geocode":"Portland"}},"action":{"message":"hi"}}`:                                                                                           "condition.direction: Must be enter or leave, not in.",
+		`{"condition":{"direction":"enter","geo":{}},"action":{"message":"hi"}}`:                                                                                                            "condition.geo: Missing a fence: a circle, geojson, esrijson or geocode.",
+		`{"condition":{"direction":"enter","geo":{"latitude":95,"longitude":-122.6,"distance":50}},"action":{"message":"hi"}}`:                                                              "condition.geo: Latitude 95 is out of range.",
+		`{"condition":{"direction":"enter","geo":{"latitude":45.5,"longitude":-122.6}},"action":{"message":"hi"}}`:                                                                          "condition.geo.distance: A circle needs a distance greater than 0.",
+		`{"condition":{"direction":"enter","geo":{"geocode":"Portland","latitude":45.5,"longitude":-122.6,"distance":50}},"action":{"message":"hi"}}`:                                       "condition.geo: Only one of a circle, geojson, esrijson or geocode can be set.",
+		`{"condition":{"direction":"enter","geo":{"geojson":{"type":"Polygon","coordinates":[[[0,0],[1,0],[1,1],[2,2]]]}}},"action":{"message":"hi"}}`:                                      "condition.geo.geojson.coordinates[0]: A ring must end where it starts.",
+		`{"condition":{"direction":"enter","geo":{"geojson":{"type":"Polygon","coordinates":[[[0,0],[1,0],[0,0]]]}}},"action":{"message":"hi"}}`:                                            "condition.geo.geojson.coordinates[0]: A ring needs at least 4 positions, this has 3.",
+		`{"condition":{"direction":"enter","geo":{"geojson":{"type":"MultiPolygon","coordinates":[[[[0,0],[1,0],[1,1],[0,0]]],[[[0,0],[200,0],[1,1],[0,0]]]]}}},"action":{"message":"hi"}}`: "condition.geo.geojson.coordinates[1][0][1]: Longitude 200 is out of range.",
+		`{"condition":{"direction":"enter","geo":{"geojson":{"type":"Point","coordinates":[0,0]}}},"action":{"message":"hi"}}`:                                                              "condition.geo.geojson: Unsupported GeoJSON geometry type: Point.",
+		`{"condition":{"direction":"enter","geo":{"latitude":45.5,"longitude":-122.6,"distance":50,"driveTime":60}},"action":{"message":"hi"}}`:                                             "condition.geo.driveTime: Only applies to a geocode.",
+		`{"condition":{"direction":"enter","geo":{"geocode":"Portland"},"fromTimestamp":"2014-01-02T00:00:00Z","toTimestamp":"2014-01-01T00:00:00Z"},"action":{"message":"hi"}}`:            "condition.fromTimestamp: Must be before condition.toTimestamp.",
+		`{"condition":{"direction":"enter","geo":{"geocode":"Portland"}},"action":{}}`:                                                                                                      "action: Needs a message, callbackUrl, notification or trackingProfile.",
+		`{"condition":{"direction":"enter","geo":{"geocode":"Portland"}},"action":{"callbackUrl":"ftp://pdx.gov/bye"}}`:                                                                     "action.callbackUrl: Must be an absolute http or https URL.",
+		`{"condition":{"direction":"enter","geo":{"geocode":"Portland"}},"action":{"message":"hi"},"tags":["pdx",""]}`:                                                                      "tags[1]: Tags can't be empty.",
+		`{"condition":{"direction":"enter","geo":{"geocode":"Portland"}},"action":{"message":"hi"},"times":-1}`:                                                                             "times: Can't be negative.",
+	}
+	for data, message := range invalid {
+		var trigger Trigger
+		test.Expect(t, json.Unmarshal([]byte(data), &trigger), nil)
+		err := trigger.Validate()
+		test.Refute(t, err, nil)
+		test.Expect(t, err.Error(), "Invalid trigger. "+message)
+	}
+
+	// every problem is reported at once
+	from := time.Date(2014, 1, 2, 0, 0, 0, 0, time.UTC)
+	trigger := &Trigger{Condition: Condition{FromTimestamp: &from, ToTimestamp: &from}}
+	err := trigger.Validate()
+	var validationError *ValidationError
+	test.Expect(t, errors.As(err, &validationError), true)
+	test.Expect(t, validationError.Subject, "trigger")
+	test.Expect(t, validationError.Fields, []FieldError{
+		{"condition.direction", "Missing."},
+		{"condition.geo", "Missing a fence: a circle, geojson, esrijson or geocode."},
+		{"condition.fromTimestamp", "Must be before condition.toTimestamp."},
+		{"action", "Needs a message, callbackUrl, notification or trackingProfile."},
+	})
+
+	// params creating a trigger check their setTags
+	var params TriggerCreateParams
+	test.Expect(t, json.Unmarshal([]byte(`{"condition":{"direction":"enter","geo":{"geocode":"Portland"}},"action":{"message":"hi"},"setTags":["pdx",""]}`), &params), nil)
+	err = params.Validate()
+	test.Refute(t, err, nil)
+	test.Expect(t, err.Error(), "Invalid trigger. setTags[1]: Tags can't be empty.")
+}
+
+func TestValidateLocations(t *testing.T) {
+	timestamp := time.Date(2014, 5, 14, 18, 0, 0, 0, time.UTC)
+	location := &Location{Latitude: 45.5, Longitude: -122.6, Accuracy: 10, Timestamp: timestamp}
+	test.Expect(t, location.Validate(), nil)
+
+	location = &Location{Latitude: -91, Longitude: 181, Accuracy: -1}
+	test.Expect(t, location.Validate().Error(), "Invalid location. latitude: -91 is out of range. "+
+		"longitude: 181 is out of range. accuracy: Can't be negative. timestamp: Missing.")
+
+	params := &LocationUpdateParams{Locations: []Location{
+		{Latitude: 45.5, Longitude: -122.6, Timestamp: timestamp},
+		{Latitude: 45.5, Longitude: -122.6, Timestamp: timestamp.Add(-time.Minute)},
+	}}
+	test.Expect(t, params.Validate().Error(),
+		"Invalid params for location/update. locations[1].timestamp: Locations must be in time order.")
+
+	params = &LocationUpdateParams{}
+	test.Expect(t, params.Validate().Error(),
+		"Invalid params for location/update. locations: At least one location is required.")
+
+	to := timestamp.Add(-time.Hour)
+	history := &HistoryParams{FromTimestamp: &timestamp, ToTimestamp: &to}
+	test.Expect(t, history.Validate().Error(),
+		"Invalid params for trigger/history. fromTimestamp: Must not be after toTimestamp.")
+}
+
+func TestInvalidParamsAreNotSent(t *testing.T) {
+	requests := 0
+	gtServer := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, r *http.Request) {
+		requests++
+		res.Write([]byte(`{"processedLocations":1}`))
+	}))
+	defer gtServer.Close()
+
+	env := Environment{GeotriggerURL: gtServer.URL, MaxRequestSize: 150}
+	client := ExistingDeviceWithEnvironment(env, "good_client_id", "device_id", "good_access_token",
+		1800, "good_refresh_token")
+	ctx := context.Background()
+	timestamp := time.Date(2014, 5, 14, 18, 0, 0, 0, time.UTC)
+
+	var response map[string]interface{}
+	err := client.Request("location/update", &LocationUpdateParams{Locations: []Location{{Latitude: 100, Timestamp: timestamp}}}, &response)
+	var validationError *ValidationError
+	test.Expect(t, errors.As(err, &validationError), true)
+	test.Expect(t, validationError.Fields[0].Field, "locations[0].latitude")
+	test.Expect(t, requests, 0)
+
+	// nil params are left to the service
+	_, err = Do[map[string]interface{}](ctx, client, "location/update", (*LocationUpdateParams)(nil))
+	test.Expect(t, err, nil)
+	test.Expect(t, requests, 1)
+
+	locations := make([]Location, 3)
+	for i := range locations {
+		locations[i] = Location{Latitude: 45.5, Longitude: -122.6, Accuracy: 10, Timestamp: timestamp}
+	}
+	_, err = Do[map[string]interface{}](ctx, client, "location/update", &LocationUpdateParams{Locations: locations})
+	test.Refute(t, err, nil)
+	test.Expect(t, strings.HasPrefix(err.Error(), "Invalid params for location/update. Params are "), true)
+	test.Expect(t, strings.HasSuffix(err.Error(), " bytes, over the maximum of 150."), true)
+	test.Expect(t, requests, 1)
+
+	_, err = Do[map[string]interface{}](ctx, client, "location/update", &LocationUpdateParams{Locations: locations[:1]})
+	test.Expect(t, err, nil)
+	test.Expect(t, requests, 2)
+
+	// params passed by value are checked too
+	trigger := Trigger{Condition: Condition{Direction: "in"}}
+	_, err = Do[map[string]interface{}](ctx, client, "trigger/create", trigger)
+	test.Expect(t, errors.As(err, &validationError), true)
+	test.Expect(t, validationError.Subject, "trigger")
+	test.Expect(t, requests, 2)
+
+	// but only for the route they describe
+	_, err = Do[map[string]interface{}](ctx, client, "trigger/update", &trigger)
+	test.Expect(t, err, nil)
+	test.Expect(t, requests, 3)
+
+	// trigger/create would drop a trigger's tags, which its create params set
+	trigger = Trigger{
+		Condition: Condition{Direction: DirectionEnter, Geo: Geo{Geocode: "Portland"}},
+		Action:    Action{Message: "hi"},
+		Tags:      []string{"pdx"},
+	}
+	_, err = Do[map[string]interface{}](ctx, client, "trigger/create", &trigger)
+	test.Refute(t, err, nil)
+	test.Expect(t, err.Error(), "Invalid trigger. tags: Ignored by trigger/create, send the trigger's CreateParams instead.")
+	test.Expect(t, requests, 3)
+
+	_, err = Do[map[string]interface{}](ctx, client, "trigger/create", trigger.CreateParams())
+	test.Expect(t, err, nil)
+	test.Expect(t, requests, 4)
+}